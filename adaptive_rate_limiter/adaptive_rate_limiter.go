@@ -0,0 +1,179 @@
+package adaptive_rate_limiter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const (
+	DEFAULT_INITIAL_LIMIT = 20
+	DEFAULT_MIN_LIMIT     = 1
+	DEFAULT_MAX_LIMIT     = 1000
+	DEFAULT_IDLE_TTL      = 10 * time.Minute
+)
+
+// AdaptiveConfig tunes an AdaptiveRateLimiter
+type AdaptiveConfig struct {
+	// InitialLimit is where every key's concurrency limit starts, DEFAULT_INITIAL_LIMIT when zero
+	InitialLimit int
+	// MinLimit and MaxLimit bound the limit, DEFAULT_MIN_LIMIT and DEFAULT_MAX_LIMIT when zero
+	MinLimit int
+	MaxLimit int
+	// NewPolicy creates the policy of each key, an AIMDPolicy with default settings when nil
+	NewPolicy func() LimitPolicyInterface
+	// IdleTTL is how long a key with nothing in flight keeps its learned limit before it is
+	// forgotten, DEFAULT_IDLE_TTL when zero. A forgotten key starts over at InitialLimit.
+	IdleTTL time.Duration
+}
+
+type keyState struct {
+	limit    float64
+	inFlight int
+	policy   LimitPolicyInterface
+	usedAt   time.Time
+}
+
+// AdaptiveRateLimiter bounds the requests in flight per key with a limit that follows the backend's
+// capacity instead of being fixed up front. Callers report how each allowed request went, and the
+// key's policy raises the limit while requests stay fast and lowers it once they fail or slow
+// down. It keeps its state in process, so each instance adapts to what it observes itself.
+//
+// Unlike the window-based limiters, an allowed decision holds a slot until it is reported. A caller
+// that only calls LimitRequests, such as the dry-run, resilient or batch wrappers, never frees its
+// slots and locks the key out after InitialLimit requests. Pair every allowed decision with Report,
+// use Track, or put rate_limiter.Middleware in front of the handler. Composite and hierarchical limiters free
+// slots they do not use through Rollback.
+type AdaptiveRateLimiter struct {
+	config  AdaptiveConfig
+	options rate_limiter.Options
+
+	mu      sync.Mutex
+	keys    map[string]*keyState
+	sweptAt time.Time
+}
+
+// NewAdaptiveRateLimiter keeps one limit per key. Of the options, it uses the clock, the decision
+// logger and the key prefix.
+func NewAdaptiveRateLimiter(config AdaptiveConfig, opts ...rate_limiter.Option) *AdaptiveRateLimiter {
+	if config.MinLimit <= 0 {
+		config.MinLimit = DEFAULT_MIN_LIMIT
+	}
+	if config.MaxLimit <= 0 {
+		config.MaxLimit = DEFAULT_MAX_LIMIT
+	}
+	if config.InitialLimit <= 0 {
+		config.InitialLimit = DEFAULT_INITIAL_LIMIT
+	}
+	config.InitialLimit = min(max(config.InitialLimit, config.MinLimit), config.MaxLimit)
+	if config.NewPolicy == nil {
+		config.NewPolicy = func() LimitPolicyInterface { return NewAIMDPolicy(AIMDConfig{}) }
+	}
+	if config.IdleTTL <= 0 {
+		config.IdleTTL = DEFAULT_IDLE_TTL
+	}
+	return &AdaptiveRateLimiter{
+		config:  config,
+		options: rate_limiter.NewOptions(opts...),
+		keys:    make(map[string]*keyState),
+	}
+}
+
+func (a *AdaptiveRateLimiter) LimitRequests(clientId string) bool {
+	return a.Decide(clientId).Allowed
+}
+
+// Decide takes a slot for clientId while fewer requests than its current limit are in flight. Every
+// allowed request must be followed by a Report, or by calling the function Track returns, or its
+// slot is never freed.
+func (a *AdaptiveRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	a.mu.Lock()
+	a.sweep()
+	state := a.state(clientId)
+	limit := int64(state.limit)
+	decision := rate_limiter.Decision{
+		Algorithm: rate_limiter.ALGORITHM_ADAPTIVE_CONCURRENCY,
+		Key:       clientId,
+		Limit:     limit,
+	}
+	if int64(state.inFlight) < limit {
+		state.inFlight++
+		decision.Allowed = true
+	}
+	decision.Remaining = max(limit-int64(state.inFlight), 0)
+	a.mu.Unlock()
+
+	a.options.Logger.Log(decision)
+	return decision
+}
+
+// Report frees the slot of an allowed request to clientId and feeds its outcome to the policy
+func (a *AdaptiveRateLimiter) Report(clientId string, latency time.Duration, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state := a.state(clientId)
+	sample := Sample{Latency: latency, Failed: err != nil, InFlight: state.inFlight}
+	state.inFlight = max(state.inFlight-1, 0)
+	next := state.policy.Update(state.limit, sample)
+	state.limit = min(max(next, float64(a.config.MinLimit)), float64(a.config.MaxLimit))
+}
+
+// Rollback frees the slot of an allowed decision whose request never ran, without telling the policy
+func (a *AdaptiveRateLimiter) Rollback(decision rate_limiter.Decision) error {
+	if !decision.Allowed {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if state, ok := a.keys[a.options.Key(decision.Key)]; ok {
+		state.inFlight = max(state.inFlight-1, 0)
+	}
+	return nil
+}
+
+// Track decides on clientId and, when allowed, returns a function to call with the request's error
+// once it has finished, which reports the latency measured in between
+func (a *AdaptiveRateLimiter) Track(clientId string) (done func(err error), allowed bool) {
+	if !a.LimitRequests(clientId) {
+		return nil, false
+	}
+	start := a.options.Clock.Now()
+	return func(err error) {
+		a.Report(clientId, a.options.Clock.Now().Sub(start), err)
+	}, true
+}
+
+// Limit returns the current concurrency limit of clientId
+func (a *AdaptiveRateLimiter) Limit(clientId string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.state(clientId).limit)
+}
+
+// state returns the state of clientId, creating it on first use. Callers hold mu.
+func (a *AdaptiveRateLimiter) state(clientId string) *keyState {
+	key := a.options.Key(clientId)
+	state, ok := a.keys[key]
+	if !ok {
+		state = &keyState{limit: float64(a.config.InitialLimit), policy: a.config.NewPolicy()}
+		a.keys[key] = state
+	}
+	state.usedAt = a.options.Clock.Now()
+	return state
+}
+
+// sweep forgets keys with nothing in flight that have not been used for IdleTTL, at most once per
+// IdleTTL. Callers hold mu.
+func (a *AdaptiveRateLimiter) sweep() {
+	now := a.options.Clock.Now()
+	if now.Sub(a.sweptAt) < a.config.IdleTTL {
+		return
+	}
+	a.sweptAt = now
+	for key, state := range a.keys {
+		if state.inFlight == 0 && now.Sub(state.usedAt) >= a.config.IdleTTL {
+			delete(a.keys, key)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package adaptive_rate_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAdaptiveRateLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AdaptiveRateLimiter Suite")
+}
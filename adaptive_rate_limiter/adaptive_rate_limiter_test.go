@@ -0,0 +1,184 @@
+package adaptive_rate_limiter_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/adaptive_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("AdaptiveRateLimiter", func() {
+	var clock *rate_limiter.ManualClock
+
+	BeforeEach(func() {
+		clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+	})
+
+	// saturate sends limit requests at once and reports them all with latency and err
+	saturate := func(limiter *adaptive_rate_limiter.AdaptiveRateLimiter, latency time.Duration, err error) {
+		var finished []func(error)
+		for {
+			done, allowed := limiter.Track("backend")
+			if !allowed {
+				break
+			}
+			finished = append(finished, done)
+		}
+		clock.Advance(latency)
+		for _, done := range finished {
+			done(err)
+		}
+	}
+
+	It("should allow up to the current limit in flight and free slots on report", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{InitialLimit: 2}, rate_limiter.WithClock(clock))
+
+		first, allowed := limiter.Track("backend")
+		Expect(allowed).To(BeTrue())
+		Expect(limiter.LimitRequests("backend")).To(BeTrue())
+		decision := limiter.Decide("backend")
+		Expect(decision.Allowed).To(BeFalse())
+		Expect(decision.Limit).To(Equal(int64(2)))
+		Expect(limiter.LimitRequests("other-backend")).To(BeTrue())
+
+		first(nil)
+		Expect(limiter.LimitRequests("backend")).To(BeTrue())
+	})
+
+	It("should grow additively and back off multiplicatively with AIMD", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{
+			InitialLimit: 10,
+			NewPolicy: func() adaptive_rate_limiter.LimitPolicyInterface {
+				return adaptive_rate_limiter.NewAIMDPolicy(adaptive_rate_limiter.AIMDConfig{Backoff: 0.5, LatencyThreshold: time.Second})
+			},
+		}, rate_limiter.WithClock(clock))
+
+		saturate(limiter, 10*time.Millisecond, nil)
+		Expect(limiter.Limit("backend")).To(BeNumerically(">", 10))
+
+		grown := limiter.Limit("backend")
+		limiter.LimitRequests("backend")
+		limiter.Report("backend", 10*time.Millisecond, errors.New("503"))
+		Expect(limiter.Limit("backend")).To(Equal(grown / 2))
+
+		shrunk := limiter.Limit("backend")
+		limiter.LimitRequests("backend")
+		limiter.Report("backend", 2*time.Second, nil)
+		Expect(limiter.Limit("backend")).To(BeNumerically("<", shrunk))
+	})
+
+	It("should not grow a limit that is far from being used", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{InitialLimit: 10}, rate_limiter.WithClock(clock))
+
+		for range 50 {
+			done, _ := limiter.Track("backend")
+			done(nil)
+		}
+
+		Expect(limiter.Limit("backend")).To(Equal(10))
+	})
+
+	It("should shrink the limit with the gradient policy once latency climbs", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{
+			InitialLimit: 10,
+			NewPolicy: func() adaptive_rate_limiter.LimitPolicyInterface {
+				return adaptive_rate_limiter.NewGradientPolicy(adaptive_rate_limiter.GradientConfig{})
+			},
+		}, rate_limiter.WithClock(clock))
+
+		for range 5 {
+			saturate(limiter, 10*time.Millisecond, nil)
+		}
+		grown := limiter.Limit("backend")
+		Expect(grown).To(BeNumerically(">", 10))
+
+		for range 5 {
+			saturate(limiter, 100*time.Millisecond, nil)
+		}
+		Expect(limiter.Limit("backend")).To(BeNumerically("<", grown))
+	})
+
+	It("should keep the limit within its bounds", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{InitialLimit: 4, MinLimit: 2, MaxLimit: 6}, rate_limiter.WithClock(clock))
+
+		for range 10 {
+			saturate(limiter, time.Millisecond, nil)
+		}
+		Expect(limiter.Limit("backend")).To(Equal(6))
+
+		for range 10 {
+			saturate(limiter, time.Millisecond, errors.New("timeout"))
+		}
+		Expect(limiter.Limit("backend")).To(Equal(2))
+	})
+
+	It("should free the slot of a rolled back decision without moving the limit", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{InitialLimit: 1}, rate_limiter.WithClock(clock))
+
+		decision := limiter.Decide("backend")
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(limiter.LimitRequests("backend")).To(BeFalse())
+
+		Expect(limiter.Rollback(decision)).To(Succeed())
+		Expect(limiter.Limit("backend")).To(Equal(1))
+		Expect(limiter.LimitRequests("backend")).To(BeTrue())
+	})
+
+	It("should report every request served through the middleware", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{
+			InitialLimit: 4,
+			NewPolicy: func() adaptive_rate_limiter.LimitPolicyInterface {
+				return adaptive_rate_limiter.NewAIMDPolicy(adaptive_rate_limiter.AIMDConfig{Backoff: 0.5})
+			},
+		}, rate_limiter.WithClock(clock))
+		status := http.StatusOK
+		handler := rate_limiter.Middleware(limiter, func(r *http.Request) string { return r.Header.Get("X-Tenant") }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		serve := func() int {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("X-Tenant", "tenant")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			return recorder.Code
+		}
+
+		for range 10 {
+			Expect(serve()).To(Equal(http.StatusOK))
+		}
+
+		status = http.StatusBadGateway
+		Expect(serve()).To(Equal(http.StatusBadGateway))
+		Expect(limiter.Limit("tenant")).To(Equal(2))
+
+		done, allowed := limiter.Track("tenant")
+		Expect(allowed).To(BeTrue())
+		_, allowed = limiter.Track("tenant")
+		Expect(allowed).To(BeTrue())
+		Expect(serve()).To(Equal(http.StatusTooManyRequests))
+		done(nil)
+	})
+
+	It("should forget keys that have been idle for IdleTTL", func() {
+		limiter := adaptive_rate_limiter.NewAdaptiveRateLimiter(adaptive_rate_limiter.AdaptiveConfig{InitialLimit: 4, IdleTTL: time.Minute}, rate_limiter.WithClock(clock))
+		saturate(limiter, time.Millisecond, errors.New("timeout"))
+		Expect(limiter.Limit("backend")).To(Equal(2))
+		busy, allowed := limiter.Track("busy")
+		Expect(allowed).To(BeTrue())
+
+		clock.Advance(time.Minute)
+		Expect(limiter.LimitRequests("other")).To(BeTrue())
+
+		Expect(limiter.Limit("backend")).To(Equal(4))
+		_, allowed = limiter.Track("busy")
+		Expect(allowed).To(BeTrue())
+		Expect(limiter.Decide("busy").Remaining).To(Equal(int64(1)))
+		busy(nil)
+	})
+})
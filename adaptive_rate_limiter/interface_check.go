@@ -0,0 +1,10 @@
+package adaptive_rate_limiter
+
+import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+
+var _ rate_limiter.RateLimiterInterface = (*AdaptiveRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*AdaptiveRateLimiter)(nil)
+var _ rate_limiter.RollbackInterface = (*AdaptiveRateLimiter)(nil)
+var _ rate_limiter.ReporterInterface = (*AdaptiveRateLimiter)(nil)
+var _ LimitPolicyInterface = (*AIMDPolicy)(nil)
+var _ LimitPolicyInterface = (*GradientPolicy)(nil)
@@ -0,0 +1,127 @@
+package adaptive_rate_limiter
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	DEFAULT_AIMD_INCREASE        = 1.0
+	DEFAULT_BACKOFF              = 0.9
+	DEFAULT_GRADIENT_SMOOTHING   = 0.2
+	DEFAULT_GRADIENT_TOLERANCE   = 1.5
+	DEFAULT_GRADIENT_LONG_WINDOW = 100
+	// MIN_GRADIENT caps how much a single sample can shrink the limit under the gradient policy
+	MIN_GRADIENT = 0.5
+)
+
+// Sample is the outcome of one request, as reported back by the caller
+type Sample struct {
+	Latency time.Duration
+	Failed  bool
+	// InFlight is how many requests of the key were in flight when this one finished, itself included
+	InFlight int
+}
+
+// LimitPolicyInterface works out a key's next concurrency limit from the outcome of a request.
+// A policy instance serves a single key, so it may keep state such as a latency baseline.
+type LimitPolicyInterface interface {
+	Update(limit float64, sample Sample) float64
+}
+
+// AIMDConfig tunes an AIMDPolicy
+type AIMDConfig struct {
+	// Increase is added to the limit after each successful request, DEFAULT_AIMD_INCREASE when zero
+	Increase float64
+	// Backoff multiplies the limit after a failed or slow request, DEFAULT_BACKOFF when zero
+	Backoff float64
+	// LatencyThreshold counts requests slower than it as failed, zero only counts errors
+	LatencyThreshold time.Duration
+}
+
+// AIMDPolicy grows the limit additively while requests succeed and shrinks it multiplicatively
+// when one fails or is too slow, like TCP congestion control
+type AIMDPolicy struct {
+	config AIMDConfig
+}
+
+func NewAIMDPolicy(config AIMDConfig) *AIMDPolicy {
+	if config.Increase <= 0 {
+		config.Increase = DEFAULT_AIMD_INCREASE
+	}
+	if config.Backoff <= 0 || config.Backoff >= 1 {
+		config.Backoff = DEFAULT_BACKOFF
+	}
+	return &AIMDPolicy{config: config}
+}
+
+func (a *AIMDPolicy) Update(limit float64, sample Sample) float64 {
+	if sample.Failed || (a.config.LatencyThreshold > 0 && sample.Latency > a.config.LatencyThreshold) {
+		return limit * a.config.Backoff
+	}
+	// A limit that is far from being used says nothing about whether it could be higher
+	if float64(sample.InFlight)*2 < limit {
+		return limit
+	}
+	return limit + a.config.Increase
+}
+
+// GradientConfig tunes a GradientPolicy
+type GradientConfig struct {
+	// Smoothing is how far each sample moves the limit towards its target, DEFAULT_GRADIENT_SMOOTHING when zero
+	Smoothing float64
+	// Tolerance is how much slower than the baseline requests may get before the limit shrinks,
+	// DEFAULT_GRADIENT_TOLERANCE when zero
+	Tolerance float64
+	// LongWindow is how many samples the latency baseline averages over, DEFAULT_GRADIENT_LONG_WINDOW when zero
+	LongWindow int
+	// Backoff multiplies the limit after a failed request, DEFAULT_BACKOFF when zero
+	Backoff float64
+}
+
+// GradientPolicy compares each request's latency with a long-running average, in the spirit of
+// TCP Vegas. While latency stays near the baseline the limit grows by a queue allowance of
+// sqrt(limit); as requests start queueing and slow down, the gradient baseline/latency drops
+// below one and pulls the limit down with it.
+type GradientPolicy struct {
+	config   GradientConfig
+	baseline float64
+}
+
+func NewGradientPolicy(config GradientConfig) *GradientPolicy {
+	if config.Smoothing <= 0 || config.Smoothing > 1 {
+		config.Smoothing = DEFAULT_GRADIENT_SMOOTHING
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = DEFAULT_GRADIENT_TOLERANCE
+	}
+	if config.LongWindow <= 0 {
+		config.LongWindow = DEFAULT_GRADIENT_LONG_WINDOW
+	}
+	if config.Backoff <= 0 || config.Backoff >= 1 {
+		config.Backoff = DEFAULT_BACKOFF
+	}
+	return &GradientPolicy{config: config}
+}
+
+func (g *GradientPolicy) Update(limit float64, sample Sample) float64 {
+	if sample.Failed {
+		return limit * g.config.Backoff
+	}
+	latency := float64(sample.Latency)
+	if latency <= 0 {
+		return limit
+	}
+	if g.baseline == 0 {
+		g.baseline = latency
+	}
+	g.baseline += (latency - g.baseline) / float64(g.config.LongWindow)
+
+	if float64(sample.InFlight)*2 < limit {
+		return limit
+	}
+
+	gradient := max(MIN_GRADIENT, min(1, g.config.Tolerance*g.baseline/latency))
+	target := limit*gradient + math.Sqrt(limit)
+	return limit*(1-g.config.Smoothing) + target*g.config.Smoothing
+}
@@ -0,0 +1,173 @@
+package bandwidth_limiter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const (
+	DEFAULT_CHUNK_SIZE    = 32 * 1024
+	DEFAULT_POLL_INTERVAL = 50 * time.Millisecond
+)
+
+// BandwidthConfig tunes how a BandwidthLimiter paces transfers
+type BandwidthConfig struct {
+	// ChunkSize is the most bytes taken from the budget and moved per round trip,
+	// DEFAULT_CHUNK_SIZE when zero. Smaller chunks pace more smoothly at the cost of more round trips.
+	ChunkSize int
+	// PollInterval is how often a transfer checks the budget again while it is used up,
+	// DEFAULT_POLL_INTERVAL when zero
+	PollInterval time.Duration
+}
+
+// BandwidthLimiter caps the bytes per second each key may transfer. Every byte is one unit of the
+// underlying limiter, usually a token bucket whose capacity is the burst in bytes and whose refill
+// rate is the bytes per second, so the budget of a key is shared by every instance using the same
+// Redis. Transfers take their budget in chunks and wait while it is used up. Build the token bucket
+// with rate_limiter.WithMillisecondRefill, or it earns its budget back once a second in one burst.
+type BandwidthLimiter struct {
+	limiter rate_limiter.LeasableInterface
+	config  BandwidthConfig
+}
+
+func NewBandwidthLimiter(limiter rate_limiter.LeasableInterface, config BandwidthConfig) *BandwidthLimiter {
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DEFAULT_CHUNK_SIZE
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DEFAULT_POLL_INTERVAL
+	}
+	return &BandwidthLimiter{
+		limiter: limiter,
+		config:  config,
+	}
+}
+
+// Take waits until clientId has budget left, or ctx is done, and takes up to n bytes of it, never
+// more than ChunkSize. The grant's Units is how many bytes were taken, at least one unless it fails.
+func (b *BandwidthLimiter) Take(ctx context.Context, clientId string, n int) (rate_limiter.LeaseGrant, error) {
+	n = min(n, b.config.ChunkSize)
+	if n <= 0 {
+		return rate_limiter.LeaseGrant{}, nil
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return rate_limiter.LeaseGrant{}, ctx.Err()
+		case <-timer.C:
+		}
+
+		grant, err := b.limiter.Lease(clientId, n)
+		if err != nil || grant.Units > 0 {
+			return grant, err
+		}
+		timer.Reset(b.config.PollInterval)
+	}
+}
+
+// Return gives back n bytes of grant that were taken but not transferred
+func (b *BandwidthLimiter) Return(grant rate_limiter.LeaseGrant, n int) error {
+	return b.limiter.ReturnLease(grant, n)
+}
+
+// Reader paces reads from an io.Reader to the budget of a key
+type Reader struct {
+	ctx      context.Context
+	clientId string
+	reader   io.Reader
+	limiter  *BandwidthLimiter
+}
+
+// Reader wraps reader so that reads spend clientId's budget. A read returns at most one chunk and
+// fails with ctx's error once ctx is done.
+func (b *BandwidthLimiter) Reader(ctx context.Context, clientId string, reader io.Reader) *Reader {
+	return &Reader{ctx: ctx, clientId: clientId, reader: reader, limiter: b}
+}
+
+// Read reads as many bytes as the budget allows, and gives back the bytes the underlying reader
+// did not fill
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return r.reader.Read(p)
+	}
+	grant, err := r.limiter.Take(r.ctx, r.clientId, len(p))
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.reader.Read(p[:grant.Units])
+	if unused := grant.Units - n; unused > 0 {
+		if returnErr := r.limiter.Return(grant, unused); err == nil {
+			err = returnErr
+		}
+	}
+	return n, err
+}
+
+// Writer paces writes to an io.Writer to the budget of a key
+type Writer struct {
+	ctx      context.Context
+	clientId string
+	writer   io.Writer
+	limiter  *BandwidthLimiter
+}
+
+// Writer wraps writer so that writes spend clientId's budget. Writes are split into chunks the
+// budget allows and stop with ctx's error once ctx is done, reporting the bytes already written.
+func (b *BandwidthLimiter) Writer(ctx context.Context, clientId string, writer io.Writer) *Writer {
+	return &Writer{ctx: ctx, clientId: clientId, writer: writer, limiter: b}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		grant, err := w.limiter.Take(w.ctx, w.clientId, len(p)-written)
+		if err != nil {
+			return written, err
+		}
+		n, err := w.writer.Write(p[written : written+grant.Units])
+		written += n
+		if unused := grant.Units - n; unused > 0 {
+			if returnErr := w.limiter.Return(grant, unused); err == nil {
+				err = returnErr
+			}
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ResponseWriter paces the body of an HTTP response to the budget of a key
+type ResponseWriter struct {
+	http.ResponseWriter
+	writer *Writer
+}
+
+// ResponseWriter wraps w so that the response body spends clientId's budget. Pass the request's
+// context so that a client hanging up stops the transfer.
+func (b *BandwidthLimiter) ResponseWriter(ctx context.Context, clientId string, w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w, writer: b.Writer(ctx, clientId, w)}
+}
+
+func (w *ResponseWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+// Flush sends buffered data to the client when the wrapped writer supports it
+func (w *ResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,13 @@
+package bandwidth_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BandwidthLimiter Suite")
+}
@@ -0,0 +1,165 @@
+package bandwidth_limiter_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/bandwidth_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("BandwidthLimiter", func() {
+	var (
+		clock   *rate_limiter.ManualClock
+		bucket  *token_bucket_ratelimiter.TokenBucketRateLimiter
+		limiter *bandwidth_limiter.BandwidthLimiter
+	)
+
+	BeforeEach(func() {
+		clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		// A burst of 10 bytes refilled at 10 bytes per second
+		bucket = token_bucket_ratelimiter.NewTokenBucketRateLimiter(rate_limiter.NewMemoryClient(clock), 10, 10, rate_limiter.WithClock(clock))
+		limiter = bandwidth_limiter.NewBandwidthLimiter(bucket, bandwidth_limiter.BandwidthConfig{ChunkSize: 4, PollInterval: time.Millisecond})
+	})
+
+	// advanceUntil moves the clock a second at a time until done is closed, and returns the seconds it took
+	advanceUntil := func(done <-chan struct{}) int {
+		seconds := 0
+		for {
+			select {
+			case <-done:
+				return seconds
+			case <-time.After(5 * time.Millisecond):
+				clock.Advance(time.Second)
+				seconds++
+			}
+		}
+	}
+
+	It("should write in chunks within the budget", func() {
+		var sizes []int
+		recorder := &recordingWriter{sizes: &sizes}
+
+		n, err := limiter.Writer(context.Background(), "tenant", recorder).Write([]byte("0123456789"))
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(10))
+		Expect(sizes).To(Equal([]int{4, 4, 2}))
+		Expect(recorder.String()).To(Equal("0123456789"))
+	})
+
+	It("should wait for the budget to refill", func() {
+		var buffer bytes.Buffer
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			n, err := io.Copy(limiter.Writer(context.Background(), "tenant", &buffer), strings.NewReader(strings.Repeat("x", 25)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(int64(25)))
+		}()
+
+		Expect(advanceUntil(done)).To(BeNumerically(">=", 2))
+		Expect(buffer.Len()).To(Equal(25))
+	})
+
+	It("should pace within a second on a bucket that refills in milliseconds", func() {
+		// A burst of 100 bytes refilled at 1000 bytes per second, one byte per millisecond
+		bucket = token_bucket_ratelimiter.NewTokenBucketRateLimiter(rate_limiter.NewMemoryClient(clock), 100, 1000,
+			rate_limiter.WithClock(clock), rate_limiter.WithMillisecondRefill())
+		limiter = bandwidth_limiter.NewBandwidthLimiter(bucket, bandwidth_limiter.BandwidthConfig{ChunkSize: 100})
+		grant, err := limiter.Take(context.Background(), "tenant", 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant.Units).To(Equal(100))
+
+		clock.Advance(50 * time.Millisecond)
+		grant, err = limiter.Take(context.Background(), "tenant", 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant.Units).To(Equal(50))
+
+		clock.Advance(250 * time.Millisecond)
+		grant, err = limiter.Take(context.Background(), "tenant", 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant.Units).To(Equal(100))
+	})
+
+	It("should read within the budget and return what the reader did not fill", func() {
+		reader := limiter.Reader(context.Background(), "tenant", strings.NewReader("abc"))
+		p := make([]byte, 4)
+
+		n, err := reader.Read(p)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(p[:n])).To(Equal("abc"))
+		_, err = reader.Read(p)
+		Expect(err).To(Equal(io.EOF))
+
+		state, err := bucket.Peek(context.Background(), "tenant")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Remaining).To(Equal(int64(7)))
+	})
+
+	It("should stop a transfer when its context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		var buffer bytes.Buffer
+		type result struct {
+			n   int
+			err error
+		}
+		results := make(chan result, 1)
+		go func() {
+			n, err := limiter.Writer(ctx, "tenant", &buffer).Write(make([]byte, 25))
+			results <- result{n, err}
+		}()
+
+		Consistently(results, 20*time.Millisecond).ShouldNot(Receive())
+		cancel()
+		var got result
+		Eventually(results).Should(Receive(&got))
+		Expect(got.n).To(Equal(10))
+		Expect(got.err).To(MatchError(context.Canceled))
+
+		_, err := limiter.Reader(ctx, "tenant", strings.NewReader("abc")).Read(make([]byte, 4))
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should keep budgets per key", func() {
+		_, err := limiter.Writer(context.Background(), "tenant", io.Discard).Write(make([]byte, 10))
+		Expect(err).NotTo(HaveOccurred())
+
+		grant, err := limiter.Take(context.Background(), "other-tenant", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant.Units).To(Equal(4))
+	})
+
+	It("should pace HTTP response bodies and pass flushes through", func() {
+		recorder := httptest.NewRecorder()
+		writer := limiter.ResponseWriter(context.Background(), "tenant", recorder)
+
+		_, err := writer.Write([]byte("hello"))
+		writer.Flush()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Body.String()).To(Equal("hello"))
+		Expect(recorder.Flushed).To(BeTrue())
+		Expect(writer.Unwrap()).To(BeIdenticalTo(recorder))
+	})
+})
+
+// recordingWriter records the size of every write it receives
+type recordingWriter struct {
+	bytes.Buffer
+	sizes *[]int
+}
+
+func (r *recordingWriter) Write(p []byte) (int, error) {
+	*r.sizes = append(*r.sizes, len(p))
+	return r.Buffer.Write(p)
+}
@@ -0,0 +1,11 @@
+package bandwidth_limiter
+
+import (
+	"io"
+	"net/http"
+)
+
+var _ io.Reader = (*Reader)(nil)
+var _ io.Writer = (*Writer)(nil)
+var _ http.ResponseWriter = (*ResponseWriter)(nil)
+var _ http.Flusher = (*ResponseWriter)(nil)
@@ -0,0 +1,153 @@
+package benchmarks_test
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/simulator"
+)
+
+const (
+	BENCHMARK_LIMIT  = 100
+	BENCHMARK_WINDOW = time.Minute
+	// HIGH_CARDINALITY_KEYS is how many distinct clients the high-cardinality benchmarks cycle through
+	HIGH_CARDINALITY_KEYS = 100_000
+	// PARALLEL_KEYS is how many clients the parallel benchmarks share, so goroutines contend on some keys
+	PARALLEL_KEYS = 64
+)
+
+// roundTripHook counts requests sent to Redis, counting a pipeline or transaction as one
+type roundTripHook struct {
+	count atomic.Int64
+}
+
+func (h *roundTripHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *roundTripHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.count.Add(1)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *roundTripHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.count.Add(1)
+		return next(ctx, cmds)
+	}
+}
+
+type backend struct {
+	name string
+	// new returns a fresh client and a counter of round trips, nil when the backend has none
+	new func(b *testing.B, clock rate_limiter.ClockInterface) (rate_limiter.RedisClientInterface, *atomic.Int64)
+}
+
+var backends = []backend{
+	{
+		name: "memory",
+		new: func(b *testing.B, clock rate_limiter.ClockInterface) (rate_limiter.RedisClientInterface, *atomic.Int64) {
+			return rate_limiter.NewMemoryClient(clock), nil
+		},
+	},
+	{
+		name: "miniredis",
+		new: func(b *testing.B, clock rate_limiter.ClockInterface) (rate_limiter.RedisClientInterface, *atomic.Int64) {
+			server := miniredis.RunT(b)
+			server.SetTime(clock.Now())
+
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			b.Cleanup(func() { _ = client.Close() })
+			hook := &roundTripHook{}
+			client.AddHook(hook)
+			return rate_limiter.NewRedisClient(client), &hook.count
+		},
+	},
+}
+
+func clientIds(count int) []string {
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = "client-" + strconv.Itoa(i)
+	}
+	return ids
+}
+
+func BenchmarkLimiters(b *testing.B) {
+	highCardinalityIds := clientIds(HIGH_CARDINALITY_KEYS)
+	parallelIds := clientIds(PARALLEL_KEYS)
+
+	for _, backend := range backends {
+		for _, algorithm := range simulator.Algorithms() {
+			newLimiter := func(b *testing.B) (rate_limiter.RateLimiterInterface, *atomic.Int64) {
+				clock := rate_limiter.NewManualClock(simulator.START_TIME)
+				client, roundTrips := backend.new(b, clock)
+				return algorithm.New(client, BENCHMARK_LIMIT, BENCHMARK_WINDOW, rate_limiter.WithClock(clock)), roundTrips
+			}
+			name := backend.name + "/" + algorithm.Name
+
+			b.Run(name+"/single_key", func(b *testing.B) {
+				limiter, roundTrips := newLimiter(b)
+				run(b, roundTrips, func(allowed *atomic.Int64) {
+					for range b.N {
+						if limiter.LimitRequests("hot-client") {
+							allowed.Add(1)
+						}
+					}
+				})
+			})
+
+			b.Run(name+"/high_cardinality", func(b *testing.B) {
+				limiter, roundTrips := newLimiter(b)
+				run(b, roundTrips, func(allowed *atomic.Int64) {
+					for i := range b.N {
+						if limiter.LimitRequests(highCardinalityIds[i%HIGH_CARDINALITY_KEYS]) {
+							allowed.Add(1)
+						}
+					}
+				})
+			})
+
+			b.Run(name+"/parallel", func(b *testing.B) {
+				limiter, roundTrips := newLimiter(b)
+				run(b, roundTrips, func(allowed *atomic.Int64) {
+					var next atomic.Int64
+					b.RunParallel(func(pb *testing.PB) {
+						for pb.Next() {
+							if limiter.LimitRequests(parallelIds[next.Add(1)%PARALLEL_KEYS]) {
+								allowed.Add(1)
+							}
+						}
+					})
+				})
+			})
+		}
+	}
+}
+
+// run times body and reports allocations, round trips and the share of requests allowed per decision
+func run(b *testing.B, roundTrips *atomic.Int64, body func(allowed *atomic.Int64)) {
+	var allowed atomic.Int64
+	b.ReportAllocs()
+	if roundTrips != nil {
+		roundTrips.Store(0)
+	}
+	b.ResetTimer()
+
+	body(&allowed)
+
+	b.StopTimer()
+	if roundTrips != nil {
+		b.ReportMetric(float64(roundTrips.Load())/float64(b.N), "roundtrips/op")
+	}
+	b.ReportMetric(float64(allowed.Load())/float64(b.N), "allowed/op")
+}
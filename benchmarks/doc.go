@@ -0,0 +1,11 @@
+// Package benchmarks measures every limiter against the in-memory store and miniredis under
+// single-key contention, high key cardinality and parallel load. Run it with
+//
+//	go test -run '^$' -bench . ./benchmarks/
+//
+// Besides ns/op and allocs/op, each benchmark reports roundtrips/op (Redis requests per decision,
+// counting a pipeline or script as one) and allowed/op. The clock is frozen during a run, so
+// single-key runs mostly measure the denial path of a hot key and high-cardinality runs mostly
+// measure admissions. miniredis runs in-process, so its allocations include the server's own work
+// and are only comparable between miniredis runs.
+package benchmarks
@@ -0,0 +1,243 @@
+package calendar_quota_rate_limiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+// QUOTA_EXPIRY_SLACK keeps a period's counter a little past its reset, so that instances whose
+// clocks lag behind still find it
+const QUOTA_EXPIRY_SLACK = time.Minute
+
+// Period is the calendar unit a quota resets on
+type Period string
+
+const (
+	// PERIOD_DAY resets at midnight
+	PERIOD_DAY Period = "day"
+	// PERIOD_WEEK resets at midnight between Sunday and Monday
+	PERIOD_WEEK Period = "week"
+	// PERIOD_MONTH resets at midnight on the first of the month
+	PERIOD_MONTH Period = "month"
+)
+
+// periodBounds is the period of a client's quota that contains a given instant
+type periodBounds struct {
+	// date is the calendar date the period starts on, which suffixes its counter key
+	date  string
+	start time.Time
+	end   time.Time
+}
+
+// Usage is how much of its quota a client has used in the current period
+type Usage struct {
+	Key       string
+	Limit     int64
+	Used      int64
+	Remaining int64
+	// PeriodStart and ResetAt bound the current period, in the client's time zone
+	PeriodStart time.Time
+	ResetAt     time.Time
+}
+
+// CalendarQuotaRateLimiter counts requests in windows aligned to the calendar, such as a day from
+// midnight to midnight in the client's time zone, rather than in rolling windows that start at the
+// first request. Periods are computed with calendar arithmetic in the client's location, so days
+// around a DST change last 23 or 25 hours. Each period has its own counter key, suffixed with the
+// date the period starts on.
+type CalendarQuotaRateLimiter struct {
+	redisClient rate_limiter.RedisClientInterface
+	period      Period
+	limit       int
+	options     rate_limiter.Options
+
+	locations sync.Map
+}
+
+// NewCalendarQuotaRateLimiter allows limit requests per period. Clients reset in the location set
+// with rate_limiter.WithLocation, or in the TimeZone of their override.
+func NewCalendarQuotaRateLimiter(redisClient rate_limiter.RedisClientInterface, period Period, limit int, opts ...rate_limiter.Option) *CalendarQuotaRateLimiter {
+	return &CalendarQuotaRateLimiter{
+		redisClient: redisClient,
+		period:      period,
+		limit:       limit,
+		options:     rate_limiter.NewOptions(opts...),
+	}
+}
+
+func (c *CalendarQuotaRateLimiter) LimitRequests(clientId string) bool {
+	return c.Decide(clientId).Allowed
+}
+
+func (c *CalendarQuotaRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	decision := c.decide(clientId)
+	c.options.Logger.Log(decision)
+	return decision
+}
+
+func (c *CalendarQuotaRateLimiter) decide(clientId string) rate_limiter.Decision {
+	override := c.options.Override(clientId)
+	now := c.options.Clock.Now()
+	period, err := c.bounds(now, override)
+	decision := rate_limiter.Decision{
+		Algorithm: rate_limiter.ALGORITHM_CALENDAR_QUOTA,
+		Key:       clientId,
+		Limit:     override.LimitOr(int64(c.limit)),
+		Window:    period.end.Sub(period.start),
+	}
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
+
+	key := c.key(clientId, period)
+	used, err := c.used(key)
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
+	if used >= decision.Limit {
+		return decision
+	}
+
+	used, err = c.redisClient.IncrWithExpiry(key, period.end.Sub(now)+QUOTA_EXPIRY_SLACK, rate_limiter.EXPIRY_MODE_NX)
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
+	// A concurrent request may have used the last unit since the read; quotas are billed, so give it back
+	if used > decision.Limit {
+		if _, err := c.redisClient.IncrByIfExists(key, -1); err != nil {
+			decision.Err = err
+		}
+		return decision
+	}
+
+	decision.Allowed = true
+	decision.Remaining = decision.Limit - used
+	return decision
+}
+
+// Usage reports the client's consumption in the current period without counting a request
+func (c *CalendarQuotaRateLimiter) Usage(ctx context.Context, clientId string) (Usage, error) {
+	override := c.options.Override(clientId)
+	usage := Usage{Key: clientId, Limit: override.LimitOr(int64(c.limit))}
+	if err := ctx.Err(); err != nil {
+		return usage, err
+	}
+	period, err := c.bounds(c.options.Clock.Now(), override)
+	if err != nil {
+		return usage, err
+	}
+	usage.PeriodStart, usage.ResetAt = period.start, period.end
+
+	if usage.Used, err = c.used(c.key(clientId, period)); err != nil {
+		return usage, err
+	}
+	usage.Remaining = max(usage.Limit-usage.Used, 0)
+	return usage, nil
+}
+
+// Peek reports the quota left until the next calendar reset
+func (c *CalendarQuotaRateLimiter) Peek(ctx context.Context, clientId string) (rate_limiter.KeyState, error) {
+	usage, err := c.Usage(ctx, clientId)
+	state := rate_limiter.KeyState{
+		Algorithm: rate_limiter.ALGORITHM_CALENDAR_QUOTA,
+		Key:       clientId,
+		Limit:     usage.Limit,
+		Remaining: usage.Remaining,
+	}
+	if err == nil && usage.Used > 0 {
+		state.ResetAfter = max(usage.ResetAt.Sub(c.options.Clock.Now()), 0)
+	}
+	return state, err
+}
+
+// Reset deletes the counter of the current period
+func (c *CalendarQuotaRateLimiter) Reset(ctx context.Context, clientId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	period, err := c.bounds(c.options.Clock.Now(), c.options.Override(clientId))
+	if err != nil {
+		return err
+	}
+	_, err = c.redisClient.Del(c.key(clientId, period))
+	return err
+}
+
+func (c *CalendarQuotaRateLimiter) used(key string) (int64, error) {
+	usedStr, err := c.redisClient.Get(key)
+	if err != nil || usedStr == "" {
+		return 0, err
+	}
+	return strconv.ParseInt(usedStr, 10, 64)
+}
+
+// key suffixes the client's key with the date its period starts on in its own time zone
+func (c *CalendarQuotaRateLimiter) key(clientId string, period periodBounds) string {
+	return c.options.Key(clientId) + ":" + period.date
+}
+
+// bounds returns the period containing now in the client's location. The period is worked out on
+// calendar dates first, so its key never depends on when its first day happens to start, and only
+// then turned into the instants the local days start at.
+func (c *CalendarQuotaRateLimiter) bounds(now time.Time, override rate_limiter.Override) (periodBounds, error) {
+	location, err := c.location(override)
+	if err != nil {
+		return periodBounds{}, err
+	}
+	local := now.In(location)
+	year, month, day := local.Date()
+
+	// first and next hold calendar dates; UTC has no DST, so AddDate moves them by whole days
+	var first, next time.Time
+	switch c.period {
+	case PERIOD_DAY:
+		first = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		next = first.AddDate(0, 0, 1)
+	case PERIOD_WEEK:
+		sinceMonday := (int(local.Weekday()) + 6) % 7
+		first = time.Date(year, month, day-sinceMonday, 0, 0, 0, 0, time.UTC)
+		next = first.AddDate(0, 0, 7)
+	case PERIOD_MONTH:
+		first = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		next = first.AddDate(0, 1, 0)
+	default:
+		return periodBounds{}, fmt.Errorf("unknown quota period %q", c.period)
+	}
+	return periodBounds{date: first.Format(time.DateOnly), start: dayStart(first, location), end: dayStart(next, location)}, nil
+}
+
+// dayStart returns the first instant of the calendar date in location. Where DST starts at
+// midnight, as in America/Santiago, the date has no 00:00 and time.Date falls back to an instant of
+// the previous day, so that instant is moved forward to when the clocks jump.
+func dayStart(date time.Time, location *time.Location) time.Time {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location)
+	wall := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), time.UTC)
+	if wall.Before(date) {
+		return start.Add(date.Sub(wall))
+	}
+	return start
+}
+
+// location returns the client's time zone, loading each named zone once
+func (c *CalendarQuotaRateLimiter) location(override rate_limiter.Override) (*time.Location, error) {
+	if override.TimeZone == "" {
+		return c.options.Location, nil
+	}
+	if location, ok := c.locations.Load(override.TimeZone); ok {
+		return location.(*time.Location), nil
+	}
+	location, err := time.LoadLocation(override.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	c.locations.Store(override.TimeZone, location)
+	return location, nil
+}
@@ -0,0 +1,13 @@
+package calendar_quota_rate_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCalendarQuotaRateLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CalendarQuotaRateLimiter Suite")
+}
@@ -0,0 +1,161 @@
+package calendar_quota_rate_limiter_test
+
+import (
+	"context"
+	"time"
+	_ "time/tzdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/calendar_quota_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("CalendarQuotaRateLimiter", func() {
+	var (
+		clock  *rate_limiter.ManualClock
+		client *rate_limiter.MemoryClient
+	)
+
+	mustLoad := func(name string) *time.Location {
+		location, err := time.LoadLocation(name)
+		Expect(err).NotTo(HaveOccurred())
+		return location
+	}
+
+	start := func(now time.Time) {
+		clock = rate_limiter.NewManualClock(now)
+		client = rate_limiter.NewMemoryClient(clock)
+	}
+
+	It("should reset at midnight in the configured time zone", func() {
+		berlin := mustLoad("Europe/Berlin")
+		start(time.Date(2024, time.June, 1, 23, 30, 0, 0, berlin))
+		limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_DAY, 2,
+			rate_limiter.WithClock(clock), rate_limiter.WithLocation(berlin))
+
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+		Expect(limiter.LimitRequests("customer")).To(BeFalse())
+
+		clock.Advance(29 * time.Minute)
+		Expect(limiter.LimitRequests("customer")).To(BeFalse())
+		clock.Advance(time.Minute)
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+	})
+
+	DescribeTable("should follow the start of the local day across DST changes",
+		func(day time.Time, length time.Duration) {
+			start(day)
+			limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_DAY, 1,
+				rate_limiter.WithClock(clock), rate_limiter.WithLocation(day.Location()))
+
+			decision := limiter.Decide("customer")
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.Window).To(Equal(length))
+
+			usage, err := limiter.Usage(context.Background(), "customer")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(usage.PeriodStart).To(BeTemporally("==", day))
+			Expect(usage.ResetAt).To(BeTemporally("==", day.Add(length)))
+
+			clock.Advance(length - time.Second)
+			Expect(limiter.LimitRequests("customer")).To(BeFalse())
+			clock.Advance(time.Second)
+			Expect(limiter.LimitRequests("customer")).To(BeTrue())
+		},
+		Entry("spring forward", time.Date(2024, time.March, 10, 0, 0, 0, 0, mustLoadLocation("America/New_York")), 23*time.Hour),
+		Entry("fall back", time.Date(2024, time.November, 3, 0, 0, 0, 0, mustLoadLocation("America/New_York")), 25*time.Hour),
+		// Santiago and Havana change their clocks at midnight, so the day of a spring forward starts at 01:00
+		Entry("spring forward at midnight", time.Date(2024, time.September, 8, 1, 0, 0, 0, mustLoadLocation("America/Santiago")), 23*time.Hour),
+		Entry("fall back at midnight", time.Date(2024, time.April, 6, 0, 0, 0, 0, mustLoadLocation("America/Santiago")), 25*time.Hour),
+		Entry("spring forward at midnight in Havana", time.Date(2024, time.March, 10, 1, 0, 0, 0, mustLoadLocation("America/Havana")), 23*time.Hour),
+	)
+
+	It("should not carry usage into a day that starts after a midnight DST change", func() {
+		santiago := mustLoad("America/Santiago")
+		start(time.Date(2024, time.September, 7, 22, 0, 0, 0, santiago))
+		limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_DAY, 1,
+			rate_limiter.WithClock(clock), rate_limiter.WithLocation(santiago))
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+
+		clock.Advance(90 * time.Minute)
+		Expect(clock.Now().In(santiago).Day()).To(Equal(7))
+		Expect(limiter.LimitRequests("customer")).To(BeFalse())
+
+		clock.Advance(time.Hour)
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+		Expect(client.Get("rate_limit:customer:2024-09-08")).To(Equal("1"))
+	})
+
+	It("should reset monthly quotas on the first of the month", func() {
+		start(time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC))
+		limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_MONTH, 100, rate_limiter.WithClock(clock))
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+
+		usage, err := limiter.Usage(context.Background(), "customer")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usage.Used).To(Equal(int64(1)))
+		Expect(usage.Remaining).To(Equal(int64(99)))
+		Expect(usage.PeriodStart).To(BeTemporally("==", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(usage.ResetAt).To(BeTemporally("==", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("should start weekly quotas on Monday", func() {
+		// A Sunday
+		start(time.Date(2024, time.June, 2, 18, 0, 0, 0, time.UTC))
+		limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_WEEK, 100, rate_limiter.WithClock(clock))
+
+		usage, err := limiter.Usage(context.Background(), "customer")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usage.PeriodStart).To(BeTemporally("==", time.Date(2024, time.May, 27, 0, 0, 0, 0, time.UTC)))
+		Expect(usage.ResetAt).To(BeTemporally("==", time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("should reset each client in the time zone of its override", func() {
+		start(time.Date(2024, time.June, 1, 15, 30, 0, 0, time.UTC))
+		resolver := rate_limiter.NewMemoryOverrideResolver(map[string]rate_limiter.Override{
+			"tokyo-customer": {TimeZone: "Asia/Tokyo"},
+			"broken":         {TimeZone: "Mars/Olympus_Mons"},
+		})
+		limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_DAY, 1,
+			rate_limiter.WithClock(clock), rate_limiter.WithOverrideResolver(resolver))
+
+		tokyo, err := limiter.Usage(context.Background(), "tokyo-customer")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tokyo.PeriodStart).To(BeTemporally("==", time.Date(2024, time.June, 1, 15, 0, 0, 0, time.UTC)))
+
+		utc, err := limiter.Usage(context.Background(), "utc-customer")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(utc.PeriodStart).To(BeTemporally("==", time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)))
+
+		decision := limiter.Decide("broken")
+		Expect(decision.Allowed).To(BeFalse())
+		Expect(decision.Err).To(HaveOccurred())
+	})
+
+	It("should peek and reset the current period", func() {
+		start(time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC))
+		limiter := calendar_quota_rate_limiter.NewCalendarQuotaRateLimiter(client, calendar_quota_rate_limiter.PERIOD_DAY, 1, rate_limiter.WithClock(clock))
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+
+		state, err := limiter.Peek(context.Background(), "customer")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Remaining).To(BeZero())
+		Expect(state.ResetAfter).To(Equal(12 * time.Hour))
+
+		Expect(limiter.Reset(context.Background(), "customer")).To(Succeed())
+		Expect(limiter.LimitRequests("customer")).To(BeTrue())
+	})
+})
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
@@ -0,0 +1,7 @@
+package calendar_quota_rate_limiter
+
+import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+
+var _ rate_limiter.RateLimiterInterface = (*CalendarQuotaRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*CalendarQuotaRateLimiter)(nil)
+var _ rate_limiter.InspectorInterface = (*CalendarQuotaRateLimiter)(nil)
@@ -0,0 +1,64 @@
+// Command rlcleanup gives an expiry to token bucket keys that were written without one, so that
+// buckets of clients that never come back stop piling up in Redis. Run it once after upgrading;
+// buckets written since then expire on their own. Pass several comma-separated addresses for Redis
+// Cluster, and set -ttl to at least the longest time any bucket takes to refill.
+//
+//	go run ./cmd/rlcleanup -addrs localhost:6379 -ttl 1h -dry-run
+//	go run ./cmd/rlcleanup -addrs node-1:6379,node-2:6379 -ttl 1h
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "rlcleanup:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("rlcleanup", flag.ContinueOnError)
+	addrs := flags.String("addrs", "localhost:6379", "comma-separated Redis addresses, several for Redis Cluster")
+	password := flags.String("password", "", "Redis password")
+	prefix := flags.String("prefix", rate_limiter.DEFAULT_KEY_PREFIX, "key prefix the token buckets were written under")
+	ttl := flags.Duration("ttl", 0, "expiry to give every bucket key that has none")
+	scanCount := flags.Int64("scan-count", rate_limiter.DEFAULT_CLEANUP_SCAN_COUNT, "SCAN COUNT hint")
+	dryRun := flags.Bool("dry-run", false, "only count the keys that would be given an expiry")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ttl <= 0 {
+		return errors.New("-ttl must be positive")
+	}
+
+	client := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:    strings.Split(*addrs, ","),
+		Password: *password,
+	})
+	defer client.Close()
+
+	stats, err := rate_limiter.NewRedisClient(client).ExpireOrphanedKeys(rate_limiter.CleanupConfig{
+		Patterns:  token_bucket_ratelimiter.KeyPatterns(*prefix),
+		Expiry:    *ttl,
+		ScanCount: *scanCount,
+		DryRun:    *dryRun,
+	})
+	verb := "expired"
+	if *dryRun {
+		verb = "would expire"
+	}
+	fmt.Fprintf(stdout, "scanned %d keys, %s %d\n", stats.Scanned, verb, stats.Expired)
+	return err
+}
@@ -0,0 +1,158 @@
+// Command rlsim replays synthetic traffic against every rate limiting algorithm on a virtual clock
+// and the in-memory backend, and reports how each one behaved. Given an access log with -trace, it
+// instead replays the log through a set of limits and reports which clients would have been denied.
+//
+//	go run ./cmd/rlsim -limit 10 -window 10s -rate 2 -duration 10m -format csv
+//	go run ./cmd/rlsim -trace access.log -limits fixed_window_counter:100/60s,token_bucket:10/1s -top 20
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/simulator"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "rlsim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("rlsim", flag.ContinueOnError)
+	limit := flags.Int("limit", 10, "requests admitted per window")
+	window := flags.Duration("window", 10*time.Second, "window length, in whole seconds")
+	rate := flags.Float64("rate", 2, "mean offered load in requests per second")
+	duration := flags.Duration("duration", 10*time.Minute, "virtual time to simulate")
+	seed := flags.Int64("seed", 1, "seed for the random patterns")
+	patternNames := flags.String("patterns", "", "comma-separated patterns to run, all by default")
+	format := flags.String("format", "csv", "output format, csv or json")
+	output := flags.String("output", "", "file to write to instead of stdout")
+	tracePath := flags.String("trace", "", "access log to replay instead of synthetic traffic")
+	traceFormat := flags.String("trace-format", string(simulator.TRACE_FORMAT_COMMON), "access log format, common, json or csv")
+	timeField := flags.String("time-field", simulator.DEFAULT_TRACE_TIME_FIELD, "json field or csv column holding the timestamp")
+	keyField := flags.String("key-field", "", "json field or csv column holding the client key, or host or user for common logs")
+	limitSpecs := flags.String("limits", "", "comma-separated limits to replay through, each algorithm:limit/window")
+	timelineBucket := flags.Duration("bucket", simulator.DEFAULT_TIMELINE_BUCKET, "width of each denial timeline bucket")
+	top := flags.Int("top", 0, "only report the most denied clients, all clients when 0")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	w := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if *tracePath != "" {
+		traceConfig := simulator.TraceConfig{
+			Format:    simulator.TraceFormat(*traceFormat),
+			TimeField: *timeField,
+			KeyField:  *keyField,
+		}
+		return replay(w, *tracePath, traceConfig, *limitSpecs, simulator.ReplayConfig{TimelineBucket: *timelineBucket}, *top, *format)
+	}
+
+	patterns, err := selectPatterns(*patternNames)
+	if err != nil {
+		return err
+	}
+
+	config := simulator.Config{
+		Limit:    *limit,
+		Window:   *window,
+		Rate:     *rate,
+		Duration: *duration,
+		Seed:     *seed,
+	}
+	results, err := simulator.Run(config, patterns, simulator.Algorithms())
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "csv":
+		return simulator.WriteCSV(w, results)
+	case "json":
+		return simulator.WriteJSON(w, results)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func replay(w io.Writer, tracePath string, traceConfig simulator.TraceConfig, limitSpecs string, replayConfig simulator.ReplayConfig, top int, format string) error {
+	if limitSpecs == "" {
+		return errors.New("-limits is required with -trace")
+	}
+	var limits []simulator.ReplayLimit
+	for _, spec := range strings.Split(limitSpecs, ",") {
+		limit, err := simulator.ParseReplayLimit(strings.TrimSpace(spec))
+		if err != nil {
+			return err
+		}
+		limits = append(limits, limit)
+	}
+
+	file, err := os.Open(tracePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	trace, err := simulator.ReadTrace(file, traceConfig)
+	if err != nil {
+		return err
+	}
+	if trace.Skipped > 0 {
+		fmt.Fprintf(os.Stderr, "rlsim: skipped %d unparseable lines\n", trace.Skipped)
+	}
+
+	report, err := simulator.Replay(trace, limits, replayConfig)
+	if err != nil {
+		return err
+	}
+	if top > 0 {
+		report.Keys = report.Top(top)
+	}
+
+	switch format {
+	case "csv":
+		return simulator.WriteReplayCSV(w, report.Keys)
+	case "json":
+		return simulator.WriteReplayJSON(w, report)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func selectPatterns(names string) ([]simulator.Pattern, error) {
+	patterns := simulator.Patterns()
+	if names == "" {
+		return patterns, nil
+	}
+
+	var selected []simulator.Pattern
+	for _, name := range strings.Split(names, ",") {
+		index := slices.IndexFunc(patterns, func(pattern simulator.Pattern) bool {
+			return pattern.Name == strings.TrimSpace(name)
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("unknown pattern %q", name)
+		}
+		selected = append(selected, patterns[index])
+	}
+	return selected, nil
+}
@@ -0,0 +1,103 @@
+package composite_rate_limiter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+// Request is one limiter and key to decide on in a batch
+type Request struct {
+	Limiter rate_limiter.RateLimiterInterface
+	Key     string
+}
+
+// AllowBatch decides every request independently and returns their decisions in order. Requests
+// whose limiters can run as a Lua script and whose client can pipeline scripts go to Redis in a
+// single round trip per client, one script per request; the rest are evaluated one by one. Scripted
+// decisions are logged and cached by their limiters as Decide would. Each decision carries its own
+// error, and the returned error summarizes them.
+func AllowBatch(ctx context.Context, requests []Request) ([]rate_limiter.Decision, error) {
+	decisions := make([]rate_limiter.Decision, len(requests))
+
+	batches := make(map[rate_limiter.BatchScriptRunnerInterface][]int)
+	steps := make([]rate_limiter.ScriptStep, len(requests))
+	var sequential []int
+	for i, request := range requests {
+		if scriptable, ok := request.Limiter.(rate_limiter.ScriptableInterface); ok {
+			steps[i] = scriptable.ScriptStep(request.Key)
+			if steps[i].Denied {
+				decisions[i] = rate_limiter.CompleteStep(steps[i], steps[i].Decision)
+				continue
+			}
+			if runner, ok := steps[i].Client.(rate_limiter.BatchScriptRunnerInterface); ok {
+				batches[runner] = append(batches[runner], i)
+				continue
+			}
+		}
+		sequential = append(sequential, i)
+	}
+
+	for runner, indexes := range batches {
+		calls := make([]rate_limiter.ScriptCall, len(indexes))
+		for j, i := range indexes {
+			calls[j] = scriptCall(steps[i])
+		}
+		for j, result := range runner.EvalBatch(ctx, calls) {
+			decisions[indexes[j]] = rate_limiter.CompleteStep(steps[indexes[j]], batchDecision(steps[indexes[j]], result))
+		}
+	}
+
+	for _, i := range sequential {
+		if err := ctx.Err(); err != nil {
+			decisions[i] = rate_limiter.Decision{Key: requests[i].Key, Err: err}
+			continue
+		}
+		decisions[i] = rate_limiter.Evaluate(requests[i].Limiter, requests[i].Key)
+	}
+
+	return decisions, batchError(decisions)
+}
+
+// scriptCall runs one step through the composite script, which checks and consumes in one go
+func scriptCall(step rate_limiter.ScriptStep) rate_limiter.ScriptCall {
+	args := append([]interface{}{1, string(step.Decision.Algorithm), len(step.Keys), len(step.Args)}, step.Args...)
+	return rate_limiter.ScriptCall{Script: compositeScript, Keys: step.Keys, Args: args}
+}
+
+func batchDecision(step rate_limiter.ScriptStep, result rate_limiter.ScriptResult) rate_limiter.Decision {
+	decision := step.Decision
+	reply, ok := result.Value.([]interface{})
+	if result.Err == nil && (!ok || len(reply) != 2) {
+		result.Err = fmt.Errorf("unexpected composite script reply %v", result.Value)
+	}
+	if result.Err != nil {
+		decision.Err = result.Err
+		return decision
+	}
+
+	deniedBy, _ := reply[0].(int64)
+	decision.Allowed = deniedBy == 0
+	if decision.Allowed {
+		decision.Remaining, _ = reply[1].(int64)
+	}
+	return decision
+}
+
+func batchError(decisions []rate_limiter.Decision) error {
+	var failed int
+	var first error
+	for _, decision := range decisions {
+		if decision.Err != nil {
+			failed++
+			if first == nil {
+				first = decision.Err
+			}
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return fmt.Errorf("%d of %d decisions failed: %w", failed, len(decisions), first)
+}
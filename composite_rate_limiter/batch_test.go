@@ -0,0 +1,161 @@
+package composite_rate_limiter_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/composite_rate_limiter"
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_log_rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+// pipelineCounter counts the pipelines sent to Redis
+type pipelineCounter struct {
+	count atomic.Int64
+}
+
+func (p *pipelineCounter) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (p *pipelineCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (p *pipelineCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		p.count.Add(1)
+		return next(ctx, cmds)
+	}
+}
+
+var _ = Describe("AllowBatch", func() {
+	var (
+		server    *miniredis.Miniredis
+		client    *rate_limiter.RedisClient
+		pipelines *pipelineCounter
+	)
+
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+
+		redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		DeferCleanup(redisClient.Close)
+		pipelines = &pipelineCounter{}
+		redisClient.AddHook(pipelines)
+		client = rate_limiter.NewRedisClient(redisClient)
+	})
+
+	It("should decide every limiter and key in one round trip", func() {
+		byIP := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 2, rate_limiter.WithKeyPrefix("rate_limit:ip:"))
+		// A fixed clock keeps the bucket from refilling between the two batches
+		clock := rate_limiter.NewManualClock(time.Now())
+		byUser := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 5, 1, rate_limiter.WithKeyPrefix("rate_limit:user:"), rate_limiter.WithClock(clock))
+		byAPIKey := sliding_window_log_rate_limiter.NewSlidingWindowLogRateLimiter(client, 3, 60, rate_limiter.WithKeyPrefix("rate_limit:key:"))
+		byRoute := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 4, 60, 1, rate_limiter.WithKeyPrefix("rate_limit:route:"))
+		requests := []composite_rate_limiter.Request{
+			{Limiter: byIP, Key: "10.0.0.1"},
+			{Limiter: byUser, Key: "user-1"},
+			{Limiter: byAPIKey, Key: "key-1"},
+			{Limiter: byRoute, Key: "/orders"},
+		}
+		// Loads the script, so that the batch below shows the steady state
+		_, err := composite_rate_limiter.AllowBatch(context.Background(), requests)
+		Expect(err).NotTo(HaveOccurred())
+		pipelines.count.Store(0)
+
+		decisions, err := composite_rate_limiter.AllowBatch(context.Background(), requests)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pipelines.count.Load()).To(Equal(int64(1)))
+		Expect(decisions).To(HaveLen(4))
+		for i, decision := range decisions {
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.Key).To(Equal(requests[i].Key))
+		}
+		Expect(decisions[0].Remaining).To(BeZero())
+		Expect(decisions[1].Remaining).To(Equal(int64(3)))
+		Expect(decisions[2].Remaining).To(Equal(int64(1)))
+		Expect(decisions[3].Remaining).To(Equal(int64(2)))
+	})
+
+	It("should deny requests independently of the rest of the batch", func() {
+		limiter := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 1)
+		Expect(limiter.LimitRequests("busy")).To(BeTrue())
+
+		decisions, err := composite_rate_limiter.AllowBatch(context.Background(), []composite_rate_limiter.Request{
+			{Limiter: limiter, Key: "busy"},
+			{Limiter: limiter, Key: "idle"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(decisions[0].Allowed).To(BeFalse())
+		Expect(decisions[1].Allowed).To(BeTrue())
+		Expect(server.Get("rate_limit:busy")).To(Equal("1"))
+	})
+
+	It("should log scripted decisions and deny cached keys without Redis", func() {
+		buffer := &bytes.Buffer{}
+		logger := rate_limiter.NewDecisionLogger(slog.New(slog.NewJSONHandler(buffer, nil)), rate_limiter.DecisionLoggerConfig{AllowSampleRate: 1})
+		limiter := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 1,
+			rate_limiter.WithDecisionLogger(logger), rate_limiter.WithDeniedCache(rate_limiter.NewDeniedCache(0)))
+		requests := []composite_rate_limiter.Request{{Limiter: limiter, Key: "busy"}}
+
+		for range 2 {
+			_, err := composite_rate_limiter.AllowBatch(context.Background(), requests)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(strings.Count(buffer.String(), "\n")).To(Equal(2))
+		pipelines.count.Store(0)
+
+		decisions, err := composite_rate_limiter.AllowBatch(context.Background(), requests)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(decisions[0].Allowed).To(BeFalse())
+		Expect(pipelines.count.Load()).To(BeZero())
+		Expect(strings.Count(buffer.String(), "\n")).To(Equal(3))
+	})
+
+	It("should evaluate limiters that cannot be scripted one by one", func() {
+		memoryLimiter := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(rate_limiter.NewMemoryClient(nil), 60, 1)
+
+		decisions, err := composite_rate_limiter.AllowBatch(context.Background(), []composite_rate_limiter.Request{
+			{Limiter: memoryLimiter, Key: "client"},
+			{Limiter: memoryLimiter, Key: "client"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(decisions[0].Allowed).To(BeTrue())
+		Expect(decisions[1].Allowed).To(BeFalse())
+	})
+
+	It("should report failed decisions without failing the others", func() {
+		failing := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(mocks.NewMockRedisClient(), 60, 1)
+		working := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 1)
+
+		decisions, err := composite_rate_limiter.AllowBatch(context.Background(), []composite_rate_limiter.Request{
+			{Limiter: failing, Key: "client"},
+			{Limiter: working, Key: "client"},
+		})
+
+		Expect(err).To(MatchError(ContainSubstring("1 of 2 decisions failed")))
+		Expect(decisions[0].Allowed).To(BeFalse())
+		Expect(decisions[0].Err).To(HaveOccurred())
+		Expect(decisions[1].Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,187 @@
+package composite_rate_limiter
+
+import (
+	"fmt"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+// CompositeMember is one of the limits that must all pass
+type CompositeMember struct {
+	Limiter rate_limiter.RateLimiterInterface
+	// KeyFunc maps the composite's client id to this member's key, nil uses the client id as-is.
+	// Returning a constant turns the member into a global limit.
+	KeyFunc func(clientId string) string
+}
+
+// CompositeDecision holds the outcome of every member for one request
+type CompositeDecision struct {
+	Allowed bool
+	// DeniedBy is the index of the first member that denied the request, -1 when allowed
+	DeniedBy  int
+	Decisions []rate_limiter.Decision
+	// Atomic reports whether the members were evaluated in a single Lua script
+	Atomic bool
+	Err    error
+}
+
+// CompositeRateLimiter allows a request only when every member allows it. When all members are
+// Redis-backed, share a client and their keys share a hash slot, the check and the consumption
+// happen in one Lua script. Otherwise members are evaluated in order and the quota taken by
+// earlier members is rolled back when a later one denies.
+type CompositeRateLimiter struct {
+	members []CompositeMember
+	options rate_limiter.Options
+}
+
+func NewCompositeRateLimiter(members []CompositeMember, opts ...rate_limiter.Option) *CompositeRateLimiter {
+	return &CompositeRateLimiter{
+		members: members,
+		options: rate_limiter.NewOptions(opts...),
+	}
+}
+
+func (c *CompositeRateLimiter) LimitRequests(clientId string) bool {
+	return c.Decide(clientId).Allowed
+}
+
+// Decide returns the denying member's decision, or the allowing member with the least quota left
+func (c *CompositeRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	composite := c.DecideAll(clientId)
+
+	decision := rate_limiter.Decision{Key: clientId, Allowed: composite.Allowed, Err: composite.Err}
+	if composite.DeniedBy >= 0 {
+		decision = composite.Decisions[composite.DeniedBy]
+		decision.Err = composite.Err
+	} else {
+		for i, member := range composite.Decisions {
+			if i == 0 || member.Remaining < decision.Remaining {
+				decision = member
+			}
+		}
+	}
+	decision.Key = clientId
+
+	c.options.Logger.Log(decision)
+	return decision
+}
+
+func (c *CompositeRateLimiter) DecideAll(clientId string) CompositeDecision {
+	if runner, steps, ok := c.scriptSteps(clientId); ok {
+		return c.decideAtomically(runner, steps)
+	}
+	return c.decideSequentially(clientId)
+}
+
+func (c *CompositeRateLimiter) memberKey(member CompositeMember, clientId string) string {
+	return rate_limiter.KeyFor(member.KeyFunc, clientId)
+}
+
+// scriptSteps returns the members' script steps when they can all run in one script
+func (c *CompositeRateLimiter) scriptSteps(clientId string) (rate_limiter.ScriptRunnerInterface, []rate_limiter.ScriptStep, bool) {
+	if len(c.members) == 0 {
+		return nil, nil, false
+	}
+
+	steps := make([]rate_limiter.ScriptStep, 0, len(c.members))
+	var keys []string
+	for _, member := range c.members {
+		scriptable, ok := member.Limiter.(rate_limiter.ScriptableInterface)
+		if !ok {
+			return nil, nil, false
+		}
+		step := scriptable.ScriptStep(c.memberKey(member, clientId))
+		if len(steps) > 0 && step.Client != steps[0].Client {
+			return nil, nil, false
+		}
+		steps = append(steps, step)
+		keys = append(keys, step.Keys...)
+	}
+
+	runner, ok := steps[0].Client.(rate_limiter.ScriptRunnerInterface)
+	if !ok || !rate_limiter.SameSlot(keys) {
+		return nil, nil, false
+	}
+	return runner, steps, true
+}
+
+func (c *CompositeRateLimiter) decideAtomically(runner rate_limiter.ScriptRunnerInterface, steps []rate_limiter.ScriptStep) CompositeDecision {
+	composite := CompositeDecision{DeniedBy: -1, Atomic: true}
+
+	// A member that already knows it denies, e.g. from its denied cache, spares the round trip
+	for i, step := range steps {
+		if !step.Denied {
+			continue
+		}
+		for _, earlier := range steps[:i] {
+			composite.Decisions = append(composite.Decisions, earlier.Decision)
+		}
+		composite.Decisions = append(composite.Decisions, rate_limiter.CompleteStep(step, step.Decision))
+		composite.DeniedBy = i
+		return composite
+	}
+
+	var keys []string
+	args := []interface{}{len(steps)}
+	for _, step := range steps {
+		keys = append(keys, step.Keys...)
+		args = append(args, string(step.Decision.Algorithm), len(step.Keys), len(step.Args))
+		args = append(args, step.Args...)
+	}
+
+	result, err := runner.Eval(compositeScript, keys, args...)
+	reply, ok := result.([]interface{})
+	if err == nil && (!ok || len(reply) != len(steps)+1) {
+		err = fmt.Errorf("unexpected composite script reply %v", result)
+	}
+
+	for i, step := range steps {
+		decision := step.Decision
+		if err != nil {
+			decision.Err = err
+		} else {
+			decision.Remaining, _ = reply[i+1].(int64)
+		}
+		composite.Decisions = append(composite.Decisions, decision)
+	}
+
+	if err != nil {
+		composite.DeniedBy = 0
+		composite.Err = err
+		for i, step := range steps {
+			rate_limiter.CompleteStep(step, composite.Decisions[i])
+		}
+		return composite
+	}
+
+	deniedBy, _ := reply[0].(int64)
+	composite.Allowed = deniedBy == 0
+	composite.DeniedBy = int(deniedBy) - 1
+	for i := range composite.Decisions {
+		composite.Decisions[i].Allowed = composite.Allowed
+	}
+	// Only the denying member is over its own limit; the others were merely left uncharged
+	if !composite.Allowed {
+		composite.Decisions[composite.DeniedBy].Remaining = 0
+		rate_limiter.CompleteStep(steps[composite.DeniedBy], composite.Decisions[composite.DeniedBy])
+		return composite
+	}
+	for i, step := range steps {
+		rate_limiter.CompleteStep(step, composite.Decisions[i])
+	}
+	return composite
+}
+
+func (c *CompositeRateLimiter) decideSequentially(clientId string) CompositeDecision {
+	limiters := make([]rate_limiter.RateLimiterInterface, len(c.members))
+	keys := make([]string, len(c.members))
+	for i, member := range c.members {
+		limiters[i] = member.Limiter
+		keys[i] = c.memberKey(member, clientId)
+	}
+
+	decisions, deniedBy, err := rate_limiter.EvaluateSequence(limiters, keys, func(i int) string {
+		return fmt.Sprintf("member %d", i)
+	})
+	return CompositeDecision{Allowed: deniedBy < 0, DeniedBy: deniedBy, Decisions: decisions, Err: err}
+}
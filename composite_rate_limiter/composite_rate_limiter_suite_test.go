@@ -0,0 +1,13 @@
+package composite_rate_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCompositeRateLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CompositeRateLimiter Suite")
+}
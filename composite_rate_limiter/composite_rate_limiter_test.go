@@ -0,0 +1,279 @@
+package composite_rate_limiter_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/composite_rate_limiter"
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("CompositeRateLimiter", func() {
+	var (
+		first    *mocks.MockRateLimiter
+		second   *mocks.MockRateLimiter
+		clientID string
+	)
+
+	BeforeEach(func() {
+		first = mocks.NewMockRateLimiter()
+		second = mocks.NewMockRateLimiter()
+		clientID = "test-client"
+	})
+
+	Describe("sequential evaluation", func() {
+		It("should allow the request when every member allows it", func() {
+			first.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: true, Remaining: 9}
+			}
+			second.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: true, Remaining: 3}
+			}
+
+			composite := composite_rate_limiter.NewCompositeRateLimiter([]composite_rate_limiter.CompositeMember{
+				{Limiter: first},
+				{Limiter: second},
+			})
+			decision := composite.Decide(clientID)
+
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.Remaining).To(Equal(int64(3)))
+			Expect(first.Rollbacks).To(BeEmpty())
+		})
+
+		It("should roll back earlier members when a later member denies", func() {
+			first.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: true, Charge: "charge"}
+			}
+			second.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: false}
+			}
+
+			composite := composite_rate_limiter.NewCompositeRateLimiter([]composite_rate_limiter.CompositeMember{
+				{Limiter: first},
+				{Limiter: second},
+			})
+			result := composite.DecideAll(clientID)
+
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.DeniedBy).To(Equal(1))
+			Expect(result.Atomic).To(BeFalse())
+			Expect(first.Rollbacks).To(HaveLen(1))
+			Expect(first.Rollbacks[0].Charge).To(Equal("charge"))
+		})
+
+		It("should stop at the first denial and map keys per member", func() {
+			first.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: false}
+			}
+
+			composite := composite_rate_limiter.NewCompositeRateLimiter([]composite_rate_limiter.CompositeMember{
+				{Limiter: first, KeyFunc: func(string) string { return "global" }},
+				{Limiter: second},
+			})
+
+			Expect(composite.LimitRequests(clientID)).To(BeFalse())
+			Expect(first.Calls).To(Equal([]string{"global"}))
+			Expect(second.Calls).To(BeEmpty())
+		})
+
+		It("should report rollback errors", func() {
+			first.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: true}
+			}
+			first.RollbackFunc = func(decision rate_limiter.Decision) error {
+				return errors.New("redis write error")
+			}
+
+			composite := composite_rate_limiter.NewCompositeRateLimiter([]composite_rate_limiter.CompositeMember{
+				{Limiter: first},
+				{Limiter: second},
+			})
+			decision := composite.Decide(clientID)
+
+			Expect(decision.Allowed).To(BeFalse())
+			Expect(decision.Err).To(MatchError(ContainSubstring("redis write error")))
+		})
+	})
+
+	Describe("atomic evaluation", func() {
+		var (
+			mockRedisClient *mocks.MockRedisClient
+			members         []composite_rate_limiter.CompositeMember
+		)
+
+		BeforeEach(func() {
+			mockRedisClient = mocks.NewMockRedisClient()
+			hashTagged := func(clientId string) string { return "{" + clientId + "}" }
+			members = []composite_rate_limiter.CompositeMember{
+				{
+					Limiter: token_bucket_ratelimiter.NewTokenBucketRateLimiter(mockRedisClient, 10, 1),
+					KeyFunc: hashTagged,
+				},
+				{
+					Limiter: fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(mockRedisClient, 3600, 1000, rate_limiter.WithKeyPrefix("rate_limit:hour:")),
+					KeyFunc: hashTagged,
+				},
+			}
+		})
+
+		It("should evaluate members sharing a hash slot in one script", func() {
+			var capturedKeys []string
+			mockRedisClient.EvalFunc = func(script string, keys []string, args ...interface{}) (interface{}, error) {
+				capturedKeys = keys
+				Expect(args[0]).To(Equal(2))
+				Expect(args[1]).To(Equal("token_bucket"))
+				return []interface{}{int64(0), int64(9), int64(999)}, nil
+			}
+
+			result := composite_rate_limiter.NewCompositeRateLimiter(members).DecideAll(clientID)
+
+			Expect(result.Atomic).To(BeTrue())
+			Expect(result.Allowed).To(BeTrue())
+			Expect(result.DeniedBy).To(Equal(-1))
+			Expect(result.Decisions[0].Remaining).To(Equal(int64(9)))
+			Expect(result.Decisions[1].Remaining).To(Equal(int64(999)))
+			Expect(capturedKeys).To(Equal([]string{
+				"rate_limit:{test-client}:count",
+				"rate_limit:{test-client}:lastRefill",
+				"rate_limit:hour:{test-client}",
+			}))
+		})
+
+		It("should report which member denied inside the script", func() {
+			mockRedisClient.EvalFunc = func(script string, keys []string, args ...interface{}) (interface{}, error) {
+				return []interface{}{int64(2), int64(9), int64(0)}, nil
+			}
+
+			result := composite_rate_limiter.NewCompositeRateLimiter(members).DecideAll(clientID)
+
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.DeniedBy).To(Equal(1))
+		})
+
+		It("should log member decisions and serve cached denials without running the script", func() {
+			buffer := &bytes.Buffer{}
+			logger := rate_limiter.NewDecisionLogger(slog.New(slog.NewJSONHandler(buffer, nil)), rate_limiter.DecisionLoggerConfig{AllowSampleRate: 1})
+			hashTagged := func(clientId string) string { return "{" + clientId + "}" }
+			members = []composite_rate_limiter.CompositeMember{
+				{
+					Limiter: token_bucket_ratelimiter.NewTokenBucketRateLimiter(mockRedisClient, 10, 1, rate_limiter.WithDecisionLogger(logger)),
+					KeyFunc: hashTagged,
+				},
+				{
+					Limiter: fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(mockRedisClient, 3600, 1000,
+						rate_limiter.WithKeyPrefix("rate_limit:hour:"),
+						rate_limiter.WithWindowAlignment(rate_limiter.WINDOW_ALIGNMENT_EPOCH),
+						rate_limiter.WithDecisionLogger(logger),
+						rate_limiter.WithDeniedCache(rate_limiter.NewDeniedCache(0))),
+					KeyFunc: hashTagged,
+				},
+			}
+			evals := 0
+			mockRedisClient.EvalFunc = func(script string, keys []string, args ...interface{}) (interface{}, error) {
+				evals++
+				if evals == 1 {
+					return []interface{}{int64(0), int64(9), int64(999)}, nil
+				}
+				return []interface{}{int64(2), int64(9), int64(0)}, nil
+			}
+			limiter := composite_rate_limiter.NewCompositeRateLimiter(members)
+
+			Expect(limiter.DecideAll(clientID).Allowed).To(BeTrue())
+			Expect(strings.Count(buffer.String(), "\n")).To(Equal(2))
+
+			denied := limiter.DecideAll(clientID)
+			Expect(denied.Allowed).To(BeFalse())
+			Expect(denied.DeniedBy).To(Equal(1))
+			Expect(strings.Count(buffer.String(), "\n")).To(Equal(3))
+
+			cached := limiter.DecideAll(clientID)
+			Expect(cached.Allowed).To(BeFalse())
+			Expect(cached.DeniedBy).To(Equal(1))
+			Expect(evals).To(Equal(2))
+		})
+
+		It("should deny the request when the script fails", func() {
+			mockRedisClient.EvalFunc = func(script string, keys []string, args ...interface{}) (interface{}, error) {
+				return nil, errors.New("redis connection error")
+			}
+
+			decision := composite_rate_limiter.NewCompositeRateLimiter(members).Decide(clientID)
+
+			Expect(decision.Allowed).To(BeFalse())
+			Expect(decision.Err).To(HaveOccurred())
+		})
+
+		It("should fall back to sequential evaluation when keys span hash slots", func() {
+			members[0].KeyFunc = nil
+			members[1].KeyFunc = nil
+			mockRedisClient.EvalFunc = func(script string, keys []string, args ...interface{}) (interface{}, error) {
+				Fail("script should not run for keys in different slots")
+				return nil, nil
+			}
+			mockRedisClient.GetCountAndLastRefillFunc = func(keyCount, keyLastRefill string) (int64, int, error) {
+				return 0, 0, nil
+			}
+			mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, currentTime int64) error {
+				return nil
+			}
+			mockRedisClient.GetFunc = func(key string) (string, error) {
+				return "1000", nil
+			}
+			var rolledBack string
+			mockRedisClient.IncrByIfExistsFunc = func(key string, increment int64) (int64, error) {
+				rolledBack = key
+				Expect(increment).To(Equal(int64(1)))
+				return 10, nil
+			}
+
+			result := composite_rate_limiter.NewCompositeRateLimiter(members).DecideAll(clientID)
+
+			Expect(result.Atomic).To(BeFalse())
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.DeniedBy).To(Equal(1))
+			Expect(rolledBack).To(Equal("rate_limit:test-client:count"))
+		})
+	})
+
+	Describe("atomic evaluation on a server without HEXPIRE", func() {
+		It("should run the string key layout of the sliding window counter in the script", func() {
+			server := miniredis.NewMiniRedis()
+			Expect(server.Start()).To(Succeed())
+			DeferCleanup(server.Close)
+			redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			DeferCleanup(redisClient.Close)
+			client := rate_limiter.NewRedisClient(redisClient)
+			client.SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "6.2.0"})
+
+			limiter := composite_rate_limiter.NewCompositeRateLimiter([]composite_rate_limiter.CompositeMember{
+				{Limiter: sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 2, 60, 1,
+					rate_limiter.WithKeyLayout(rate_limiter.KEY_LAYOUT_STRINGS), rate_limiter.WithHashTaggedKeys())},
+				{Limiter: fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 100,
+					rate_limiter.WithKeyPrefix("rate_limit:minute:"), rate_limiter.WithHashTaggedKeys())},
+			})
+
+			var results []bool
+			for range 3 {
+				result := limiter.DecideAll(clientID)
+				Expect(result.Err).NotTo(HaveOccurred())
+				Expect(result.Atomic).To(BeTrue())
+				results = append(results, result.Allowed)
+			}
+
+			Expect(results).To(Equal([]bool{true, true, false}))
+			Expect(server.Get("rate_limit:minute:{test-client}")).To(Equal("2"))
+		})
+	})
+})
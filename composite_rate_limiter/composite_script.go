@@ -0,0 +1,121 @@
+package composite_rate_limiter
+
+// compositeScript checks every member first and only consumes quota when all of them allow the
+// request, so a denial never leaves earlier members charged.
+//
+// ARGV[1] is the member count, followed by "algorithm, key count, arg count, args..." per member.
+// KEYS holds every member's keys in order. The reply is the 1-based index of the first denying
+// member (0 when allowed) followed by each member's remaining quota.
+const compositeScript = `
+local count = tonumber(ARGV[1])
+local members = {}
+local keyIndex, argIndex = 1, 2
+for i = 1, count do
+	local member = {algorithm = ARGV[argIndex], keys = {}, args = {}}
+	local keyCount, argCount = tonumber(ARGV[argIndex + 1]), tonumber(ARGV[argIndex + 2])
+	argIndex = argIndex + 3
+	for j = 1, keyCount do
+		member.keys[j] = KEYS[keyIndex]
+		keyIndex = keyIndex + 1
+	end
+	for j = 1, argCount do
+		member.args[j] = ARGV[argIndex]
+		argIndex = argIndex + 1
+	end
+	members[i] = member
+end
+
+local function check(member)
+	local keys, args = member.keys, member.args
+	local limit = tonumber(args[1])
+	local used = 0
+	if member.algorithm == 'token_bucket' then
+		local refillRate, now = tonumber(args[2]), tonumber(args[3])
+		local lastRefill = tonumber(redis.call('GET', keys[2]) or '0')
+		local tokens = tonumber(redis.call('GET', keys[1]) or '0')
+		if lastRefill == 0 then
+			lastRefill = now
+			tokens = limit
+		else
+			local tokensToAdd = math.floor((now - lastRefill) * refillRate)
+			if tokensToAdd > 0 then
+				tokens = math.min(limit, tokens + tokensToAdd)
+				if tokens >= limit then
+					lastRefill = now
+				else
+					lastRefill = lastRefill + math.floor(tokensToAdd / refillRate)
+				end
+			end
+			tokens = math.min(tokens, limit)
+		end
+		member.tokens = tokens
+		member.lastRefill = lastRefill
+		used = limit - tokens
+	elseif member.algorithm == 'fixed_window_counter' then
+		used = tonumber(redis.call('GET', keys[1]) or '0')
+	elseif member.algorithm == 'sliding_window_log' then
+		used = redis.call('HLEN', keys[1])
+	elseif member.algorithm == 'sliding_window_counter' and args[4] == 'strings' then
+		for _, value in ipairs(redis.call('MGET', unpack(keys))) do
+			used = used + tonumber(value or '0')
+		end
+	elseif member.algorithm == 'sliding_window_counter' then
+		for _, value in ipairs(redis.call('HVALS', keys[1])) do
+			used = used + tonumber(value)
+		end
+	else
+		error('unsupported algorithm ' .. member.algorithm)
+	end
+	return used < limit, math.max(limit - used - 1, 0)
+end
+
+local function commit(member)
+	local keys, args = member.keys, member.args
+	if member.algorithm == 'token_bucket' then
+		local expiry = tonumber(args[4] or '0')
+		if expiry > 0 then
+			redis.call('SET', keys[1], member.tokens - 1, 'PX', expiry)
+			redis.call('SET', keys[2], member.lastRefill, 'PX', expiry)
+		else
+			redis.call('SET', keys[1], member.tokens - 1)
+			redis.call('SET', keys[2], member.lastRefill)
+		end
+	elseif member.algorithm == 'fixed_window_counter' then
+		redis.call('INCR', keys[1])
+		if redis.call('TTL', keys[1]) < 0 then
+			redis.call('EXPIRE', keys[1], args[2])
+		end
+	elseif member.algorithm == 'sliding_window_log' then
+		redis.call('HSET', keys[1], args[3], 1)
+		if redis.call('TTL', keys[1]) < 0 then
+			redis.call('EXPIRE', keys[1], args[2])
+		end
+	elseif member.algorithm == 'sliding_window_counter' and args[4] == 'strings' then
+		local current = keys[#keys]
+		redis.call('INCR', current)
+		if redis.call('TTL', current) < 0 then
+			redis.call('EXPIRE', current, args[2])
+		end
+	elseif member.algorithm == 'sliding_window_counter' then
+		redis.call('HINCRBY', keys[1], args[3], 1)
+		redis.call('HEXPIRE', keys[1], args[2], 'NX', 'FIELDS', 1, args[3])
+	end
+end
+
+local reply = {0}
+for i, member in ipairs(members) do
+	local allowed, remaining = check(member)
+	reply[i + 1] = remaining
+	if not allowed and reply[1] == 0 then
+		reply[1] = i
+	end
+end
+
+if reply[1] == 0 then
+	for _, member in ipairs(members) do
+		commit(member)
+	end
+end
+
+return reply
+`
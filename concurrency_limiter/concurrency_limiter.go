@@ -0,0 +1,116 @@
+package concurrency_limiter
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const (
+	DEFAULT_LEASE_TTL     = 30 * time.Second
+	DEFAULT_POLL_INTERVAL = 50 * time.Millisecond
+)
+
+// ConcurrencyConfig tunes the leases of a ConcurrencyLimiter
+type ConcurrencyConfig struct {
+	// LeaseTTL is how long a lease holds its slot unless released, DEFAULT_LEASE_TTL when zero.
+	// Pick it comfortably above the slowest request.
+	LeaseTTL time.Duration
+	// PollInterval is how often Acquire retries while every slot is taken, DEFAULT_POLL_INTERVAL when zero
+	PollInterval time.Duration
+}
+
+// ErrLimitReached is returned by TryAcquire when every slot of the client is taken
+var ErrLimitReached = errors.New("concurrency limit reached")
+
+// Lease holds one slot until it is released or expires
+type Lease struct {
+	Key       string
+	ID        string
+	ExpiresAt time.Time
+	// InFlight is how many leases the client held with this one included
+	InFlight int64
+
+	limiter *ConcurrencyLimiter
+}
+
+// Release frees the slot held by the lease
+func (l *Lease) Release() error {
+	return l.limiter.Release(l)
+}
+
+// ConcurrencyLimiter bounds how many requests of a client are in flight at once, rather than how
+// many start per window, so slow requests cannot pile up. Every acquired slot is a lease with a
+// TTL; a holder that crashes without releasing loses its slot once the lease expires. On Redis the
+// leases of a client live in one sorted set scored by their expiry.
+type ConcurrencyLimiter struct {
+	client  rate_limiter.ConcurrencyClientInterface
+	limit   int
+	config  ConcurrencyConfig
+	options rate_limiter.Options
+}
+
+// NewConcurrencyLimiter allows limit leases per client at once. Overrides replace limit per client.
+func NewConcurrencyLimiter(client rate_limiter.ConcurrencyClientInterface, limit int, config ConcurrencyConfig, opts ...rate_limiter.Option) *ConcurrencyLimiter {
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = DEFAULT_LEASE_TTL
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DEFAULT_POLL_INTERVAL
+	}
+	return &ConcurrencyLimiter{
+		client:  client,
+		limit:   limit,
+		config:  config,
+		options: rate_limiter.NewOptions(opts...),
+	}
+}
+
+// TryAcquire takes a slot for clientId, or fails with ErrLimitReached without waiting
+func (c *ConcurrencyLimiter) TryAcquire(clientId string) (*Lease, error) {
+	now := c.options.Clock.Now()
+	lease := &Lease{
+		Key:       clientId,
+		ID:        uuid.NewString(),
+		ExpiresAt: now.Add(c.config.LeaseTTL),
+		limiter:   c,
+	}
+	limit := c.options.Override(clientId).LimitOr(int64(c.limit))
+	acquired, inFlight, err := c.client.AcquireSlot(c.options.Key(clientId), lease.ID, limit, lease.ExpiresAt, now)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLimitReached
+	}
+	lease.InFlight = inFlight
+	return lease, nil
+}
+
+// Acquire waits until a slot for clientId frees up, or ctx is done
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context, clientId string) (*Lease, error) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		lease, err := c.TryAcquire(clientId)
+		if !errors.Is(err, ErrLimitReached) {
+			return lease, err
+		}
+		timer.Reset(c.config.PollInterval)
+	}
+}
+
+// Release frees the slot held by lease. Releasing an expired or already released lease is a no-op.
+func (c *ConcurrencyLimiter) Release(lease *Lease) error {
+	return c.client.ReleaseSlot(c.options.Key(lease.Key), lease.ID)
+}
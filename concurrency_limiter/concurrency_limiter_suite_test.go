@@ -0,0 +1,13 @@
+package concurrency_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ConcurrencyLimiter Suite")
+}
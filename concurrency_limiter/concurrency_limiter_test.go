@@ -0,0 +1,141 @@
+package concurrency_limiter_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/concurrency_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("ConcurrencyLimiter", func() {
+	backends := map[string]func(clock *rate_limiter.ManualClock) rate_limiter.ConcurrencyClientInterface{
+		"in-memory": func(clock *rate_limiter.ManualClock) rate_limiter.ConcurrencyClientInterface {
+			return rate_limiter.NewMemoryClient(clock)
+		},
+		"miniredis": func(clock *rate_limiter.ManualClock) rate_limiter.ConcurrencyClientInterface {
+			server := miniredis.NewMiniRedis()
+			Expect(server.Start()).To(Succeed())
+			DeferCleanup(server.Close)
+			redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			DeferCleanup(redisClient.Close)
+			return rate_limiter.NewRedisClient(redisClient)
+		},
+	}
+
+	for name, newClient := range backends {
+		Describe("on "+name, func() {
+			var (
+				clock   *rate_limiter.ManualClock
+				limiter *concurrency_limiter.ConcurrencyLimiter
+			)
+
+			BeforeEach(func() {
+				clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+				limiter = concurrency_limiter.NewConcurrencyLimiter(newClient(clock), 2,
+					concurrency_limiter.ConcurrencyConfig{LeaseTTL: 10 * time.Second, PollInterval: time.Millisecond},
+					rate_limiter.WithClock(clock))
+			})
+
+			It("should hand out at most limit leases per client", func() {
+				first, err := limiter.TryAcquire("client")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(first.InFlight).To(Equal(int64(1)))
+				_, err = limiter.TryAcquire("client")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = limiter.TryAcquire("client")
+				Expect(err).To(MatchError(concurrency_limiter.ErrLimitReached))
+				_, err = limiter.TryAcquire("other-client")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should free the slot on release", func() {
+				first, _ := limiter.TryAcquire("client")
+				_, _ = limiter.TryAcquire("client")
+
+				Expect(first.Release()).To(Succeed())
+				Expect(first.Release()).To(Succeed())
+
+				_, err := limiter.TryAcquire("client")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should reclaim the slots of leases that expired without a release", func() {
+				_, _ = limiter.TryAcquire("client")
+				_, _ = limiter.TryAcquire("client")
+
+				clock.Advance(10 * time.Second)
+
+				lease, err := limiter.TryAcquire("client")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lease.InFlight).To(Equal(int64(1)))
+			})
+
+			It("should block until a slot frees up", func() {
+				first, _ := limiter.TryAcquire("client")
+				_, _ = limiter.TryAcquire("client")
+
+				acquired := make(chan *concurrency_limiter.Lease)
+				go func() {
+					defer GinkgoRecover()
+					lease, err := limiter.Acquire(context.Background(), "client")
+					Expect(err).NotTo(HaveOccurred())
+					acquired <- lease
+				}()
+				Consistently(acquired, 20*time.Millisecond).ShouldNot(Receive())
+
+				Expect(first.Release()).To(Succeed())
+				Eventually(acquired).Should(Receive())
+			})
+
+			It("should stop waiting once the context is done", func() {
+				_, _ = limiter.TryAcquire("client")
+				_, _ = limiter.TryAcquire("client")
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+
+				_, err := limiter.Acquire(ctx, "client")
+
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+			})
+
+			It("should never exceed the limit under concurrent acquires", func() {
+				var (
+					wg       sync.WaitGroup
+					inFlight atomic.Int64
+					peak     atomic.Int64
+				)
+				for range 20 {
+					wg.Add(1)
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+						lease, err := limiter.Acquire(context.Background(), "client")
+						Expect(err).NotTo(HaveOccurred())
+						current := inFlight.Add(1)
+						for {
+							previous := peak.Load()
+							if current <= previous || peak.CompareAndSwap(previous, current) {
+								break
+							}
+						}
+						time.Sleep(time.Millisecond)
+						inFlight.Add(-1)
+						Expect(lease.Release()).To(Succeed())
+					}()
+				}
+				wg.Wait()
+
+				Expect(peak.Load()).To(BeNumerically("<=", 2))
+			})
+		})
+	}
+})
@@ -0,0 +1,137 @@
+package dry_run_rate_limiter
+
+import (
+	"sync/atomic"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const (
+	METRIC_DRY_RUN_DECISIONS = "rate_limiter_dry_run_decisions_total"
+	METRIC_SHADOW_DECISIONS  = "rate_limiter_shadow_decisions_total"
+)
+
+// DryRunConfig controls where the would-be decisions of a DryRunRateLimiter are recorded
+type DryRunConfig struct {
+	Logger  *rate_limiter.DecisionLogger
+	Metrics rate_limiter.MetricsRecorderInterface
+	// OnDecision is called with the decision of the limiter under evaluation
+	OnDecision func(decision rate_limiter.Decision)
+	// OnDisagreement is called when the enforced and candidate limiters reach different outcomes
+	OnDisagreement func(enforced rate_limiter.Decision, candidate rate_limiter.Decision)
+}
+
+// ShadowStats summarises how often the candidate limiter disagreed with the enforced one
+type ShadowStats struct {
+	Total int64
+	// CandidateDenied counts requests the enforced limiter allowed but the candidate would have denied
+	CandidateDenied int64
+	// CandidateAllowed counts requests the enforced limiter denied but the candidate would have allowed
+	CandidateAllowed int64
+}
+
+func (s ShadowStats) Disagreements() int64 {
+	return s.CandidateDenied + s.CandidateAllowed
+}
+
+func (s ShadowStats) DisagreementRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Disagreements()) / float64(s.Total)
+}
+
+// DryRunRateLimiter evaluates a candidate limiter without enforcing it. With no enforced limiter
+// every request is allowed; otherwise the enforced limiter's outcome is returned and the candidate
+// runs next to it in shadow mode.
+type DryRunRateLimiter struct {
+	enforced  rate_limiter.RateLimiterInterface
+	candidate rate_limiter.RateLimiterInterface
+	config    DryRunConfig
+
+	total            atomic.Int64
+	candidateDenied  atomic.Int64
+	candidateAllowed atomic.Int64
+}
+
+// NewDryRunRateLimiter wraps limiter so that it is evaluated and recorded but never denies a request
+func NewDryRunRateLimiter(limiter rate_limiter.RateLimiterInterface, config DryRunConfig) *DryRunRateLimiter {
+	return &DryRunRateLimiter{
+		candidate: limiter,
+		config:    config,
+	}
+}
+
+// NewShadowRateLimiter enforces enforced while evaluating candidate on the same traffic. Both limiters
+// count every request, so a candidate on the same Redis must be built with its own WithKeyPrefix.
+// Under the enforced limiter's prefix it would share its counters and charge each request twice.
+func NewShadowRateLimiter(enforced rate_limiter.RateLimiterInterface, candidate rate_limiter.RateLimiterInterface, config DryRunConfig) *DryRunRateLimiter {
+	return &DryRunRateLimiter{
+		enforced:  enforced,
+		candidate: candidate,
+		config:    config,
+	}
+}
+
+func (d *DryRunRateLimiter) LimitRequests(clientId string) bool {
+	return d.Decide(clientId).Allowed
+}
+
+func (d *DryRunRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	candidate := rate_limiter.Evaluate(d.candidate, clientId)
+	d.record(candidate)
+
+	if d.enforced == nil {
+		decision := candidate
+		decision.Allowed = true
+		decision.Err = nil
+		return decision
+	}
+
+	enforced := rate_limiter.Evaluate(d.enforced, clientId)
+	d.compare(enforced, candidate)
+	return enforced
+}
+
+// Stats returns the shadow comparison counters collected so far
+func (d *DryRunRateLimiter) Stats() ShadowStats {
+	return ShadowStats{
+		Total:            d.total.Load(),
+		CandidateDenied:  d.candidateDenied.Load(),
+		CandidateAllowed: d.candidateAllowed.Load(),
+	}
+}
+
+func (d *DryRunRateLimiter) record(candidate rate_limiter.Decision) {
+	d.config.Logger.Log(candidate)
+
+	if d.config.Metrics != nil {
+		d.config.Metrics.IncCounter(METRIC_DRY_RUN_DECISIONS, rate_limiter.DecisionLabels(candidate))
+	}
+
+	if d.config.OnDecision != nil {
+		d.config.OnDecision(candidate)
+	}
+}
+
+func (d *DryRunRateLimiter) compare(enforced rate_limiter.Decision, candidate rate_limiter.Decision) {
+	d.total.Add(1)
+
+	outcome := "agree"
+	switch {
+	case enforced.Allowed && !candidate.Allowed:
+		d.candidateDenied.Add(1)
+		outcome = "candidate_denied"
+	case !enforced.Allowed && candidate.Allowed:
+		d.candidateAllowed.Add(1)
+		outcome = "candidate_allowed"
+	}
+
+	if d.config.Metrics != nil {
+		d.config.Metrics.IncCounter(METRIC_SHADOW_DECISIONS, map[string]string{"outcome": outcome})
+	}
+
+	if outcome != "agree" && d.config.OnDisagreement != nil {
+		d.config.OnDisagreement(enforced, candidate)
+	}
+}
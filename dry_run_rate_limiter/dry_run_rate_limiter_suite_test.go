@@ -0,0 +1,13 @@
+package dry_run_rate_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDryRunRateLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DryRunRateLimiter Suite")
+}
@@ -0,0 +1,94 @@
+package dry_run_rate_limiter_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/dry_run_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+)
+
+var _ = Describe("DryRunRateLimiter", func() {
+	var (
+		candidate *mocks.MockRateLimiter
+		enforced  *mocks.MockRateLimiter
+		metrics   *mocks.MockMetricsRecorder
+		clientID  string
+	)
+
+	BeforeEach(func() {
+		candidate = mocks.NewMockRateLimiter()
+		enforced = mocks.NewMockRateLimiter()
+		metrics = mocks.NewMockMetricsRecorder()
+		clientID = "test-client"
+	})
+
+	Describe("dry-run mode", func() {
+		It("should allow requests the wrapped limiter would deny and record the would-be decision", func() {
+			candidate.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: false, Limit: 5}
+			}
+
+			var recorded []rate_limiter.Decision
+			dryRun := dry_run_rate_limiter.NewDryRunRateLimiter(candidate, dry_run_rate_limiter.DryRunConfig{
+				Metrics:    metrics,
+				OnDecision: func(decision rate_limiter.Decision) { recorded = append(recorded, decision) },
+			})
+
+			Expect(dryRun.LimitRequests(clientID)).To(BeTrue())
+			Expect(candidate.Calls).To(Equal([]string{clientID}))
+			Expect(recorded).To(HaveLen(1))
+			Expect(recorded[0].Allowed).To(BeFalse())
+			Expect(metrics.Counters[dry_run_rate_limiter.METRIC_DRY_RUN_DECISIONS]).To(ConsistOf(HaveKeyWithValue("allowed", "false")))
+		})
+
+		It("should allow requests when the wrapped limiter fails", func() {
+			candidate.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Err: errors.New("redis connection error")}
+			}
+
+			dryRun := dry_run_rate_limiter.NewDryRunRateLimiter(candidate, dry_run_rate_limiter.DryRunConfig{})
+			decision := dryRun.Decide(clientID)
+
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.Err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("shadow mode", func() {
+		It("should enforce the enforced limiter and report disagreements with the candidate", func() {
+			enforcedAllows := []bool{true, true, false, false}
+			candidateAllows := []bool{true, false, false, true}
+			call := 0
+			enforced.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: enforcedAllows[call]}
+			}
+			candidate.DecideFunc = func(clientId string) rate_limiter.Decision {
+				return rate_limiter.Decision{Key: clientId, Allowed: candidateAllows[call]}
+			}
+
+			disagreements := 0
+			shadow := dry_run_rate_limiter.NewShadowRateLimiter(enforced, candidate, dry_run_rate_limiter.DryRunConfig{
+				Metrics: metrics,
+				OnDisagreement: func(enforced rate_limiter.Decision, candidate rate_limiter.Decision) {
+					disagreements++
+				},
+			})
+
+			for call = range enforcedAllows {
+				Expect(shadow.LimitRequests(clientID)).To(Equal(enforcedAllows[call]))
+			}
+
+			stats := shadow.Stats()
+			Expect(stats.Total).To(Equal(int64(4)))
+			Expect(stats.CandidateDenied).To(Equal(int64(1)))
+			Expect(stats.CandidateAllowed).To(Equal(int64(1)))
+			Expect(stats.DisagreementRate()).To(Equal(0.5))
+			Expect(disagreements).To(Equal(2))
+			Expect(metrics.Count(dry_run_rate_limiter.METRIC_SHADOW_DECISIONS)).To(Equal(4))
+		})
+	})
+})
@@ -0,0 +1,6 @@
+package dry_run_rate_limiter
+
+import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+
+var _ rate_limiter.RateLimiterInterface = (*DryRunRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*DryRunRateLimiter)(nil)
@@ -1,33 +1,84 @@
 package fixed_window_counter_ratelimiter
 
 import (
+	"context"
+	"fmt"
+	"hash/fnv"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 )
 
+// ALIGNED_EXPIRY_SLACK keeps the counter of an aligned window around a little past the window's
+// end, so that a Redis clock running slightly ahead does not reset it early. The next window has
+// its own key, so the slack never lets a count leak into it.
+const ALIGNED_EXPIRY_SLACK = time.Second
+
+// refundScript takes up to ARGV[1] requests off the counter, never below zero. A non-zero ARGV[2]
+// is the Unix time in milliseconds at which the charged counter expires, and the counter is only
+// refunded while it expires within ARGV[3] milliseconds of it.
+const refundScript = `
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if count <= 0 then
+	return 0
+end
+local expected = tonumber(ARGV[2])
+if expected > 0 then
+	local ttl = redis.call('PTTL', KEYS[1])
+	local now = redis.call('TIME')
+	local expiresAt = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000) + ttl
+	if ttl < 0 or math.abs(expiresAt - expected) >= tonumber(ARGV[3]) then
+		return 0
+	end
+end
+local refund = math.min(tonumber(ARGV[1]), count)
+redis.call('DECRBY', KEYS[1], refund)
+return refund
+`
+
 type FixedWindowCounterRateLimiter struct {
-    redisClient rate_limiter.RedisClientInterface
-	windowSize int
-	limit int
+	redisClient rate_limiter.RedisClientInterface
+	windowSize  int
+	limit       int
+	options     rate_limiter.Options
 }
 
-func NewFixedWindowCounterRateLimiter(redisClient rate_limiter.RedisClientInterface, windowSize int, limit int) *FixedWindowCounterRateLimiter {
+func NewFixedWindowCounterRateLimiter(redisClient rate_limiter.RedisClientInterface, windowSize int, limit int, opts ...rate_limiter.Option) *FixedWindowCounterRateLimiter {
 	return &FixedWindowCounterRateLimiter{
 		redisClient: redisClient,
-		windowSize: windowSize,
-		limit: limit,
+		windowSize:  windowSize,
+		limit:       limit,
+		options:     rate_limiter.NewOptions(opts...),
 	}
 }
 
 func (f *FixedWindowCounterRateLimiter) LimitRequests(clientId string) bool {
-	key := "rate_limit:" + clientId
+	return f.Decide(clientId).Allowed
+}
+
+func (f *FixedWindowCounterRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	decision := f.decide(clientId)
+	f.options.Logger.Log(decision)
+	return decision
+}
+
+func (f *FixedWindowCounterRateLimiter) decide(clientId string) rate_limiter.Decision {
+	decision := f.newDecision(clientId)
+	chargedAt := f.options.Clock.Now()
+
+	key, end := f.window(clientId, chargedAt)
+	if f.options.DeniedCache != nil && f.options.DeniedCache.Denied(key, decision.Limit, f.options.Clock.Now()) {
+		return decision
+	}
+
 	currentCounterStr, err := f.redisClient.Get(key)
 
-	// If there's an error and it's not just an empty string (new client), reject the request
+	// Missing keys read as an empty string, so any error means Redis could not be asked
 	if err != nil {
-		return false
+		decision.Err = err
+		return decision
 	}
 
 	// For new clients or expired windows, currentCounterStr will be empty
@@ -37,15 +88,353 @@ func (f *FixedWindowCounterRateLimiter) LimitRequests(clientId string) bool {
 	}
 
 	// If counter is at or above limit, reject the request
-	if currentCounter >= f.limit {
-		return false
+	if int64(currentCounter) >= decision.Limit {
+		f.rememberDenied(key, decision.Limit, end)
+		return decision
 	}
 
 	// Request is allowed, increment the counter and set expiry
-	incrResult, err := f.redisClient.IncrWithExpiry(key, time.Duration(f.windowSize) * time.Second, rate_limiter.EXPIRY_MODE_NX)
-	if err != nil || incrResult == 0 {
-		return false
+	incrResult, expiresAt, err := f.count(key, f.expiry(decision.Window, chargedAt, end), end)
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
+	// A concurrent request may have taken the last slot between the Get and the increment
+	if incrResult == 0 || incrResult > decision.Limit {
+		f.rememberDenied(key, decision.Limit, end)
+		return decision
+	}
+
+	decision.Allowed = true
+	decision.Remaining = max(decision.Limit-incrResult, 0)
+	decision.Charge = charge(chargedAt, expiresAt)
+	return decision
+}
+
+// count increments the counter of a window, starting its expiry on the first request. For
+// first-request windows it also returns when the counter expires, when the client can tell.
+func (f *FixedWindowCounterRateLimiter) count(key string, expiry time.Duration, end time.Time) (int64, time.Time, error) {
+	windowClient, ok := f.redisClient.(rate_limiter.WindowClientInterface)
+	if !end.IsZero() || !ok {
+		count, err := f.redisClient.IncrWithExpiry(key, expiry, rate_limiter.EXPIRY_MODE_NX)
+		return count, time.Time{}, err
+	}
+	return windowClient.IncrWithExpiryAt(key, expiry, rate_limiter.EXPIRY_MODE_NX)
+}
+
+// charge records when a decision was charged and, for first-request windows, when the charged
+// counter expires. Every later counter under the same key expires at least a window after it, so
+// the expiry tells the charged window apart from later ones.
+func charge(chargedAt time.Time, expiresAt time.Time) string {
+	if expiresAt.IsZero() {
+		return strconv.FormatInt(chargedAt.UnixMilli(), 10)
+	}
+	return strconv.FormatInt(chargedAt.UnixMilli(), 10) + ":" + strconv.FormatInt(expiresAt.UnixMilli(), 10)
+}
+
+// parseCharge reads a charge written by charge; expiresAt is zero when it was not recorded
+func parseCharge(charge string) (chargedAt time.Time, expiresAt time.Time, err error) {
+	chargedAtStr, expiresAtStr, found := strings.Cut(charge, ":")
+	chargedAtMs, err := strconv.ParseInt(chargedAtStr, 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !found {
+		return time.UnixMilli(chargedAtMs), time.Time{}, nil
+	}
+	expiresAtMs, err := strconv.ParseInt(expiresAtStr, 10, 64)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return time.UnixMilli(chargedAtMs), time.UnixMilli(expiresAtMs), nil
+}
+
+// window returns the counter key of clientId's window at now. Aligned windows also return when
+// they end and carry the window's id in their key; first-request windows end with their key's TTL.
+func (f *FixedWindowCounterRateLimiter) window(clientId string, now time.Time) (string, time.Time) {
+	key := f.options.Key(clientId)
+	windowMs := int64(f.windowSize) * 1000
+	if f.options.WindowAlignment == rate_limiter.WINDOW_ALIGNMENT_FIRST_REQUEST || windowMs <= 0 {
+		return key, time.Time{}
 	}
 
-	return true
-}
\ No newline at end of file
+	var offset int64
+	if f.options.WindowAlignment == rate_limiter.WINDOW_ALIGNMENT_STAGGERED {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		offset = int64(hash.Sum64() % uint64(windowMs))
+	}
+	// Floor the division so that windows before the epoch line up too
+	windowId := (now.UnixMilli() - offset) / windowMs
+	if now.UnixMilli()-offset < windowId*windowMs {
+		windowId--
+	}
+	return key + ":" + strconv.FormatInt(windowId, 10), time.UnixMilli((windowId+1)*windowMs + offset)
+}
+
+// expiry returns the TTL a window's counter gets on its first request
+func (f *FixedWindowCounterRateLimiter) expiry(window time.Duration, now time.Time, end time.Time) time.Duration {
+	if end.IsZero() {
+		return window
+	}
+	return end.Sub(now) + ALIGNED_EXPIRY_SLACK
+}
+
+// rememberDenied caches key as denied until its window resets, when a denied cache is configured
+// and the limiter can tell when that is
+func (f *FixedWindowCounterRateLimiter) rememberDenied(key string, limit int64, end time.Time) {
+	if f.options.DeniedCache == nil {
+		return
+	}
+	if !end.IsZero() {
+		f.options.DeniedCache.Add(key, limit, end)
+		return
+	}
+	ttlClient, ok := f.redisClient.(rate_limiter.TTLClientInterface)
+	if !ok {
+		return
+	}
+	ttl, err := ttlClient.PTTL(key)
+	if err != nil || ttl <= 0 {
+		return
+	}
+	f.options.DeniedCache.Add(key, limit, f.options.Clock.Now().Add(ttl))
+}
+
+// Peek reads the window counter and when the window ends without counting a request
+func (f *FixedWindowCounterRateLimiter) Peek(ctx context.Context, clientId string) (rate_limiter.KeyState, error) {
+	decision := f.newDecision(clientId)
+	state := rate_limiter.KeyState{Algorithm: decision.Algorithm, Key: clientId, Limit: decision.Limit, Remaining: decision.Limit}
+	if err := ctx.Err(); err != nil {
+		return state, err
+	}
+
+	now := f.options.Clock.Now()
+	key, end := f.window(clientId, now)
+	currentCounterStr, err := f.redisClient.Get(key)
+	if err != nil || currentCounterStr == "" {
+		return state, err
+	}
+	currentCounter, err := strconv.ParseInt(currentCounterStr, 10, 64)
+	if err != nil {
+		return state, err
+	}
+	state.Remaining = max(decision.Limit-currentCounter, 0)
+	if !end.IsZero() {
+		state.ResetAfter = end.Sub(now)
+		return state, nil
+	}
+	state.ResetAfter, err = rate_limiter.PTTL(f.redisClient, key)
+	return state, err
+}
+
+// Reset deletes the counter of the current window and forgets that the client was denied, here
+// and, on clients that support pub/sub, in every denied cache that listens for resets
+func (f *FixedWindowCounterRateLimiter) Reset(ctx context.Context, clientId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	key, _ := f.window(clientId, f.options.Clock.Now())
+	if _, err := f.redisClient.Del(key); err != nil {
+		return err
+	}
+	if f.options.DeniedCache != nil {
+		f.options.DeniedCache.Invalidate(key)
+	}
+	if pubSubClient, ok := f.redisClient.(rate_limiter.PubSubClientInterface); ok {
+		return pubSubClient.Publish(rate_limiter.DENIED_CACHE_INVALIDATION_CHANNEL, key)
+	}
+	return nil
+}
+
+// ScriptStep lets the window counter be checked and incremented inside a multi-limiter Lua script
+func (f *FixedWindowCounterRateLimiter) ScriptStep(clientId string) rate_limiter.ScriptStep {
+	decision := f.newDecision(clientId)
+	now := f.options.Clock.Now()
+	decision.Charge = strconv.FormatInt(now.UnixMilli(), 10)
+	key, end := f.window(clientId, now)
+	// The script expires counters in whole seconds, so round aligned expiries up
+	expiry := f.expiry(decision.Window, now, end)
+	return rate_limiter.ScriptStep{
+		Client:   f.redisClient,
+		Keys:     []string{key},
+		Args:     []interface{}{decision.Limit, int64((expiry + time.Second - 1) / time.Second)},
+		Decision: decision,
+		Denied:   f.options.DeniedCache != nil && f.options.DeniedCache.Denied(key, decision.Limit, now),
+		Done: func(decision rate_limiter.Decision) {
+			if !decision.Allowed && decision.Err == nil {
+				f.rememberDenied(key, decision.Limit, end)
+			}
+			f.options.Logger.Log(decision)
+		},
+	}
+}
+
+// Rollback decrements the window counter, unless the window has already expired
+func (f *FixedWindowCounterRateLimiter) Rollback(decision rate_limiter.Decision) error {
+	if !decision.Allowed {
+		return nil
+	}
+	key, _ := f.window(decision.Key, f.chargedAt(decision))
+	_, err := f.redisClient.IncrByIfExists(key, -1)
+	return err
+}
+
+// chargedAt returns when decision was charged, or the current time for decisions without a charge time
+func (f *FixedWindowCounterRateLimiter) chargedAt(decision rate_limiter.Decision) time.Time {
+	chargedAt, _, err := parseCharge(decision.Charge)
+	if err != nil {
+		return f.options.Clock.Now()
+	}
+	return chargedAt
+}
+
+// Lease counts up to n requests against the current window at once
+func (f *FixedWindowCounterRateLimiter) Lease(clientId string, n int) (rate_limiter.LeaseGrant, error) {
+	grant := rate_limiter.LeaseGrant{Decision: f.newDecision(clientId)}
+	if n <= 0 {
+		return grant, nil
+	}
+	now := f.options.Clock.Now()
+	key, end := f.window(clientId, now)
+
+	currentCounterStr, err := f.redisClient.Get(key)
+	if err != nil {
+		return grant, err
+	}
+	if currentCounter, _ := strconv.ParseInt(currentCounterStr, 10, 64); currentCounter >= grant.Limit {
+		return grant, nil
+	}
+
+	// The first unit starts the window exactly like a single request does
+	incrResult, expiresAt, err := f.count(key, f.expiry(grant.Window, now, end), end)
+	if err != nil {
+		return grant, err
+	}
+	grant.Charge = charge(now, expiresAt)
+	if incrResult == 0 || incrResult > grant.Limit {
+		return grant, nil
+	}
+	grant.Units, grant.Allowed, grant.Remaining = 1, true, grant.Limit-incrResult
+
+	extra := min(int64(n-1), grant.Limit-incrResult)
+	if extra <= 0 {
+		return grant, nil
+	}
+	total, err := f.redisClient.IncrByIfExists(key, extra)
+	if err != nil {
+		return grant, err
+	}
+	// Concurrent leases may have pushed the counter past the limit; give back what does not fit
+	if over := min(total-grant.Limit, extra); over > 0 {
+		if _, err := f.redisClient.IncrByIfExists(key, -over); err != nil {
+			return grant, err
+		}
+		extra -= over
+	}
+	grant.Units += int(extra)
+	grant.Remaining = max(grant.Limit-total, 0)
+	return grant, nil
+}
+
+// ReturnLease uncounts unspent leased requests the way RefundDecision does, from the window they were
+// leased in and never below zero. Units returned after that window ended are dropped.
+func (f *FixedWindowCounterRateLimiter) ReturnLease(grant rate_limiter.LeaseGrant, n int) error {
+	return f.RefundDecision(context.Background(), grant.Decision, min(n, grant.Units))
+}
+
+// Refund takes up to n requests off clientId's current window, never below zero
+func (f *FixedWindowCounterRateLimiter) Refund(ctx context.Context, clientId string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	key, _ := f.window(clientId, f.options.Clock.Now())
+	return f.refund(key, n, time.Time{}, 0)
+}
+
+// RefundDecision takes up to n requests off the counter of the window that charged decision, never
+// below zero, and never off a later window. Aligned windows are told apart by their key. First-request
+// windows share one key and are told apart by the counter's expiry recorded in decision.Charge.
+// Decisions that carry no expiry, because they were taken inside a composite or batch script or on
+// a client without rate_limiter.WindowClientInterface, cannot be matched to their window, so their
+// refunds are dropped.
+func (f *FixedWindowCounterRateLimiter) RefundDecision(ctx context.Context, decision rate_limiter.Decision, n int) error {
+	if !decision.Allowed || n <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	chargedAt, expiresAt, err := parseCharge(decision.Charge)
+	if err != nil {
+		return fmt.Errorf("decision carries no charge time: %w", err)
+	}
+	key, end := f.window(decision.Key, chargedAt)
+	if !end.IsZero() {
+		if !f.options.Clock.Now().Before(end) {
+			return nil
+		}
+		return f.refund(key, n, time.Time{}, 0)
+	}
+	if expiresAt.IsZero() {
+		return nil
+	}
+	// Later counters expire at least a whole window later, so half a window absorbs the millisecond
+	// that servers before Redis 7.0 may be off by without mistaking one window for another
+	return f.refund(key, n, expiresAt, decision.Window/2)
+}
+
+// refund takes up to n requests off key's counter, never below zero. When expiresAt is set, only a
+// counter expiring within tolerance of it is refunded.
+func (f *FixedWindowCounterRateLimiter) refund(key string, n int, expiresAt time.Time, tolerance time.Duration) error {
+	var expected int64
+	if !expiresAt.IsZero() {
+		expected = expiresAt.UnixMilli()
+	}
+	if runner, ok := f.redisClient.(rate_limiter.ScriptRunnerInterface); ok {
+		_, err := runner.Eval(refundScript, []string{key}, n, expected, tolerance.Milliseconds())
+		return err
+	}
+
+	if expected > 0 {
+		windowClient, ok := f.redisClient.(rate_limiter.WindowClientInterface)
+		if !ok {
+			return nil
+		}
+		current, err := windowClient.PExpireTime(key)
+		if err != nil || current.IsZero() || (current.Sub(expiresAt)).Abs() >= tolerance {
+			return err
+		}
+	}
+	currentCounterStr, err := f.redisClient.Get(key)
+	if err != nil || currentCounterStr == "" {
+		return err
+	}
+	currentCounter, err := strconv.ParseInt(currentCounterStr, 10, 64)
+	if err != nil {
+		return err
+	}
+	refund := min(int64(n), currentCounter)
+	if refund <= 0 {
+		return nil
+	}
+	// Concurrent refunds may have drained the counter in the meantime, so put back what went below zero
+	result, err := f.redisClient.IncrByIfExists(key, -refund)
+	if err != nil || result >= 0 {
+		return err
+	}
+	_, err = f.redisClient.IncrByIfExists(key, -result)
+	return err
+}
+
+func (f *FixedWindowCounterRateLimiter) newDecision(clientId string) rate_limiter.Decision {
+	return rate_limiter.Decision{
+		Algorithm: rate_limiter.ALGORITHM_FIXED_WINDOW_COUNTER,
+		Key:       clientId,
+		Limit:     f.options.Override(clientId).LimitOr(int64(f.limit)),
+		Window:    time.Duration(f.windowSize) * time.Second,
+	}
+}
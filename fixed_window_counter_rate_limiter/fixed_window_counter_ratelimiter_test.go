@@ -1,6 +1,7 @@
 package fixed_window_counter_ratelimiter_test
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -162,5 +163,174 @@ var _ = Describe("FixedWindowCounterRatelimiter", func() {
 				Expect(result).To(BeTrue())
 			})
 		})
+
+		Context("when the client has an override", func() {
+			It("should enforce the overridden limit instead of the default", func() {
+				mockRedisClient.GetFunc = func(key string) (string, error) {
+					return "7", nil // Above the default limit of 5
+				}
+
+				mockRedisClient.IncrWithExpiryFunc = func(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error) {
+					return 8, nil
+				}
+
+				resolver := rate_limiter.NewMemoryOverrideResolver(map[string]rate_limiter.Override{
+					clientID: {Limit: 10},
+				})
+				rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(mockRedisClient, windowSize, limit, rate_limiter.WithOverrideResolver(resolver))
+				decision := rateLimiter.Decide(clientID)
+
+				Expect(decision.Allowed).To(BeTrue())
+				Expect(decision.Limit).To(Equal(int64(10)))
+				Expect(decision.Remaining).To(Equal(int64(2)))
+			})
+		})
+	})
+
+	Describe("with a denied cache", func() {
+		var (
+			clock  *rate_limiter.ManualClock
+			client *countingClient
+			cache  *rate_limiter.DeniedCache
+		)
+
+		BeforeEach(func() {
+			clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+			client = &countingClient{MemoryClient: rate_limiter.NewMemoryClient(clock)}
+			cache = rate_limiter.NewDeniedCache(10)
+		})
+
+		It("should deny without asking Redis until the window resets", func() {
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1, rate_limiter.WithClock(clock), rate_limiter.WithDeniedCache(cache))
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+			gets := client.gets
+
+			clock.Advance(5 * time.Second)
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+			Expect(client.gets).To(Equal(gets))
+
+			clock.Advance(5 * time.Second)
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+		})
+
+		It("should ask Redis again once the key is invalidated or its limit changes", func() {
+			resolver := rate_limiter.NewMemoryOverrideResolver(nil)
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1,
+				rate_limiter.WithClock(clock), rate_limiter.WithDeniedCache(cache), rate_limiter.WithOverrideResolver(resolver))
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+
+			Expect(client.Set("rate_limit:test-client", "0")).To(Succeed())
+			cache.Invalidate("rate_limit:test-client")
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+
+			resolver.Set(clientID, rate_limiter.Override{Limit: 3})
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+		})
+
+		It("should forget a denied client on Reset", func() {
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1, rate_limiter.WithClock(clock), rate_limiter.WithDeniedCache(cache))
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+
+			Expect(rateLimiter.Reset(context.Background(), clientID)).To(Succeed())
+
+			Expect(cache.Len()).To(BeZero())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+		})
+
+		It("should forget a denied client reset by another instance", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			Expect(cache.Listen(ctx, client)).To(Succeed())
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1, rate_limiter.WithClock(clock), rate_limiter.WithDeniedCache(cache))
+			other := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1,
+				rate_limiter.WithClock(clock), rate_limiter.WithDeniedCache(rate_limiter.NewDeniedCache(10)))
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+
+			Expect(other.Reset(context.Background(), clientID)).To(Succeed())
+
+			Expect(cache.Len()).To(BeZero())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+		})
+	})
+
+	Describe("with aligned windows", func() {
+		var (
+			clock  *rate_limiter.ManualClock
+			client *rate_limiter.MemoryClient
+		)
+
+		BeforeEach(func() {
+			// 4 seconds into a 10 second epoch window
+			clock = rate_limiter.NewManualClock(time.Unix(1_700_000_004, 0))
+			client = rate_limiter.NewMemoryClient(clock)
+		})
+
+		It("should reset every client at the same epoch boundary", func() {
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1,
+				rate_limiter.WithClock(clock), rate_limiter.WithWindowAlignment(rate_limiter.WINDOW_ALIGNMENT_EPOCH))
+			Expect(rateLimiter.LimitRequests("first")).To(BeTrue())
+			clock.Advance(3 * time.Second)
+			Expect(rateLimiter.LimitRequests("second")).To(BeTrue())
+			Expect(rateLimiter.LimitRequests("first")).To(BeFalse())
+			Expect(rateLimiter.LimitRequests("second")).To(BeFalse())
+
+			Expect(client.Get("rate_limit:first:170000000")).To(Equal("1"))
+			state, err := rateLimiter.Peek(context.Background(), "second")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.ResetAfter).To(Equal(3 * time.Second))
+
+			clock.Advance(3 * time.Second)
+			Expect(rateLimiter.LimitRequests("first")).To(BeTrue())
+			Expect(rateLimiter.LimitRequests("second")).To(BeTrue())
+		})
+
+		It("should offset each client's windows by a fixed amount", func() {
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1,
+				rate_limiter.WithClock(clock), rate_limiter.WithWindowAlignment(rate_limiter.WINDOW_ALIGNMENT_STAGGERED))
+
+			resetAfter := map[time.Duration]bool{}
+			for _, id := range []string{"a", "b", "c", "d", "e"} {
+				Expect(rateLimiter.LimitRequests(id)).To(BeTrue())
+				state, err := rateLimiter.Peek(context.Background(), id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state.ResetAfter).To(And(BeNumerically(">", 0), BeNumerically("<=", 10*time.Second)))
+				resetAfter[state.ResetAfter] = true
+
+				clock.Advance(state.ResetAfter - time.Millisecond)
+				Expect(rateLimiter.LimitRequests(id)).To(BeFalse())
+				clock.Advance(time.Millisecond)
+				Expect(rateLimiter.LimitRequests(id)).To(BeTrue())
+				clock.Set(time.Unix(1_700_000_004, 0))
+			}
+			Expect(len(resetAfter)).To(BeNumerically(">", 1))
+		})
+
+		It("should refund only the window that was charged", func() {
+			rateLimiter = fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, windowSize, 1,
+				rate_limiter.WithClock(clock), rate_limiter.WithWindowAlignment(rate_limiter.WINDOW_ALIGNMENT_EPOCH))
+			decision := rateLimiter.Decide(clientID)
+			Expect(decision.Allowed).To(BeTrue())
+
+			clock.Advance(6 * time.Second)
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+			Expect(rateLimiter.RefundDecision(context.Background(), decision, 1)).To(Succeed())
+			Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+		})
 	})
 })
+
+// countingClient counts the reads that reach the backend
+type countingClient struct {
+	*rate_limiter.MemoryClient
+	gets int
+}
+
+func (c *countingClient) Get(key string) (string, error) {
+	c.gets++
+	return c.MemoryClient.Get(key)
+}
@@ -0,0 +1,81 @@
+package fixed_window_counter_ratelimiter_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("FixedWindowCounterRatelimiter refunds", func() {
+	var (
+		clock   *rate_limiter.ManualClock
+		client  rate_limiter.RedisClientInterface
+		advance func(time.Duration)
+	)
+
+	refundAcrossRollover := func() {
+		rateLimiter := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 10, 2, rate_limiter.WithClock(clock))
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		advance(10*time.Second - 5*time.Millisecond)
+		late := rateLimiter.Decide("client")
+		Expect(late.Allowed).To(BeTrue())
+
+		advance(10 * time.Millisecond)
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		Expect(rateLimiter.RefundDecision(context.Background(), late, 1)).To(Succeed())
+
+		Expect(client.Get("rate_limit:client")).To(Equal("2"))
+		Expect(rateLimiter.LimitRequests("client")).To(BeFalse())
+	}
+
+	refundWithinWindow := func() {
+		rateLimiter := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 10, 2, rate_limiter.WithClock(clock))
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		advance(10*time.Second - 5*time.Millisecond)
+		late := rateLimiter.Decide("client")
+
+		Expect(rateLimiter.RefundDecision(context.Background(), late, 1)).To(Succeed())
+
+		Expect(client.Get("rate_limit:client")).To(Equal("1"))
+	}
+
+	Context("on the in-memory store", func() {
+		BeforeEach(func() {
+			clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+			client = rate_limiter.NewMemoryClient(clock)
+			advance = clock.Advance
+		})
+
+		It("should not refund a decision charged just before its window ended into the next one", refundAcrossRollover)
+		It("should refund a decision charged just before its window ends while it lasts", refundWithinWindow)
+	})
+
+	Context("on Redis", func() {
+		BeforeEach(func() {
+			clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+			server := miniredis.NewMiniRedis()
+			Expect(server.Start()).To(Succeed())
+			DeferCleanup(server.Close)
+			server.SetTime(clock.Now())
+			redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			DeferCleanup(redisClient.Close)
+			client = rate_limiter.NewRedisClient(redisClient)
+			advance = func(d time.Duration) {
+				clock.Advance(d)
+				server.FastForward(d)
+				server.SetTime(clock.Now())
+			}
+		})
+
+		It("should not refund a decision charged just before its window ended into the next one", refundAcrossRollover)
+		It("should refund a decision charged just before its window ends while it lasts", refundWithinWindow)
+	})
+})
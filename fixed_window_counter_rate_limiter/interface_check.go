@@ -3,3 +3,10 @@ package fixed_window_counter_ratelimiter
 import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 
 var _ rate_limiter.RateLimiterInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.ScriptableInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.RollbackInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.LeasableInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.InspectorInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.RefundInterface = (*FixedWindowCounterRateLimiter)(nil)
+var _ rate_limiter.DecisionRefundInterface = (*FixedWindowCounterRateLimiter)(nil)
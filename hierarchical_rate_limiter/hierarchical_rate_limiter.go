@@ -0,0 +1,135 @@
+package hierarchical_rate_limiter
+
+import (
+	"fmt"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+// Level is one tier of the hierarchy, such as user, organization, plan or global
+type Level struct {
+	Name    string
+	Limiter rate_limiter.RateLimiterInterface
+	// KeyFunc maps the client id passed to LimitRequests to this level's key, nil uses it as-is
+	KeyFunc func(clientId string) string
+	// Borrow lets a request this level denies through when the next level up still has spare
+	// capacity, i.e. its remaining quota after the request is at least BorrowReserve
+	Borrow        bool
+	BorrowReserve int64
+}
+
+// HierarchicalDecision holds the outcome of every level for one request
+type HierarchicalDecision struct {
+	Allowed bool
+	// DeniedBy is the index of the level that denied the request, -1 when allowed
+	DeniedBy int
+	// DeniedLevel is the name of the level that denied the request
+	DeniedLevel string
+	// Borrowed lists the levels that were over their own limit and borrowed from their parent
+	Borrowed  []string
+	Decisions []rate_limiter.Decision
+	Err       error
+}
+
+// HierarchicalRateLimiter enforces a limit at every level of a tenant hierarchy, ordered from the
+// most specific level (user) to the least specific one (global). Levels reuse the existing
+// algorithms, so each level may use a different one. When any level denies, the quota already
+// consumed at the other levels is rolled back.
+type HierarchicalRateLimiter struct {
+	levels  []Level
+	options rate_limiter.Options
+}
+
+func NewHierarchicalRateLimiter(levels []Level, opts ...rate_limiter.Option) *HierarchicalRateLimiter {
+	return &HierarchicalRateLimiter{
+		levels:  levels,
+		options: rate_limiter.NewOptions(opts...),
+	}
+}
+
+func (h *HierarchicalRateLimiter) LimitRequests(clientId string) bool {
+	return h.Decide(clientId).Allowed
+}
+
+// Decide returns the decision of the level that denied the request, or of the most specific level
+func (h *HierarchicalRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	keys := make([]string, len(h.levels))
+	for i, level := range h.levels {
+		keys[i] = rate_limiter.KeyFor(level.KeyFunc, clientId)
+	}
+
+	hierarchical := h.DecideKeys(keys)
+
+	decision := rate_limiter.Decision{Key: clientId, Allowed: hierarchical.Allowed, Err: hierarchical.Err}
+	if hierarchical.DeniedBy >= 0 {
+		decision = hierarchical.Decisions[hierarchical.DeniedBy]
+		decision.Err = hierarchical.Err
+	} else if len(hierarchical.Decisions) > 0 {
+		decision = hierarchical.Decisions[0]
+		decision.Allowed = true
+	}
+	decision.Key = clientId
+
+	h.options.Logger.Log(decision)
+	return decision
+}
+
+// DecideKeys evaluates one request where keys[i] is the key of the i-th level
+func (h *HierarchicalRateLimiter) DecideKeys(keys []string) HierarchicalDecision {
+	hierarchical := HierarchicalDecision{DeniedBy: -1}
+	if len(keys) != len(h.levels) {
+		hierarchical.DeniedBy = 0
+		hierarchical.Err = fmt.Errorf("got %d keys for %d levels", len(keys), len(h.levels))
+		return hierarchical
+	}
+
+	borrowing := false
+	for i, level := range h.levels {
+		decision := rate_limiter.Evaluate(level.Limiter, keys[i])
+		hierarchical.Decisions = append(hierarchical.Decisions, decision)
+
+		if borrowing {
+			borrowing = false
+			if decision.Allowed && decision.Remaining < h.levels[i-1].BorrowReserve {
+				h.deny(&hierarchical, i-1, i)
+				return hierarchical
+			}
+		}
+
+		if decision.Allowed {
+			continue
+		}
+
+		if level.Borrow && decision.Err == nil && i+1 < len(h.levels) {
+			borrowing = true
+			hierarchical.Borrowed = append(hierarchical.Borrowed, level.Name)
+			continue
+		}
+
+		h.deny(&hierarchical, i, i)
+		return hierarchical
+	}
+
+	hierarchical.Allowed = true
+	return hierarchical
+}
+
+// deny records deniedBy as the denying level and rolls back every level up to last
+func (h *HierarchicalRateLimiter) deny(hierarchical *HierarchicalDecision, deniedBy int, last int) {
+	hierarchical.DeniedBy = deniedBy
+	hierarchical.DeniedLevel = h.levels[deniedBy].Name
+	hierarchical.Borrowed = nil
+	hierarchical.Err = rate_limiter.RollbackSequence(h.limiters()[:last+1], hierarchical.Decisions[:last+1], hierarchical.Decisions[last].Err, h.levelName)
+}
+
+func (h *HierarchicalRateLimiter) limiters() []rate_limiter.RateLimiterInterface {
+	limiters := make([]rate_limiter.RateLimiterInterface, len(h.levels))
+	for i, level := range h.levels {
+		limiters[i] = level.Limiter
+	}
+	return limiters
+}
+
+func (h *HierarchicalRateLimiter) levelName(i int) string {
+	return "level " + h.levels[i].Name
+}
@@ -0,0 +1,105 @@
+package hierarchical_rate_limiter_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/hierarchical_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+)
+
+var _ = Describe("HierarchicalRateLimiter", func() {
+	var (
+		user   *mocks.MockRateLimiter
+		org    *mocks.MockRateLimiter
+		global *mocks.MockRateLimiter
+		levels []hierarchical_rate_limiter.Level
+		keys   []string
+	)
+
+	decideWith := func(allowed bool, remaining int64) func(string) rate_limiter.Decision {
+		return func(clientId string) rate_limiter.Decision {
+			return rate_limiter.Decision{Key: clientId, Allowed: allowed, Remaining: remaining}
+		}
+	}
+
+	BeforeEach(func() {
+		user = mocks.NewMockRateLimiter()
+		org = mocks.NewMockRateLimiter()
+		global = mocks.NewMockRateLimiter()
+		user.DecideFunc = decideWith(true, 5)
+		org.DecideFunc = decideWith(true, 50)
+		global.DecideFunc = decideWith(true, 500)
+		levels = []hierarchical_rate_limiter.Level{
+			{Name: "user", Limiter: user},
+			{Name: "org", Limiter: org},
+			{Name: "global", Limiter: global},
+		}
+		keys = []string{"user-1", "org-1", "global"}
+	})
+
+	It("should allow the request when every level allows it", func() {
+		result := hierarchical_rate_limiter.NewHierarchicalRateLimiter(levels).DecideKeys(keys)
+
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.DeniedBy).To(Equal(-1))
+		Expect(user.Calls).To(Equal([]string{"user-1"}))
+		Expect(org.Calls).To(Equal([]string{"org-1"}))
+		Expect(global.Calls).To(Equal([]string{"global"}))
+	})
+
+	It("should report the denying level and roll back the levels below it", func() {
+		org.DecideFunc = decideWith(false, 0)
+
+		result := hierarchical_rate_limiter.NewHierarchicalRateLimiter(levels).DecideKeys(keys)
+
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.DeniedBy).To(Equal(1))
+		Expect(result.DeniedLevel).To(Equal("org"))
+		Expect(user.Rollbacks).To(HaveLen(1))
+		Expect(global.Calls).To(BeEmpty())
+	})
+
+	It("should let a level borrow unused capacity from its parent", func() {
+		user.DecideFunc = decideWith(false, 0)
+		levels[0].Borrow = true
+		levels[0].BorrowReserve = 10
+
+		result := hierarchical_rate_limiter.NewHierarchicalRateLimiter(levels).DecideKeys(keys)
+
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Borrowed).To(Equal([]string{"user"}))
+	})
+
+	It("should not borrow past the parent's reserve", func() {
+		user.DecideFunc = decideWith(false, 0)
+		org.DecideFunc = decideWith(true, 3)
+		levels[0].Borrow = true
+		levels[0].BorrowReserve = 10
+
+		result := hierarchical_rate_limiter.NewHierarchicalRateLimiter(levels).DecideKeys(keys)
+
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.DeniedLevel).To(Equal("user"))
+		Expect(result.Borrowed).To(BeEmpty())
+		Expect(org.Rollbacks).To(HaveLen(1))
+		Expect(global.Calls).To(BeEmpty())
+	})
+
+	It("should map a single client id to every level's key", func() {
+		levels[1].KeyFunc = func(clientId string) string { return "org-of-" + clientId }
+		levels[2].KeyFunc = func(string) string { return "global" }
+
+		Expect(hierarchical_rate_limiter.NewHierarchicalRateLimiter(levels).LimitRequests("user-1")).To(BeTrue())
+		Expect(org.Calls).To(Equal([]string{"org-of-user-1"}))
+		Expect(global.Calls).To(Equal([]string{"global"}))
+	})
+
+	It("should reject a key list that does not match the levels", func() {
+		result := hierarchical_rate_limiter.NewHierarchicalRateLimiter(levels).DecideKeys(keys[:2])
+
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.Err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,6 @@
+package hierarchical_rate_limiter
+
+import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+
+var _ rate_limiter.RateLimiterInterface = (*HierarchicalRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*HierarchicalRateLimiter)(nil)
@@ -0,0 +1,221 @@
+package leased_rate_limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const (
+	DEFAULT_LEASE_SIZE = 10
+	DEFAULT_LEASE_TTL  = time.Second
+)
+
+var ErrClosed = errors.New("leased rate limiter is closed")
+
+// LeaseConfig tunes how much quota a LeasedRateLimiter takes from the backend at once
+type LeaseConfig struct {
+	// LeaseSize is how many units are leased per round trip, DEFAULT_LEASE_SIZE when zero
+	LeaseSize int
+	// MaxOvershoot and Instances derive LeaseSize from the overshoot the fleet may cause, as
+	// MaxOvershoot / Instances. They take precedence over LeaseSize when both are set.
+	MaxOvershoot int
+	Instances    int
+	// LeaseTTL is how long leased units may be spent locally before the unspent ones are returned,
+	// DEFAULT_LEASE_TTL when zero. Keep it shorter than the backend's window or refill period.
+	LeaseTTL time.Duration
+	Clock    rate_limiter.ClockInterface
+}
+
+// LeasedRateLimiter serves very hot keys from quota leased out of a Redis-backed limiter, so that
+// only one request per lease reaches Redis. Leased units are consumed in Redis up front, so the
+// backend never admits more than its limit. The overshoot comes from timing: units leased near the
+// end of a window, or before a bucket refilled, are spent after the backend has handed out fresh
+// quota. Each instance holds at most LeaseSize such units per key, so the fleet admits at most
+// LeaseSize × instances requests per key beyond the backend limit; see MaxOvershoot. Unspent
+// units also look used to other instances until their lease expires and they are returned. They
+// are only returned to the window they were leased from, so they never free quota in a later one.
+type LeasedRateLimiter struct {
+	limiter rate_limiter.LeasableInterface
+	config  LeaseConfig
+
+	mu      sync.Mutex
+	leases  map[string]*lease
+	sweptAt time.Time
+	closed  bool
+}
+
+// lease is the local quota held for one key. Its mutex is held across the round trip that renews
+// it, so concurrent requests for the same key share one lease instead of each taking their own.
+type lease struct {
+	mu        sync.Mutex
+	grant     rate_limiter.LeaseGrant
+	remaining int
+	expiresAt time.Time
+	// removed is set once the lease has left the map, so holders of a stale pointer look it up again
+	removed bool
+}
+
+func NewLeasedRateLimiter(limiter rate_limiter.LeasableInterface, config LeaseConfig) *LeasedRateLimiter {
+	if config.MaxOvershoot > 0 && config.Instances > 0 {
+		config.LeaseSize = max(config.MaxOvershoot/config.Instances, 1)
+	}
+	if config.LeaseSize <= 0 {
+		config.LeaseSize = DEFAULT_LEASE_SIZE
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = DEFAULT_LEASE_TTL
+	}
+	if config.Clock == nil {
+		config.Clock = rate_limiter.SystemClock{}
+	}
+	return &LeasedRateLimiter{
+		limiter: limiter,
+		config:  config,
+		leases:  make(map[string]*lease),
+	}
+}
+
+// MaxOvershoot is how many requests per key instances of this limiter may admit together beyond
+// the backend limit in the worst case
+func (l *LeasedRateLimiter) MaxOvershoot(instances int) int {
+	return l.config.LeaseSize * instances
+}
+
+func (l *LeasedRateLimiter) LimitRequests(clientId string) bool {
+	return l.Decide(clientId).Allowed
+}
+
+// Decide spends one unit of the local lease for clientId, leasing more from the backend when the
+// lease is used up or has expired. Algorithm, Limit and Window come from the backend's last grant;
+// Remaining is what is left of the local lease.
+func (l *LeasedRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	l.sweep()
+	decision := rate_limiter.Decision{Key: clientId}
+
+	for {
+		current, err := l.lease(clientId)
+		if err != nil {
+			decision.Err = err
+			return decision
+		}
+
+		current.mu.Lock()
+		if current.removed {
+			current.mu.Unlock()
+			continue
+		}
+		decision.Allowed, decision.Err = l.spend(clientId, current)
+		decision.Algorithm, decision.Limit, decision.Window = current.grant.Algorithm, current.grant.Limit, current.grant.Window
+		decision.Remaining = int64(current.remaining)
+		current.mu.Unlock()
+		return decision
+	}
+}
+
+// spend takes one unit from current, renewing it first when needed. Callers hold current.mu.
+func (l *LeasedRateLimiter) spend(clientId string, current *lease) (bool, error) {
+	now := l.config.Clock.Now()
+	if current.remaining > 0 && now.Before(current.expiresAt) {
+		current.remaining--
+		return true, nil
+	}
+
+	var returnErr error
+	if current.remaining > 0 {
+		returnErr = l.limiter.ReturnLease(current.grant, current.remaining)
+		current.remaining = 0
+	}
+
+	grant, err := l.limiter.Lease(clientId, l.config.LeaseSize)
+	current.grant = grant
+	if grant.Units > 0 {
+		current.remaining = grant.Units - 1
+		current.expiresAt = now.Add(l.config.LeaseTTL)
+	}
+	return grant.Units > 0, errors.Join(returnErr, err)
+}
+
+func (l *LeasedRateLimiter) lease(clientId string) (*lease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, ErrClosed
+	}
+	current, ok := l.leases[clientId]
+	if !ok {
+		current = &lease{}
+		l.leases[clientId] = current
+	}
+	return current, nil
+}
+
+// sweep drops expired leases at most once per LeaseTTL and returns their unspent units, so that
+// keys which went quiet neither hold quota nor memory. Leases busy renewing are left for later.
+func (l *LeasedRateLimiter) sweep() {
+	now := l.config.Clock.Now()
+	l.mu.Lock()
+	if now.Sub(l.sweptAt) < l.config.LeaseTTL {
+		l.mu.Unlock()
+		return
+	}
+	l.sweptAt = now
+
+	var unspent []unspentUnits
+	for clientId, current := range l.leases {
+		if !current.mu.TryLock() {
+			continue
+		}
+		if !now.Before(current.expiresAt) {
+			unspent = append(unspent, unspentUnits{grant: current.grant, remaining: current.remaining})
+			current.removed = true
+			delete(l.leases, clientId)
+		}
+		current.mu.Unlock()
+	}
+	l.mu.Unlock()
+
+	// Best effort: units that cannot be returned are released when the backend's window ends
+	_ = l.returnAll(unspent)
+}
+
+// Close returns the unspent units of every lease to the backend. Decisions made after Close fail
+// with ErrClosed.
+func (l *LeasedRateLimiter) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	leases := l.leases
+	l.leases = make(map[string]*lease)
+	l.mu.Unlock()
+
+	unspent := make([]unspentUnits, 0, len(leases))
+	for _, current := range leases {
+		current.mu.Lock()
+		unspent = append(unspent, unspentUnits{grant: current.grant, remaining: current.remaining})
+		current.remaining = 0
+		current.removed = true
+		current.mu.Unlock()
+	}
+	return l.returnAll(unspent)
+}
+
+// unspentUnits are the units left of a grant when its lease is dropped
+type unspentUnits struct {
+	grant     rate_limiter.LeaseGrant
+	remaining int
+}
+
+func (l *LeasedRateLimiter) returnAll(unspent []unspentUnits) error {
+	var errs []error
+	for _, units := range unspent {
+		if units.remaining <= 0 {
+			continue
+		}
+		if err := l.limiter.ReturnLease(units.grant, units.remaining); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package leased_rate_limiter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/leased_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("LeasedRateLimiter", func() {
+	var (
+		clock  *rate_limiter.ManualClock
+		client *rate_limiter.MemoryClient
+		bucket *token_bucket_ratelimiter.TokenBucketRateLimiter
+	)
+
+	BeforeEach(func() {
+		clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		client = rate_limiter.NewMemoryClient(clock)
+		// A bucket that never refills keeps the counts below exact
+		bucket = token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 100, 0, rate_limiter.WithClock(clock))
+	})
+
+	tokens := func() string {
+		count, err := client.Get("rate_limit:client:count")
+		Expect(err).NotTo(HaveOccurred())
+		return count
+	}
+
+	It("should serve a whole lease from a single round trip", func() {
+		limiter := leased_rate_limiter.NewLeasedRateLimiter(bucket, leased_rate_limiter.LeaseConfig{LeaseSize: 10, Clock: clock})
+
+		for i := 0; i < 10; i++ {
+			decision := limiter.Decide("client")
+			Expect(decision.Allowed).To(BeTrue())
+			Expect(decision.Remaining).To(Equal(int64(9 - i)))
+		}
+		Expect(tokens()).To(Equal("90"))
+
+		Expect(limiter.LimitRequests("client")).To(BeTrue())
+		Expect(tokens()).To(Equal("80"))
+	})
+
+	It("should return unspent units when the lease expires", func() {
+		limiter := leased_rate_limiter.NewLeasedRateLimiter(bucket, leased_rate_limiter.LeaseConfig{LeaseSize: 10, LeaseTTL: time.Second, Clock: clock})
+		for i := 0; i < 3; i++ {
+			Expect(limiter.LimitRequests("client")).To(BeTrue())
+		}
+
+		clock.Advance(time.Second)
+		Expect(limiter.LimitRequests("client")).To(BeTrue())
+
+		// 3 spent from the first lease, 7 returned and a fresh lease of 10 taken
+		Expect(tokens()).To(Equal("87"))
+	})
+
+	It("should deny once the backend has no quota left to lease", func() {
+		small := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 5, 0, rate_limiter.WithClock(clock))
+		limiter := leased_rate_limiter.NewLeasedRateLimiter(small, leased_rate_limiter.LeaseConfig{LeaseSize: 10, Clock: clock})
+
+		for i := 0; i < 5; i++ {
+			Expect(limiter.LimitRequests("client")).To(BeTrue())
+		}
+		decision := limiter.Decide("client")
+		Expect(decision.Allowed).To(BeFalse())
+		Expect(decision.Err).NotTo(HaveOccurred())
+	})
+
+	It("should return every lease on Close and refuse decisions afterwards", func() {
+		limiter := leased_rate_limiter.NewLeasedRateLimiter(bucket, leased_rate_limiter.LeaseConfig{LeaseSize: 10, Clock: clock})
+		Expect(limiter.LimitRequests("client")).To(BeTrue())
+
+		Expect(limiter.Close()).To(Succeed())
+
+		Expect(tokens()).To(Equal("99"))
+		Expect(limiter.Decide("client").Err).To(MatchError(leased_rate_limiter.ErrClosed))
+	})
+
+	It("should derive the lease size from the configured overshoot", func() {
+		limiter := leased_rate_limiter.NewLeasedRateLimiter(bucket, leased_rate_limiter.LeaseConfig{MaxOvershoot: 40, Instances: 4, Clock: clock})
+
+		Expect(limiter.MaxOvershoot(4)).To(Equal(40))
+		Expect(limiter.LimitRequests("client")).To(BeTrue())
+		Expect(tokens()).To(Equal("90"))
+	})
+
+	It("should not lease more than a fixed window has left", func() {
+		window := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 10, rate_limiter.WithClock(clock))
+
+		units := func(grant rate_limiter.LeaseGrant, err error) int {
+			Expect(err).NotTo(HaveOccurred())
+			return grant.Units
+		}
+		first, err := window.Lease("client", 4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Units).To(Equal(4))
+		Expect(units(window.Lease("client", 4))).To(Equal(4))
+		Expect(units(window.Lease("client", 4))).To(Equal(2))
+		Expect(units(window.Lease("client", 4))).To(BeZero())
+
+		Expect(window.ReturnLease(first, 3)).To(Succeed())
+		Expect(client.Get("rate_limit:client")).To(Equal("7"))
+	})
+
+	It("should only return fixed window units to the window they were leased from", func() {
+		window := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 10, 10, rate_limiter.WithClock(clock))
+		grant, err := window.Lease("client", 4)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.IncrByIfExists("rate_limit:client", -3)).To(Equal(int64(1)))
+		Expect(window.ReturnLease(grant, 3)).To(Succeed())
+		Expect(client.Get("rate_limit:client")).To(Equal("0"))
+
+		grant, err = window.Lease("client", 4)
+		Expect(err).NotTo(HaveOccurred())
+		clock.Advance(10 * time.Second)
+		Expect(window.LimitRequests("client")).To(BeTrue())
+		Expect(window.ReturnLease(grant, 3)).To(Succeed())
+		Expect(client.Get("rate_limit:client")).To(Equal("1"))
+	})
+
+	It("should describe decisions with the algorithm and limits of the backend", func() {
+		window := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 100, rate_limiter.WithClock(clock))
+		limiter := leased_rate_limiter.NewLeasedRateLimiter(window, leased_rate_limiter.LeaseConfig{LeaseSize: 10, Clock: clock})
+
+		decision := limiter.Decide("client")
+		Expect(decision.Algorithm).To(Equal(rate_limiter.ALGORITHM_FIXED_WINDOW_COUNTER))
+		Expect(decision.Limit).To(Equal(int64(100)))
+		Expect(decision.Window).To(Equal(60 * time.Second))
+		Expect(limiter.Decide("client").Algorithm).To(Equal(rate_limiter.ALGORITHM_FIXED_WINDOW_COUNTER))
+	})
+
+	It("should keep the fleet within lease size times instances of the window limit", func() {
+		const (
+			limit     = 10
+			leaseSize = 4
+			instances = 3
+		)
+		window := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 10, limit, rate_limiter.WithClock(clock))
+		fleet := make([]*leased_rate_limiter.LeasedRateLimiter, instances)
+		for i := range fleet {
+			fleet[i] = leased_rate_limiter.NewLeasedRateLimiter(window, leased_rate_limiter.LeaseConfig{LeaseSize: leaseSize, LeaseTTL: 5 * time.Second, Clock: clock})
+		}
+
+		admitted := make(map[int64]int)
+		for step := 0; step < 300; step++ {
+			for _, limiter := range fleet {
+				if limiter.LimitRequests("client") {
+					admitted[clock.Now().Unix()/10]++
+				}
+			}
+			clock.Advance(100 * time.Millisecond)
+		}
+
+		Expect(admitted).NotTo(BeEmpty())
+		for _, count := range admitted {
+			Expect(count).To(BeNumerically("<=", limit+fleet[0].MaxOvershoot(instances)))
+		}
+	})
+})
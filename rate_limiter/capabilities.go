@@ -0,0 +1,106 @@
+package rate_limiter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedServer is returned when the Redis server lacks a command a limiter depends on
+var ErrUnsupportedServer = errors.New("redis server lacks a required capability")
+
+// ErrHashFieldExpiryUnsupported is returned by HIncrByWithExpiry on servers without HEXPIRE
+var ErrHashFieldExpiryUnsupported = fmt.Errorf("%w: hash field expiry (HEXPIRE) needs Redis 7.4 or later, use WithKeyLayout(KEY_LAYOUT_STRINGS)", ErrUnsupportedServer)
+
+// Capabilities describes the server behind a client. Server and Version come from INFO and are
+// empty when INFO is unavailable; the feature flags are probed directly, so forks that report an
+// unrelated version are judged by what they actually support.
+type Capabilities struct {
+	// Server is "redis", "valkey" or "dragonfly"
+	Server  string
+	Version string
+	// ExpireOptions is set when EXPIRE accepts NX, XX, GT and LT, added in Redis 7.0
+	ExpireOptions bool
+	// HashFieldExpiry is set when HEXPIRE exists, added in Redis 7.4
+	HashFieldExpiry bool
+}
+
+func (c Capabilities) String() string {
+	if c.Server == "" {
+		return "unknown server"
+	}
+	return strings.TrimSpace(c.Server + " " + c.Version)
+}
+
+// Requirements lists the capabilities a limiter cannot work without
+type Requirements struct {
+	ExpireOptions   bool
+	HashFieldExpiry bool
+}
+
+// Missing names every capability in requirements that c lacks
+func (c Capabilities) Missing(requirements Requirements) []string {
+	var missing []string
+	if requirements.ExpireOptions && !c.ExpireOptions {
+		missing = append(missing, "EXPIRE options (Redis 7.0+)")
+	}
+	if requirements.HashFieldExpiry && !c.HashFieldExpiry {
+		missing = append(missing, "HEXPIRE (Redis 7.4+)")
+	}
+	return missing
+}
+
+// CapabilityDetectorInterface is implemented by clients that can report what their server supports
+type CapabilityDetectorInterface interface {
+	DetectCapabilities() (Capabilities, error)
+}
+
+// RequirementsInterface is implemented by limiters that only work on servers with certain capabilities
+type RequirementsInterface interface {
+	Requirements() Requirements
+}
+
+// CheckServer detects the capabilities of client's server and fails when any of limiters needs one
+// it lacks. Call it at startup so that an old server stops the process instead of quietly
+// breaking the limits. Clients that cannot detect capabilities pass unchecked.
+func CheckServer(client RedisClientInterface, limiters ...RateLimiterInterface) error {
+	detector, ok := client.(CapabilityDetectorInterface)
+	if !ok {
+		return nil
+	}
+	capabilities, err := detector.DetectCapabilities()
+	if err != nil {
+		return fmt.Errorf("detecting redis capabilities: %w", err)
+	}
+
+	for _, limiter := range limiters {
+		requirer, ok := limiter.(RequirementsInterface)
+		if !ok {
+			continue
+		}
+		if missing := capabilities.Missing(requirer.Requirements()); len(missing) > 0 {
+			return fmt.Errorf("%w: %T needs %s, which %s does not support", ErrUnsupportedServer, limiter, strings.Join(missing, " and "), capabilities)
+		}
+	}
+	return nil
+}
+
+// parseServerInfo reads the server name and version from the reply to INFO server. Valkey and
+// Dragonfly also report a redis_version for compatibility, so their own fields win.
+func parseServerInfo(info string) (server string, version string) {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(line), ":"); ok {
+			fields[name] = value
+		}
+	}
+	for _, server := range []string{"valkey", "dragonfly"} {
+		if version, ok := fields[server+"_version"]; ok {
+			return server, version
+		}
+	}
+	if version, ok := fields["redis_version"]; ok {
+		return "redis", version
+	}
+	return "", ""
+}
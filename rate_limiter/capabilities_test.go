@@ -0,0 +1,109 @@
+package rate_limiter_test
+
+import (
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("Capabilities", func() {
+	var (
+		server *miniredis.Miniredis
+		client *rate_limiter.RedisClient
+	)
+
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+
+		redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		DeferCleanup(redisClient.Close)
+		client = rate_limiter.NewRedisClient(redisClient)
+	})
+
+	It("should detect expiry options and hash field expiry by probing the server", func() {
+		capabilities, err := client.DetectCapabilities()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(capabilities.ExpireOptions).To(BeTrue())
+		Expect(capabilities.HashFieldExpiry).To(BeTrue())
+		Expect(server.Exists(rate_limiter.CAPABILITY_PROBE_KEY)).To(BeFalse())
+	})
+
+	It("should name the capabilities a server lacks", func() {
+		capabilities := rate_limiter.Capabilities{ExpireOptions: true}
+		Expect(capabilities.Missing(rate_limiter.Requirements{ExpireOptions: true, HashFieldExpiry: true})).To(ConsistOf("HEXPIRE (Redis 7.4+)"))
+		Expect(capabilities.Missing(rate_limiter.Requirements{ExpireOptions: true})).To(BeEmpty())
+	})
+
+	Context("on a server older than Redis 7.0", func() {
+		BeforeEach(func() {
+			client.SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "6.2.0"})
+		})
+
+		It("should emulate NX by keeping an existing expiry", func() {
+			_, err := client.IncrWithExpiry("counter", time.Minute, rate_limiter.EXPIRY_MODE_NX)
+			Expect(err).NotTo(HaveOccurred())
+			count, err := client.IncrWithExpiry("counter", time.Hour, rate_limiter.EXPIRY_MODE_NX)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(count).To(Equal(int64(2)))
+			Expect(server.TTL("counter")).To(Equal(time.Minute))
+		})
+
+		It("should emulate GT and LT against the current expiry", func() {
+			Expect(client.Set("key", "1")).To(Succeed())
+			Expect(client.Expire("key", time.Minute, rate_limiter.EXPIRY_MODE_GT)).To(Succeed())
+			Expect(server.TTL("key")).To(BeZero(), "GT never applies to a key without an expiry")
+
+			Expect(client.Expire("key", time.Minute, rate_limiter.EXPIRY_MODE_LT)).To(Succeed())
+			Expect(server.TTL("key")).To(Equal(time.Minute))
+
+			Expect(client.Expire("key", time.Hour, rate_limiter.EXPIRY_MODE_LT)).To(Succeed())
+			Expect(server.TTL("key")).To(Equal(time.Minute))
+			Expect(client.Expire("key", time.Hour, rate_limiter.EXPIRY_MODE_GT)).To(Succeed())
+			Expect(server.TTL("key")).To(Equal(time.Hour))
+		})
+
+		It("should refuse hash field expiry instead of leaving fields that never expire", func() {
+			_, err := client.HIncrByWithExpiry("hash", "field", 1, time.Minute, rate_limiter.EXPIRY_MODE_NX)
+			Expect(err).To(MatchError(rate_limiter.ErrHashFieldExpiryUnsupported))
+			Expect(err).To(MatchError(rate_limiter.ErrUnsupportedServer))
+			Expect(server.Exists("hash")).To(BeFalse())
+		})
+	})
+
+	Describe("CheckServer", func() {
+		It("should fail for the hash layout of the sliding window counter without HEXPIRE", func() {
+			client.SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "7.2.4", ExpireOptions: true})
+			// Built on a client that cannot detect capabilities, the limiter keeps the hash layout
+			limiter := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(mocks.NewMockRedisClient(), 10, 60, 1)
+
+			err := rate_limiter.CheckServer(client, limiter)
+			Expect(err).To(MatchError(rate_limiter.ErrUnsupportedServer))
+			Expect(err.Error()).To(ContainSubstring("redis 7.2.4"))
+		})
+
+		It("should pass limiters that work on the server", func() {
+			client.SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "6.2.0"})
+			counter := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 10, 60, 1,
+				rate_limiter.WithKeyLayout(rate_limiter.KEY_LAYOUT_STRINGS))
+			bucket := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 10, 1)
+
+			Expect(rate_limiter.CheckServer(client, counter, bucket)).To(Succeed())
+		})
+
+		It("should pass clients that cannot detect capabilities", func() {
+			limiter := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 10, 60, 1)
+			Expect(rate_limiter.CheckServer(mocks.NewMockRedisClient(), limiter)).To(Succeed())
+		})
+	})
+})
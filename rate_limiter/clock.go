@@ -0,0 +1,46 @@
+package rate_limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// ClockInterface is the source of time used by rate limiters and in-memory backends
+type ClockInterface interface {
+	Now() time.Time
+}
+
+// SystemClock reads the local wall clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock only moves when told to, for tests and simulations that run on virtual time
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (m *ManualClock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *ManualClock) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+func (m *ManualClock) Advance(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(duration)
+}
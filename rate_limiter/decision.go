@@ -0,0 +1,45 @@
+package rate_limiter
+
+import "time"
+
+// Algorithm identifies the rate limiting algorithm that produced a Decision
+type Algorithm string
+
+const (
+	ALGORITHM_TOKEN_BUCKET           Algorithm = "token_bucket"
+	ALGORITHM_FIXED_WINDOW_COUNTER   Algorithm = "fixed_window_counter"
+	ALGORITHM_SLIDING_WINDOW_LOG     Algorithm = "sliding_window_log"
+	ALGORITHM_SLIDING_WINDOW_COUNTER Algorithm = "sliding_window_counter"
+	ALGORITHM_ADAPTIVE_CONCURRENCY   Algorithm = "adaptive_concurrency"
+	ALGORITHM_CALENDAR_QUOTA         Algorithm = "calendar_quota"
+)
+
+// Decision describes the outcome of a single rate limit evaluation
+type Decision struct {
+	Algorithm Algorithm
+	Key       string
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	Window    time.Duration
+	Err       error
+	// Charge identifies the quota consumed by an allowed decision, such as a log entry,
+	// sub-window or the time a fixed window was charged, so that it can be rolled back or refunded later
+	Charge string
+}
+
+// DeciderInterface is implemented by rate limiters that can report the details
+// behind a LimitRequests call instead of a bare bool
+type DeciderInterface interface {
+	RateLimiterInterface
+	Decide(clientId string) Decision
+}
+
+// Evaluate runs limiter for clientId and returns the full Decision when the limiter
+// supports it, falling back to a Decision carrying only the allow/deny outcome
+func Evaluate(limiter RateLimiterInterface, clientId string) Decision {
+	if decider, ok := limiter.(DeciderInterface); ok {
+		return decider.Decide(clientId)
+	}
+	return Decision{Key: clientId, Allowed: limiter.LimitRequests(clientId)}
+}
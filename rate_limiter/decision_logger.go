@@ -0,0 +1,132 @@
+package rate_limiter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const DEFAULT_MAX_TRACKED_LOG_KEYS = 10000
+
+// DecisionLoggerConfig controls which decisions a DecisionLogger writes
+type DecisionLoggerConfig struct {
+	// DenySampleRate is the fraction of denials logged, values <= 0 log every denial
+	DenySampleRate float64
+	// AllowSampleRate is the fraction of allowed requests logged, 0 disables allow logging
+	AllowSampleRate float64
+	// Redact rewrites the client id before it is written, nil logs it as-is
+	Redact func(clientId string) string
+	// PerKeyLimit caps the number of lines logged per key within PerKeyInterval, 0 disables the cap
+	PerKeyLimit    int
+	PerKeyInterval time.Duration
+	// MaxTrackedKeys bounds the memory used by the per-key cap, keys past it are not logged
+	MaxTrackedKeys int
+}
+
+// DecisionLogger writes rate limit decisions to a slog.Logger as structured records
+type DecisionLogger struct {
+	logger *slog.Logger
+	config DecisionLoggerConfig
+
+	mu            sync.Mutex
+	intervalStart time.Time
+	keyCounts     map[string]int
+}
+
+func NewDecisionLogger(logger *slog.Logger, config DecisionLoggerConfig) *DecisionLogger {
+	if config.DenySampleRate <= 0 {
+		config.DenySampleRate = 1
+	}
+	if config.PerKeyLimit > 0 && config.PerKeyInterval <= 0 {
+		config.PerKeyInterval = time.Minute
+	}
+	if config.MaxTrackedKeys <= 0 {
+		config.MaxTrackedKeys = DEFAULT_MAX_TRACKED_LOG_KEYS
+	}
+
+	return &DecisionLogger{
+		logger:    logger,
+		config:    config,
+		keyCounts: make(map[string]int),
+	}
+}
+
+// HashRedactor returns a Redact function that replaces client ids with a salted SHA-256 prefix,
+// so the same client can still be correlated across log lines without exposing the id
+func HashRedactor(salt string) func(string) string {
+	return func(clientId string) string {
+		sum := sha256.Sum256([]byte(salt + clientId))
+		return hex.EncodeToString(sum[:8])
+	}
+}
+
+// Log records decision if it passes sampling and the per-key cap. A nil DecisionLogger is a no-op.
+func (l *DecisionLogger) Log(decision Decision) {
+	if l == nil || l.logger == nil {
+		return
+	}
+
+	sampleRate := l.config.DenySampleRate
+	if decision.Allowed {
+		sampleRate = l.config.AllowSampleRate
+	}
+	if sampleRate <= 0 || (sampleRate < 1 && rand.Float64() >= sampleRate) {
+		return
+	}
+
+	if !l.takeKeyBudget(decision.Key) {
+		return
+	}
+
+	key := decision.Key
+	if l.config.Redact != nil {
+		key = l.config.Redact(key)
+	}
+
+	attrs := []slog.Attr{
+		slog.String("algorithm", string(decision.Algorithm)),
+		slog.String("key", key),
+		slog.Bool("allowed", decision.Allowed),
+		slog.Int64("limit", decision.Limit),
+		slog.Int64("remaining", decision.Remaining),
+		slog.Duration("window", decision.Window),
+	}
+
+	level := slog.LevelInfo
+	if decision.Err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", decision.Err.Error()))
+	}
+
+	l.logger.LogAttrs(context.Background(), level, "rate limit decision", attrs...)
+}
+
+func (l *DecisionLogger) takeKeyBudget(key string) bool {
+	if l.config.PerKeyLimit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.intervalStart) >= l.config.PerKeyInterval {
+		l.intervalStart = now
+		clear(l.keyCounts)
+	}
+
+	count, tracked := l.keyCounts[key]
+	if !tracked && len(l.keyCounts) >= l.config.MaxTrackedKeys {
+		return false
+	}
+	if count >= l.config.PerKeyLimit {
+		return false
+	}
+
+	l.keyCounts[key] = count + 1
+	return true
+}
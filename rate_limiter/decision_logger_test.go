@@ -0,0 +1,118 @@
+package rate_limiter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("DecisionLogger", func() {
+	var (
+		buffer   *bytes.Buffer
+		logger   *slog.Logger
+		denied   rate_limiter.Decision
+		allowed  rate_limiter.Decision
+		logLines func() []map[string]any
+	)
+
+	BeforeEach(func() {
+		buffer = &bytes.Buffer{}
+		logger = slog.New(slog.NewJSONHandler(buffer, nil))
+		denied = rate_limiter.Decision{
+			Algorithm: rate_limiter.ALGORITHM_FIXED_WINDOW_COUNTER,
+			Key:       "test-client",
+			Limit:     5,
+			Remaining: 0,
+			Window:    10 * time.Second,
+		}
+		allowed = denied
+		allowed.Allowed = true
+		allowed.Remaining = 3
+
+		logLines = func() []map[string]any {
+			var lines []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				var record map[string]any
+				Expect(json.Unmarshal([]byte(line), &record)).To(Succeed())
+				lines = append(lines, record)
+			}
+			return lines
+		}
+	})
+
+	It("should log denials with structured fields", func() {
+		decisionLogger := rate_limiter.NewDecisionLogger(logger, rate_limiter.DecisionLoggerConfig{})
+		decisionLogger.Log(denied)
+
+		lines := logLines()
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(HaveKeyWithValue("algorithm", "fixed_window_counter"))
+		Expect(lines[0]).To(HaveKeyWithValue("key", "test-client"))
+		Expect(lines[0]).To(HaveKeyWithValue("allowed", false))
+		Expect(lines[0]).To(HaveKeyWithValue("limit", BeNumerically("==", 5)))
+		Expect(lines[0]).To(HaveKeyWithValue("remaining", BeNumerically("==", 0)))
+		Expect(lines[0]).To(HaveKey("window"))
+		Expect(lines[0]).NotTo(HaveKey("error"))
+	})
+
+	It("should not log allowed requests unless an allow sample rate is set", func() {
+		rate_limiter.NewDecisionLogger(logger, rate_limiter.DecisionLoggerConfig{}).Log(allowed)
+		Expect(logLines()).To(BeEmpty())
+
+		rate_limiter.NewDecisionLogger(logger, rate_limiter.DecisionLoggerConfig{AllowSampleRate: 1}).Log(allowed)
+		Expect(logLines()).To(HaveLen(1))
+	})
+
+	It("should log backend errors at error level", func() {
+		denied.Err = errors.New("redis connection error")
+		rate_limiter.NewDecisionLogger(logger, rate_limiter.DecisionLoggerConfig{}).Log(denied)
+
+		lines := logLines()
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(HaveKeyWithValue("level", "ERROR"))
+		Expect(lines[0]).To(HaveKeyWithValue("error", "redis connection error"))
+	})
+
+	It("should redact client ids", func() {
+		decisionLogger := rate_limiter.NewDecisionLogger(logger, rate_limiter.DecisionLoggerConfig{
+			Redact: rate_limiter.HashRedactor("salt"),
+		})
+		decisionLogger.Log(denied)
+
+		lines := logLines()
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]["key"]).NotTo(Equal("test-client"))
+		Expect(lines[0]["key"]).To(Equal(rate_limiter.HashRedactor("salt")("test-client")))
+	})
+
+	It("should cap the number of lines per key", func() {
+		decisionLogger := rate_limiter.NewDecisionLogger(logger, rate_limiter.DecisionLoggerConfig{
+			PerKeyLimit:    2,
+			PerKeyInterval: time.Hour,
+		})
+		for range 5 {
+			decisionLogger.Log(denied)
+		}
+		other := denied
+		other.Key = "other-client"
+		decisionLogger.Log(other)
+
+		Expect(logLines()).To(HaveLen(3))
+	})
+
+	It("should be a no-op when nil", func() {
+		var decisionLogger *rate_limiter.DecisionLogger
+		Expect(func() { decisionLogger.Log(denied) }).NotTo(Panic())
+	})
+})
@@ -0,0 +1,114 @@
+package rate_limiter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const DEFAULT_DENIED_CACHE_SIZE = 10000
+
+// DENIED_CACHE_INVALIDATION_CHANNEL carries the keys that limiters reset, for the caches of other instances
+const DENIED_CACHE_INVALIDATION_CHANNEL = "rate_limit:denied_cache:invalidate"
+
+type deniedEntry struct {
+	key     string
+	limit   int64
+	resetAt time.Time
+}
+
+// DeniedCache remembers keys that are over their limit until their window resets, so that further
+// requests from a client under attack are denied without a round trip to Redis. It holds at most
+// size keys and evicts the least recently denied one first. Changed limits invalidate an entry on
+// their own; call Invalidate after resetting a key by hand, and Listen to hear of resets made by
+// other instances.
+type DeniedCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// NewDeniedCache holds up to size keys, DEFAULT_DENIED_CACHE_SIZE when size is not positive
+func NewDeniedCache(size int) *DeniedCache {
+	if size <= 0 {
+		size = DEFAULT_DENIED_CACHE_SIZE
+	}
+	return &DeniedCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Denied reports whether key was denied at limit and its window has not reset by now
+func (d *DeniedCache) Denied(key string, limit int64, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	element, ok := d.entries[key]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*deniedEntry)
+	// A raised or lowered limit, e.g. from a new override, needs a fresh look at the counter
+	if entry.limit != limit || !now.Before(entry.resetAt) {
+		d.remove(element)
+		return false
+	}
+	d.order.MoveToFront(element)
+	return true
+}
+
+// Add remembers that key was denied at limit until resetAt
+func (d *DeniedCache) Add(key string, limit int64, resetAt time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.entries[key]; ok {
+		element.Value = &deniedEntry{key: key, limit: limit, resetAt: resetAt}
+		d.order.MoveToFront(element)
+		return
+	}
+	d.entries[key] = d.order.PushFront(&deniedEntry{key: key, limit: limit, resetAt: resetAt})
+	if d.order.Len() > d.size {
+		d.remove(d.order.Back())
+	}
+}
+
+// Invalidate forgets the given keys, or every key when none is given
+func (d *DeniedCache) Invalidate(keys ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(keys) == 0 {
+		clear(d.entries)
+		d.order.Init()
+		return
+	}
+	for _, key := range keys {
+		if element, ok := d.entries[key]; ok {
+			d.remove(element)
+		}
+	}
+}
+
+// Listen subscribes the cache to the keys that limiters broadcast when they reset a client, so that
+// a reset made on another instance is not denied from this cache until the window ends. It returns
+// once subscribed and stops listening when ctx is done.
+func (d *DeniedCache) Listen(ctx context.Context, client PubSubClientInterface) error {
+	return client.Subscribe(ctx, DENIED_CACHE_INVALIDATION_CHANNEL, func(key string) {
+		d.Invalidate(key)
+	})
+}
+
+func (d *DeniedCache) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len()
+}
+
+// remove drops element from the cache. Callers hold mu.
+func (d *DeniedCache) remove(element *list.Element) {
+	d.order.Remove(element)
+	delete(d.entries, element.Value.(*deniedEntry).key)
+}
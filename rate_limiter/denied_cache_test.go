@@ -0,0 +1,55 @@
+package rate_limiter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("DeniedCache", func() {
+	now := time.Unix(1_700_000_000, 0)
+
+	It("should forget a key once its window resets", func() {
+		cache := rate_limiter.NewDeniedCache(10)
+		cache.Add("client", 5, now.Add(time.Second))
+
+		Expect(cache.Denied("client", 5, now)).To(BeTrue())
+		Expect(cache.Denied("client", 5, now.Add(time.Second))).To(BeFalse())
+		Expect(cache.Len()).To(BeZero())
+	})
+
+	It("should evict the least recently denied key when full", func() {
+		cache := rate_limiter.NewDeniedCache(2)
+		cache.Add("first", 5, now.Add(time.Minute))
+		cache.Add("second", 5, now.Add(time.Minute))
+		Expect(cache.Denied("first", 5, now)).To(BeTrue())
+
+		cache.Add("third", 5, now.Add(time.Minute))
+
+		Expect(cache.Len()).To(Equal(2))
+		Expect(cache.Denied("second", 5, now)).To(BeFalse())
+		Expect(cache.Denied("first", 5, now)).To(BeTrue())
+		Expect(cache.Denied("third", 5, now)).To(BeTrue())
+	})
+
+	It("should not deny a key whose limit has changed", func() {
+		cache := rate_limiter.NewDeniedCache(10)
+		cache.Add("client", 5, now.Add(time.Minute))
+
+		Expect(cache.Denied("client", 10, now)).To(BeFalse())
+		Expect(cache.Denied("client", 5, now)).To(BeFalse())
+	})
+
+	It("should forget every key when invalidated without keys", func() {
+		cache := rate_limiter.NewDeniedCache(10)
+		cache.Add("first", 5, now.Add(time.Minute))
+		cache.Add("second", 5, now.Add(time.Minute))
+
+		cache.Invalidate()
+
+		Expect(cache.Len()).To(BeZero())
+	})
+})
@@ -0,0 +1,98 @@
+package rate_limiter
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileOverrideResolver reads overrides from a JSON file mapping client ids to overrides, e.g.
+//
+//	{"customer-1": {"limit": 1000}, "customer-2": {"tier": "pro"}, "abuser": {"limit": 1}}
+//
+// The file's modification time is checked at most once per reload interval and the file re-read
+// when it changed, so edits apply without a restart. Requests between checks never touch the disk.
+type FileOverrideResolver struct {
+	path           string
+	reloadInterval time.Duration
+
+	mu        sync.RWMutex
+	checkedAt time.Time
+	modTime   time.Time
+	overrides map[string]Override
+}
+
+// DEFAULT_FILE_RELOAD_INTERVAL is how often NewFileOverrideResolver checks its file for changes
+const DEFAULT_FILE_RELOAD_INTERVAL = time.Second
+
+func NewFileOverrideResolver(path string) (*FileOverrideResolver, error) {
+	return NewFileOverrideResolverWithInterval(path, DEFAULT_FILE_RELOAD_INTERVAL)
+}
+
+// NewFileOverrideResolverWithInterval checks the file for changes at most once per reloadInterval
+func NewFileOverrideResolverWithInterval(path string, reloadInterval time.Duration) (*FileOverrideResolver, error) {
+	resolver := &FileOverrideResolver{path: path, reloadInterval: reloadInterval}
+	if err := resolver.reload(); err != nil {
+		return nil, err
+	}
+	resolver.checkedAt = time.Now()
+	return resolver, nil
+}
+
+// Resolve returns the error of a failed check once, then serves the last overrides read until the
+// next check
+func (f *FileOverrideResolver) Resolve(clientId string) (Override, bool, error) {
+	if f.checkDue() {
+		if err := f.reload(); err != nil {
+			return Override{}, false, err
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	override, found := f.overrides[clientId]
+	return override, found, nil
+}
+
+// checkDue reports whether the file should be checked, claiming the check for the caller so
+// concurrent requests do not all stat the file
+func (f *FileOverrideResolver) checkDue() bool {
+	now := time.Now()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.Sub(f.checkedAt) < f.reloadInterval {
+		return false
+	}
+	f.checkedAt = now
+	return true
+}
+
+func (f *FileOverrideResolver) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.RLock()
+	upToDate := f.overrides != nil && info.ModTime().Equal(f.modTime)
+	f.mu.RUnlock()
+	if upToDate {
+		return nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	overrides := make(map[string]Override)
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.overrides = overrides
+	f.modTime = info.ModTime()
+	f.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,27 @@
+package rate_limiter
+
+// This is just a compile-time check to ensure the clients implement the interfaces the limiters look for
+var _ RedisClientInterface = (*RedisClient)(nil)
+var _ ScriptRunnerInterface = (*RedisClient)(nil)
+var _ TokenBucketClientInterface = (*RedisClient)(nil)
+var _ SlidingWindowLogClientInterface = (*RedisClient)(nil)
+var _ MultiGetClientInterface = (*RedisClient)(nil)
+var _ CapabilityDetectorInterface = (*RedisClient)(nil)
+var _ ServerTimeInterface = (*RedisClient)(nil)
+var _ BatchScriptRunnerInterface = (*RedisClient)(nil)
+var _ RedisClientInterface = (*MemoryClient)(nil)
+var _ TokenBucketClientInterface = (*MemoryClient)(nil)
+var _ SlidingWindowLogClientInterface = (*MemoryClient)(nil)
+var _ MultiGetClientInterface = (*MemoryClient)(nil)
+var _ CapabilityDetectorInterface = (*MemoryClient)(nil)
+var _ ServerTimeInterface = (*MemoryClient)(nil)
+var _ TTLClientInterface = (*RedisClient)(nil)
+var _ TTLClientInterface = (*MemoryClient)(nil)
+var _ ConcurrencyClientInterface = (*RedisClient)(nil)
+var _ ConcurrencyClientInterface = (*MemoryClient)(nil)
+var _ WindowClientInterface = (*RedisClient)(nil)
+var _ WindowClientInterface = (*MemoryClient)(nil)
+var _ PubSubClientInterface = (*RedisClient)(nil)
+var _ PubSubClientInterface = (*MemoryClient)(nil)
+var _ ExpiringTokenBucketClientInterface = (*RedisClient)(nil)
+var _ ExpiringTokenBucketClientInterface = (*MemoryClient)(nil)
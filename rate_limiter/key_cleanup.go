@@ -0,0 +1,133 @@
+package rate_limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const DEFAULT_CLEANUP_SCAN_COUNT = 1000
+
+// CleanupConfig selects the keys ExpireOrphanedKeys gives an expiry to
+type CleanupConfig struct {
+	// Patterns are SCAN MATCH patterns, e.g. rate_limit:*:count
+	Patterns []string
+	// Expiry is set on every matching key that has none. It must be at least the longest time any
+	// limiter needs the key for, or live state is dropped early.
+	Expiry time.Duration
+	// ScanCount is the SCAN COUNT hint, DEFAULT_CLEANUP_SCAN_COUNT when zero
+	ScanCount int64
+	// DryRun only counts the keys that would be given an expiry
+	DryRun bool
+}
+
+// CleanupStats counts the keys ExpireOrphanedKeys looked at and the ones it gave an expiry to
+type CleanupStats struct {
+	Scanned int
+	Expired int
+}
+
+// expireIfPersistentScript expires a key that has no expiry yet and returns 1, or returns 0 for
+// keys that already have one, such as keys a limiter rewrote with an expiry since the scan
+var expireIfPersistentScript = redis.NewScript(`
+if redis.call('PTTL', KEYS[1]) ~= -1 then
+	return 0
+end
+if ARGV[2] ~= '1' then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// ExpireOrphanedKeys scans the keyspace, every master on Redis Cluster, for keys matching the
+// patterns that were written without an expiry, and gives them one. It is meant to be run once
+// against the keys left behind by limiters that never expired them.
+func (r *RedisClient) ExpireOrphanedKeys(config CleanupConfig) (CleanupStats, error) {
+	if len(config.Patterns) == 0 || config.Expiry <= 0 {
+		return CleanupStats{}, errors.New("cleanup needs at least one pattern and a positive expiry")
+	}
+	if config.ScanCount <= 0 {
+		config.ScanCount = DEFAULT_CLEANUP_SCAN_COUNT
+	}
+
+	var (
+		mu    sync.Mutex
+		stats CleanupStats
+	)
+	scanNode := func(ctx context.Context, node *redis.Client) error {
+		for _, pattern := range config.Patterns {
+			nodeStats, err := expireOrphanedKeys(ctx, node, pattern, config)
+			mu.Lock()
+			stats.Scanned += nodeStats.Scanned
+			stats.Expired += nodeStats.Expired
+			mu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch client := r.client.(type) {
+	case *redis.ClusterClient:
+		return stats, client.ForEachMaster(r.ctx, scanNode)
+	case *redis.Client:
+		return stats, scanNode(r.ctx, client)
+	default:
+		return stats, errors.New("cleanup needs a single-node, failover or cluster client")
+	}
+}
+
+func expireOrphanedKeys(ctx context.Context, node *redis.Client, pattern string, config CleanupConfig) (CleanupStats, error) {
+	var (
+		stats  CleanupStats
+		cursor uint64
+	)
+	dryRun := "0"
+	if config.DryRun {
+		dryRun = "1"
+	}
+	if err := expireIfPersistentScript.Load(ctx, node).Err(); err != nil {
+		return stats, err
+	}
+	for {
+		keys, next, err := node.Scan(ctx, cursor, pattern, config.ScanCount).Result()
+		if err != nil {
+			return stats, err
+		}
+		stats.Scanned += len(keys)
+		expired, err := expireIfPersistent(ctx, node, keys, config.Expiry, dryRun)
+		stats.Expired += expired
+		if err != nil {
+			return stats, err
+		}
+		if next == 0 {
+			return stats, nil
+		}
+		cursor = next
+	}
+}
+
+// expireIfPersistent runs the script once per key, so every call stays in one slot on Redis
+// Cluster, and pipelines the calls so a batch costs one round trip
+func expireIfPersistent(ctx context.Context, node *redis.Client, keys []string, expiry time.Duration, dryRun string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	cmds, err := node.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			expireIfPersistentScript.EvalSha(ctx, pipe, []string{key}, expiry.Milliseconds(), dryRun)
+		}
+		return nil
+	})
+	expired := 0
+	for _, cmd := range cmds {
+		if result, err := cmd.(*redis.Cmd).Int(); err == nil {
+			expired += result
+		}
+	}
+	return expired, err
+}
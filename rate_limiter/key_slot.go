@@ -0,0 +1,41 @@
+package rate_limiter
+
+import "strings"
+
+const CLUSTER_SLOT_COUNT = 16384
+
+// KeySlot returns the Redis Cluster hash slot of key, honouring {hash tags}
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % CLUSTER_SLOT_COUNT
+}
+
+// SameSlot reports whether every key hashes to the same cluster slot
+func SameSlot(keys []string) bool {
+	for _, key := range keys[min(1, len(keys)):] {
+		if KeySlot(key) != KeySlot(keys[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// crc16 implements CRC16-CCITT (XMODEM), the checksum Redis Cluster uses for key slots
+func crc16(key string) uint16 {
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
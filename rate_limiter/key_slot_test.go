@@ -0,0 +1,35 @@
+package rate_limiter_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("KeySlot", func() {
+	It("should match the slots Redis Cluster assigns", func() {
+		Expect(rate_limiter.KeySlot("foo")).To(Equal(12182))
+		Expect(rate_limiter.KeySlot("123456789")).To(Equal(12739))
+	})
+
+	It("should only hash the contents of a hash tag", func() {
+		Expect(rate_limiter.KeySlot("rate_limit:{user1000}:count")).To(Equal(rate_limiter.KeySlot("user1000")))
+		Expect(rate_limiter.SameSlot([]string{"{user1000}.following", "{user1000}.followers"})).To(BeTrue())
+		Expect(rate_limiter.SameSlot([]string{"foo{}{bar}", "foo{}{bar}:other"})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Options.Key", func() {
+	It("should keep the plain layout by default", func() {
+		Expect(rate_limiter.NewOptions().Key("client")).To(Equal("rate_limit:client"))
+	})
+
+	It("should hash-tag the client id so every key of a client shares a slot", func() {
+		options := rate_limiter.NewOptions(rate_limiter.WithHashTaggedKeys(), rate_limiter.WithKeyPrefix("rl:"))
+		key := options.Key("client")
+
+		Expect(key).To(Equal("rl:{client}"))
+		Expect(rate_limiter.SameSlot([]string{key + ":count", key + ":lastRefill"})).To(BeTrue())
+	})
+})
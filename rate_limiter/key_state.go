@@ -0,0 +1,33 @@
+package rate_limiter
+
+import (
+	"context"
+	"time"
+)
+
+// KeyState is a read-only view of one client's quota
+type KeyState struct {
+	Algorithm Algorithm
+	Key       string
+	Limit     int64
+	Remaining int64
+	// ResetAfter is how long until the client has its full quota back if it sends no further
+	// requests, zero when it already has it or the backend cannot tell
+	ResetAfter time.Duration
+}
+
+// InspectorInterface is implemented by limiters whose per-client state can be read without
+// consuming quota, and cleared, e.g. when support unblocks a customer
+type InspectorInterface interface {
+	Peek(ctx context.Context, clientId string) (KeyState, error)
+	Reset(ctx context.Context, clientId string) error
+}
+
+// PTTL returns how long key has left to live when client can tell, zero otherwise
+func PTTL(client RedisClientInterface, key string) (time.Duration, error) {
+	ttlClient, ok := client.(TTLClientInterface)
+	if !ok {
+		return 0, nil
+	}
+	return ttlClient.PTTL(key)
+}
@@ -0,0 +1,22 @@
+package rate_limiter
+
+// LeaseGrant is quota a limiter handed out ahead of the requests that spend it
+type LeaseGrant struct {
+	// Decision describes the limit the units were taken from. Its Charge tells the limiter which
+	// window the units count against when they are returned.
+	Decision
+	// Units is how many units were granted, zero when the client is out of quota
+	Units int
+}
+
+// LeasableInterface is implemented by limiters that can hand out several units of quota at once,
+// for the caller to spend locally instead of asking the backend on every request
+type LeasableInterface interface {
+	RateLimiterInterface
+	// Lease consumes up to n units of quota for clientId. Units consumed before an error are still
+	// counted in the grant.
+	Lease(clientId string, n int) (LeaseGrant, error)
+	// ReturnLease gives back n units of grant that were never spent, to the window they were taken
+	// from. Once that window has ended they are dropped rather than handed to the next one.
+	ReturnLease(grant LeaseGrant, n int) error
+}
@@ -0,0 +1,609 @@
+package rate_limiter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const MEMORY_CLIENT_SWEEP_INTERVAL = 1024
+
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+type memoryEntry struct {
+	value       string
+	hash        map[string]string
+	fieldExpiry map[string]time.Time
+	expiresAt   time.Time
+}
+
+func (e *memoryEntry) isHash() bool {
+	return e.hash != nil
+}
+
+// MemoryClient is an in-process implementation of RedisClientInterface. It keeps the same key
+// layout and expiry semantics as Redis, so every limiter runs unchanged on a single instance,
+// in tests and in simulations driven by a ManualClock.
+type MemoryClient struct {
+	clock ClockInterface
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	writes  int
+	// leases holds the expiry of every lease by key and lease id
+	leases map[string]map[string]time.Time
+	// subscribers holds the live handlers of every channel by subscription
+	subscribers map[string]map[*memorySubscription]struct{}
+}
+
+type memorySubscription struct {
+	handle func(message string)
+}
+
+func NewMemoryClient(clock ClockInterface) *MemoryClient {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &MemoryClient{
+		clock:   clock,
+		entries: make(map[string]*memoryEntry),
+		leases:  make(map[string]map[string]time.Time),
+
+		subscribers: make(map[string]map[*memorySubscription]struct{}),
+	}
+}
+
+// lookup returns the live entry for key, dropping it and its expired hash fields first. Callers hold mu.
+func (m *MemoryClient) lookup(key string, now time.Time) *memoryEntry {
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+		delete(m.entries, key)
+		return nil
+	}
+	if entry.isHash() {
+		for field, expiresAt := range entry.fieldExpiry {
+			if !now.Before(expiresAt) {
+				delete(entry.hash, field)
+				delete(entry.fieldExpiry, field)
+			}
+		}
+		if len(entry.hash) == 0 {
+			delete(m.entries, key)
+			return nil
+		}
+	}
+	return entry
+}
+
+func (m *MemoryClient) stringEntry(key string, now time.Time, create bool) (*memoryEntry, error) {
+	entry := m.lookup(key, now)
+	if entry != nil && entry.isHash() {
+		return nil, ErrWrongType
+	}
+	if entry == nil && create {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+		m.sweep(now)
+	}
+	return entry, nil
+}
+
+func (m *MemoryClient) hashEntry(key string, now time.Time, create bool) (*memoryEntry, error) {
+	entry := m.lookup(key, now)
+	if entry != nil && !entry.isHash() {
+		return nil, ErrWrongType
+	}
+	if entry == nil && create {
+		entry = &memoryEntry{hash: make(map[string]string), fieldExpiry: make(map[string]time.Time)}
+		m.entries[key] = entry
+		m.sweep(now)
+	}
+	return entry, nil
+}
+
+// sweep periodically drops expired keys and leases that are never read again. Callers hold mu.
+func (m *MemoryClient) sweep(now time.Time) {
+	m.writes++
+	if m.writes%MEMORY_CLIENT_SWEEP_INTERVAL != 0 {
+		return
+	}
+	for key := range m.entries {
+		m.lookup(key, now)
+	}
+	for key := range m.leases {
+		m.liveLeases(key, now)
+	}
+}
+
+// liveLeases returns the unexpired leases of key, dropping the rest and the key once it has none.
+// Callers hold mu.
+func (m *MemoryClient) liveLeases(key string, now time.Time) map[string]time.Time {
+	leases := m.leases[key]
+	for id, expiresAt := range leases {
+		if !now.Before(expiresAt) {
+			delete(leases, id)
+		}
+	}
+	if len(leases) == 0 {
+		delete(m.leases, key)
+		return nil
+	}
+	return leases
+}
+
+func (m *MemoryClient) incrBy(key string, increment int64, now time.Time) (int64, error) {
+	entry, err := m.stringEntry(key, now, true)
+	if err != nil {
+		return 0, err
+	}
+	current := int64(0)
+	if entry.value != "" {
+		if current, err = strconv.ParseInt(entry.value, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	current += increment
+	entry.value = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+// expiryAllowed applies the NX/XX/GT/LT rules to an existing expiry, where zero means none
+func expiryAllowed(current time.Time, next time.Time, expiryMode ExpiryMode) (bool, error) {
+	switch expiryMode {
+	case EXPIRY_MODE_DEFAULT:
+		return true, nil
+	case EXPIRY_MODE_NX:
+		return current.IsZero(), nil
+	case EXPIRY_MODE_XX:
+		return !current.IsZero(), nil
+	case EXPIRY_MODE_GT:
+		return !current.IsZero() && next.After(current), nil
+	case EXPIRY_MODE_LT:
+		return current.IsZero() || next.Before(current), nil
+	default:
+		return false, errors.New("INVALID EXPIRY MODE")
+	}
+}
+
+func (m *MemoryClient) expire(entry *memoryEntry, duration time.Duration, expiryMode ExpiryMode, now time.Time) error {
+	next := now.Add(duration)
+	allowed, err := expiryAllowed(entry.expiresAt, next, expiryMode)
+	if err != nil || !allowed {
+		return err
+	}
+	entry.expiresAt = next
+	return nil
+}
+
+func (m *MemoryClient) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, err := m.stringEntry(key, m.clock.Now(), false)
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return entry.value, nil
+}
+
+func (m *MemoryClient) Set(key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	delete(m.entries, key)
+	entry, _ := m.stringEntry(key, now, true)
+	entry.value = value
+	return nil
+}
+
+func (m *MemoryClient) Incr(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.incrBy(key, 1, m.clock.Now())
+}
+
+func (m *MemoryClient) Decr(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.incrBy(key, -1, m.clock.Now())
+}
+
+func (m *MemoryClient) Expire(key string, duration time.Duration, expiryMode ExpiryMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	entry := m.lookup(key, now)
+	if entry == nil {
+		_, err := expiryAllowed(time.Time{}, now, expiryMode)
+		return err
+	}
+	return m.expire(entry, duration, expiryMode, now)
+}
+
+func (m *MemoryClient) IncrWithExpiry(key string, duration time.Duration, expiryMode ExpiryMode) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if _, err := expiryAllowed(time.Time{}, now, expiryMode); err != nil {
+		return 0, err
+	}
+	result, err := m.incrBy(key, 1, now)
+	if err != nil {
+		return 0, err
+	}
+	return result, m.expire(m.entries[key], duration, expiryMode, now)
+}
+
+func (m *MemoryClient) GetCountAndLastRefill(keyCount, keyLastRefill string) (int64, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lastRefill, tokenCount, err := m.getCountAndLastRefill(keyCount, keyLastRefill, m.clock.Now())
+	return lastRefill, tokenCount, err
+}
+
+func (m *MemoryClient) getCountAndLastRefill(keyCount, keyLastRefill string, now time.Time) (int64, int, error) {
+	var lastRefill int64
+	var tokenCount int
+	entry, err := m.stringEntry(keyLastRefill, now, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if entry != nil {
+		lastRefill, _ = strconv.ParseInt(entry.value, 10, 64)
+	}
+	entry, err = m.stringEntry(keyCount, now, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if entry != nil {
+		tokenCount, _ = strconv.Atoi(entry.value)
+	}
+	return lastRefill, tokenCount, nil
+}
+
+func (m *MemoryClient) SetCountAndLastRefill(keyCount, keyLastRefill string, tokenCount int, currentTime int64) error {
+	return m.SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill, tokenCount, currentTime, 0)
+}
+
+func (m *MemoryClient) SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setCountAndLastRefill(keyCount, keyLastRefill, tokenCount, currentTime, expiry, m.clock.Now())
+}
+
+func (m *MemoryClient) setCountAndLastRefill(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration, now time.Time) error {
+	for key, value := range map[string]string{
+		keyLastRefill: strconv.FormatInt(currentTime, 10),
+		keyCount:      strconv.Itoa(tokenCount),
+	} {
+		delete(m.entries, key)
+		entry, _ := m.stringEntry(key, now, true)
+		entry.value = value
+		if expiry > 0 {
+			entry.expiresAt = now.Add(expiry)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryClient) TakeTokens(keyCount, keyLastRefill string, bucketCapacity int, refillRate float64, currentTime int64, expiry time.Duration, requested int) (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+
+	lastRefill, tokenCount, err := m.getCountAndLastRefill(keyCount, keyLastRefill, now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tokenCount, lastRefill = RefillTokens(tokenCount, lastRefill, bucketCapacity, refillRate, currentTime)
+	taken := min(requested, max(tokenCount, 0))
+	tokenCount -= taken
+
+	return taken, tokenCount, m.setCountAndLastRefill(keyCount, keyLastRefill, tokenCount, lastRefill, expiry, now)
+}
+
+func (m *MemoryClient) HGetAll(key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, err := m.hashEntry(key, m.clock.Now(), false)
+	result := make(map[string]string)
+	if err != nil || entry == nil {
+		return result, err
+	}
+	for field, value := range entry.hash {
+		result[field] = value
+	}
+	return result, nil
+}
+
+func (m *MemoryClient) HIncrByWithExpiry(key string, value string, increment int64, duration time.Duration, expiryMode ExpiryMode) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if _, err := expiryAllowed(time.Time{}, now, expiryMode); err != nil {
+		return 0, err
+	}
+
+	entry, err := m.hashEntry(key, now, true)
+	if err != nil {
+		return 0, err
+	}
+	result, err := hIncrBy(entry, value, increment)
+	if err != nil {
+		return 0, err
+	}
+
+	next := now.Add(duration)
+	if allowed, _ := expiryAllowed(entry.fieldExpiry[value], next, expiryMode); allowed {
+		entry.fieldExpiry[value] = next
+	}
+	return result, nil
+}
+
+func hIncrBy(entry *memoryEntry, field string, increment int64) (int64, error) {
+	current := int64(0)
+	if value, ok := entry.hash[field]; ok {
+		var err error
+		if current, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	current += increment
+	entry.hash[field] = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+func (m *MemoryClient) HLen(key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, err := m.hashEntry(key, m.clock.Now(), false)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return int64(len(entry.hash)), nil
+}
+
+func (m *MemoryClient) HSetWithExpiry(key string, value string, duration time.Duration, expiryMode ExpiryMode) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if _, err := expiryAllowed(time.Time{}, now, expiryMode); err != nil {
+		return 0, err
+	}
+
+	entry, err := m.hashEntry(key, now, true)
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	if _, ok := entry.hash[value]; !ok {
+		added = 1
+	}
+	entry.hash[value] = "1"
+	delete(entry.fieldExpiry, value)
+	return added, m.expire(entry, duration, expiryMode, now)
+}
+
+func (m *MemoryClient) HSetIfLenBelow(key string, value string, limit int64, duration time.Duration, expiryMode ExpiryMode) (bool, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if _, err := expiryAllowed(time.Time{}, now, expiryMode); err != nil {
+		return false, 0, err
+	}
+
+	entry, err := m.hashEntry(key, now, true)
+	if err != nil {
+		return false, 0, err
+	}
+	length := int64(len(entry.hash))
+	if length >= limit {
+		return false, length, nil
+	}
+	if _, ok := entry.hash[value]; !ok {
+		length++
+	}
+	entry.hash[value] = "1"
+	delete(entry.fieldExpiry, value)
+	return true, length, m.expire(entry, duration, expiryMode, now)
+}
+
+func (m *MemoryClient) IncrByIfExists(key string, increment int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	entry, err := m.stringEntry(key, now, false)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	return m.incrBy(key, increment, now)
+}
+
+func (m *MemoryClient) PTTL(key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	entry := m.lookup(key, now)
+	if entry == nil || entry.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return entry.expiresAt.Sub(now), nil
+}
+
+func (m *MemoryClient) IncrWithExpiryAt(key string, duration time.Duration, expiryMode ExpiryMode) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if _, err := expiryAllowed(time.Time{}, now, expiryMode); err != nil {
+		return 0, time.Time{}, err
+	}
+	result, err := m.incrBy(key, 1, now)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	entry := m.entries[key]
+	if err := m.expire(entry, duration, expiryMode, now); err != nil {
+		return 0, time.Time{}, err
+	}
+	return result, entry.expiresAt, nil
+}
+
+func (m *MemoryClient) PExpireTime(key string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.lookup(key, m.clock.Now())
+	if entry == nil {
+		return time.Time{}, nil
+	}
+	return entry.expiresAt, nil
+}
+
+func (m *MemoryClient) HIncrByIfExists(key string, field string, increment int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, err := m.hashEntry(key, m.clock.Now(), false)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	if _, ok := entry.hash[field]; !ok {
+		return 0, nil
+	}
+	return hIncrBy(entry, field, increment)
+}
+
+func (m *MemoryClient) Del(keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	var deleted int64
+	for _, key := range keys {
+		if m.lookup(key, now) != nil {
+			delete(m.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// DBSize counts the stored keys, concurrency slot keys included. Like DBSIZE in Redis it also
+// counts keys that have expired but have not been dropped yet.
+func (m *MemoryClient) DBSize() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.entries) + len(m.leases))
+}
+
+func (m *MemoryClient) HDel(key string, fields ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, err := m.hashEntry(key, m.clock.Now(), false)
+	if err != nil || entry == nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, field := range fields {
+		if _, ok := entry.hash[field]; ok {
+			delete(entry.hash, field)
+			delete(entry.fieldExpiry, field)
+			deleted++
+		}
+	}
+	if len(entry.hash) == 0 {
+		delete(m.entries, key)
+	}
+	return deleted, nil
+}
+
+// MGet returns an empty string for every key that is missing or not a string, like MGET does
+func (m *MemoryClient) MGet(keys ...string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		if entry, err := m.stringEntry(key, now, false); err == nil && entry != nil {
+			values[i] = entry.value
+		}
+	}
+	return values, nil
+}
+
+// DetectCapabilities reports that the MemoryClient supports everything a Redis server might
+func (m *MemoryClient) DetectCapabilities() (Capabilities, error) {
+	return Capabilities{Server: "memory", ExpireOptions: true, HashFieldExpiry: true}, nil
+}
+
+func (m *MemoryClient) AcquireSlot(key string, leaseId string, limit int64, expiresAt time.Time, now time.Time) (bool, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leases := m.liveLeases(key, now)
+	inFlight := int64(len(leases))
+	if inFlight >= limit {
+		return false, inFlight, nil
+	}
+	if leases == nil {
+		leases = map[string]time.Time{leaseId: expiresAt}
+		m.leases[key] = leases
+		m.sweep(now)
+		return true, 1, nil
+	}
+	leases[leaseId] = expiresAt
+	return true, inFlight + 1, nil
+}
+
+func (m *MemoryClient) ReleaseSlot(key string, leaseId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leases[key], leaseId)
+	if len(m.leases[key]) == 0 {
+		delete(m.leases, key)
+	}
+	return nil
+}
+
+// ServerTime returns the time of the MemoryClient's clock, which is the server clock of an in-process store
+func (m *MemoryClient) ServerTime() (time.Time, error) {
+	return m.clock.Now(), nil
+}
+
+// Publish calls the handler of every live subscription to channel before returning
+func (m *MemoryClient) Publish(channel string, message string) error {
+	m.mu.Lock()
+	handlers := make([]func(string), 0, len(m.subscribers[channel]))
+	for subscription := range m.subscribers[channel] {
+		handlers = append(handlers, subscription.handle)
+	}
+	m.mu.Unlock()
+	for _, handle := range handlers {
+		handle(message)
+	}
+	return nil
+}
+
+// Subscribe registers handle for channel until ctx is done
+func (m *MemoryClient) Subscribe(ctx context.Context, channel string, handle func(message string)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	subscription := &memorySubscription{handle: handle}
+	m.mu.Lock()
+	if m.subscribers[channel] == nil {
+		m.subscribers[channel] = make(map[*memorySubscription]struct{})
+	}
+	m.subscribers[channel][subscription] = struct{}{}
+	m.mu.Unlock()
+	context.AfterFunc(ctx, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subscribers[channel], subscription)
+		if len(m.subscribers[channel]) == 0 {
+			delete(m.subscribers, channel)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,30 @@
+package rate_limiter_test
+
+import (
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("MemoryClient", func() {
+	It("should sweep expired concurrency slots of keys that are never used again", func() {
+		clock := rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		client := rate_limiter.NewMemoryClient(clock)
+		for i := range rate_limiter.MEMORY_CLIENT_SWEEP_INTERVAL - 1 {
+			acquired, _, err := client.AcquireSlot("slots:"+strconv.Itoa(i), "lease", 1, clock.Now().Add(time.Second), clock.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		}
+		Expect(client.DBSize()).To(Equal(int64(rate_limiter.MEMORY_CLIENT_SWEEP_INTERVAL - 1)))
+
+		clock.Advance(2 * time.Second)
+		_, _, err := client.AcquireSlot("slots:last", "lease", 1, clock.Now().Add(time.Second), clock.Now())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(client.DBSize()).To(Equal(int64(1)))
+	})
+})
@@ -0,0 +1,22 @@
+package rate_limiter
+
+// MetricsRecorderInterface receives counter increments from rate limiters and their wrappers,
+// so callers can forward them to Prometheus, StatsD or any other backend
+type MetricsRecorderInterface interface {
+	IncCounter(name string, labels map[string]string)
+}
+
+// DecisionLabels returns the labels that identify decision in a metric
+func DecisionLabels(decision Decision) map[string]string {
+	labels := map[string]string{
+		"algorithm": string(decision.Algorithm),
+		"allowed":   "false",
+	}
+	if decision.Allowed {
+		labels["allowed"] = "true"
+	}
+	if decision.Err != nil {
+		labels["error"] = "true"
+	}
+	return labels
+}
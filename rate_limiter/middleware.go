@@ -0,0 +1,61 @@
+package rate_limiter
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrServerError is reported by Middleware for responses with a 5xx status
+var ErrServerError = errors.New("handler responded with a server error")
+
+// ReporterInterface is implemented by limiters that learn from how allowed requests went, such as
+// the adaptive concurrency limiter, which also holds a slot for each allowed request until it is
+// reported. Every allowed decision must be followed by exactly one Report.
+type ReporterInterface interface {
+	Report(clientId string, latency time.Duration, err error)
+}
+
+// Middleware limits the requests next serves per key, answering 429 Too Many Requests to requests
+// the limiter denies. When the limiter implements ReporterInterface, each allowed request is
+// reported with its latency once next returns, with ErrServerError for responses with a 5xx status.
+func Middleware(limiter RateLimiterInterface, key func(*http.Request) string, next http.Handler) http.Handler {
+	reporter, reports := limiter.(ReporterInterface)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientId := key(r)
+		if !limiter.LimitRequests(clientId) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		if !reports {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			var err error
+			if recorder.status >= http.StatusInternalServerError {
+				err = ErrServerError
+			}
+			reporter.Report(clientId, time.Since(start), err)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// statusRecorder remembers the status a handler responded with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,69 @@
+package rate_limiter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+)
+
+// reportingLimiter allows every request and records what it is told about them
+type reportingLimiter struct {
+	reports []error
+}
+
+func (r *reportingLimiter) LimitRequests(clientId string) bool {
+	return true
+}
+
+func (r *reportingLimiter) Report(clientId string, latency time.Duration, err error) {
+	r.reports = append(r.reports, err)
+}
+
+var _ = Describe("Middleware", func() {
+	var status int
+
+	serve := func(handler http.Handler) int {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Tenant", "tenant")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	tenant := func(r *http.Request) string { return r.Header.Get("X-Tenant") }
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+
+	BeforeEach(func() {
+		status = http.StatusOK
+	})
+
+	It("should answer 429 to requests the limiter denies", func() {
+		limiter := mocks.NewMockRateLimiter()
+		limiter.DecideFunc = func(clientId string) rate_limiter.Decision {
+			return rate_limiter.Decision{Key: clientId, Allowed: len(limiter.Calls) <= 1}
+		}
+		handler := rate_limiter.Middleware(limiter, tenant, next)
+
+		Expect(serve(handler)).To(Equal(http.StatusOK))
+		Expect(serve(handler)).To(Equal(http.StatusTooManyRequests))
+		Expect(limiter.Calls).To(Equal([]string{"tenant", "tenant"}))
+	})
+
+	It("should report every allowed request to limiters that take reports", func() {
+		limiter := &reportingLimiter{}
+		handler := rate_limiter.Middleware(limiter, tenant, next)
+
+		Expect(serve(handler)).To(Equal(http.StatusOK))
+		status = http.StatusBadGateway
+		Expect(serve(handler)).To(Equal(http.StatusBadGateway))
+
+		Expect(limiter.reports).To(Equal([]error{nil, rate_limiter.ErrServerError}))
+	})
+})
@@ -0,0 +1,306 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var (
+	ErrInjectedFault          = errors.New("injected fault")
+	ErrInjectedOutage         = errors.New("injected outage")
+	ErrInjectedPartialFailure = errors.New("injected partial pipeline failure")
+	// ErrInjectedTimeout wraps context.DeadlineExceeded, like a timed out go-redis call
+	ErrInjectedTimeout = fmt.Errorf("injected timeout: %w", context.DeadlineExceeded)
+)
+
+// Outage is a window of time during which every call fails with ErrInjectedOutage
+type Outage struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (o Outage) contains(now time.Time) bool {
+	return !now.Before(o.Start) && now.Before(o.End)
+}
+
+// FaultConfig describes the faults a FaultInjectingClient injects. Rates are probabilities between
+// 0 and 1, drawn from a generator seeded with Seed so that runs are reproducible.
+type FaultConfig struct {
+	Seed int64
+	// Latency is added to every call, plus a uniformly random amount up to LatencyJitter
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// ErrorRate fails calls with Err, ErrInjectedFault when nil, before they reach the wrapped client
+	ErrorRate float64
+	Err       error
+	// TimeoutRate fails calls with ErrInjectedTimeout after waiting Timeout
+	TimeoutRate float64
+	Timeout     time.Duration
+	// PartialFailureRate fails the MULTI/EXEC-based methods after their write has landed: IncrWithExpiry,
+	// SetCountAndLastRefill, HIncrByWithExpiry and HSetWithExpiry apply in full, expiry included, and
+	// lose the reply, as when a connection drops before EXEC answers.
+	PartialFailureRate float64
+	// Outages are checked against Clock, the system clock when nil
+	Outages []Outage
+	Clock   rate_limiter.ClockInterface
+	// Sleep waits out latency and timeouts, time.Sleep when nil. Pass a ManualClock's Advance to
+	// keep tests on virtual time.
+	Sleep func(time.Duration)
+}
+
+// FaultStats counts the calls a FaultInjectingClient has seen and the faults it has injected
+type FaultStats struct {
+	Calls           int
+	Errors          int
+	Timeouts        int
+	PartialFailures int
+	OutageErrors    int
+}
+
+// FaultInjectingClient wraps any RedisClientInterface and injects latency, errors, timeouts,
+// partial pipeline failures and outages. It only exposes RedisClientInterface, so limiters fall
+// back from their atomic fast paths to calls that all pass through the injected faults.
+type FaultInjectingClient struct {
+	client rate_limiter.RedisClientInterface
+	config FaultConfig
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	stats FaultStats
+}
+
+// NewFaultInjectingClient creates a new fault-injecting wrapper around client
+func NewFaultInjectingClient(client rate_limiter.RedisClientInterface, config FaultConfig) *FaultInjectingClient {
+	if config.Err == nil {
+		config.Err = ErrInjectedFault
+	}
+	if config.Clock == nil {
+		config.Clock = rate_limiter.SystemClock{}
+	}
+	if config.Sleep == nil {
+		config.Sleep = time.Sleep
+	}
+	return &FaultInjectingClient{
+		client: client,
+		config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Stats returns the counts so far
+func (f *FaultInjectingClient) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// roll reports whether an event with probability rate happens
+func (f *FaultInjectingClient) roll(rate float64) bool {
+	return rate > 0 && f.rng.Float64() < rate
+}
+
+// inject waits out the latency and returns the fault to fail the call with, if any
+func (f *FaultInjectingClient) inject() error {
+	f.mu.Lock()
+	f.stats.Calls++
+	latency := f.config.Latency
+	if f.config.LatencyJitter > 0 {
+		latency += time.Duration(f.rng.Int63n(int64(f.config.LatencyJitter) + 1))
+	}
+	timedOut := f.roll(f.config.TimeoutRate)
+	failed := !timedOut && f.roll(f.config.ErrorRate)
+	f.mu.Unlock()
+
+	if latency > 0 {
+		f.config.Sleep(latency)
+	}
+
+	now := f.config.Clock.Now()
+	for _, outage := range f.config.Outages {
+		if outage.contains(now) {
+			f.count(func(stats *FaultStats) { stats.OutageErrors++ })
+			return ErrInjectedOutage
+		}
+	}
+	if timedOut {
+		f.config.Sleep(f.config.Timeout)
+		f.count(func(stats *FaultStats) { stats.Timeouts++ })
+		return ErrInjectedTimeout
+	}
+	if failed {
+		f.count(func(stats *FaultStats) { stats.Errors++ })
+		return f.config.Err
+	}
+	return nil
+}
+
+// partialFailure reports whether a MULTI/EXEC-based call should fail after its write
+func (f *FaultInjectingClient) partialFailure() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.roll(f.config.PartialFailureRate) {
+		return false
+	}
+	f.stats.PartialFailures++
+	return true
+}
+
+func (f *FaultInjectingClient) count(update func(stats *FaultStats)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	update(&f.stats)
+}
+
+func (f *FaultInjectingClient) Get(key string) (string, error) {
+	if err := f.inject(); err != nil {
+		return "", err
+	}
+	return f.client.Get(key)
+}
+
+func (f *FaultInjectingClient) Set(key string, value string) error {
+	if err := f.inject(); err != nil {
+		return err
+	}
+	return f.client.Set(key, value)
+}
+
+func (f *FaultInjectingClient) Incr(key string) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.Incr(key)
+}
+
+func (f *FaultInjectingClient) Decr(key string) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.Decr(key)
+}
+
+func (f *FaultInjectingClient) Expire(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) error {
+	if err := f.inject(); err != nil {
+		return err
+	}
+	return f.client.Expire(key, duration, expiryMode)
+}
+
+func (f *FaultInjectingClient) IncrWithExpiry(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	count, err := f.client.IncrWithExpiry(key, duration, expiryMode)
+	if err == nil && f.partialFailure() {
+		return 0, ErrInjectedPartialFailure
+	}
+	return count, err
+}
+
+func (f *FaultInjectingClient) GetCountAndLastRefill(keyCount, keyLastRefill string) (int64, int, error) {
+	if err := f.inject(); err != nil {
+		return 0, 0, err
+	}
+	return f.client.GetCountAndLastRefill(keyCount, keyLastRefill)
+}
+
+func (f *FaultInjectingClient) SetCountAndLastRefill(keyCount, keyLastRefill string, tokenCount int, currentTime int64) error {
+	if err := f.inject(); err != nil {
+		return err
+	}
+	if err := f.client.SetCountAndLastRefill(keyCount, keyLastRefill, tokenCount, currentTime); err != nil {
+		return err
+	}
+	if f.partialFailure() {
+		return ErrInjectedPartialFailure
+	}
+	return nil
+}
+
+// SetCountAndLastRefillWithExpiry drops the expiry when the wrapped client cannot set one
+func (f *FaultInjectingClient) SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration) error {
+	expiringClient, ok := f.client.(rate_limiter.ExpiringTokenBucketClientInterface)
+	if !ok {
+		return f.SetCountAndLastRefill(keyCount, keyLastRefill, tokenCount, currentTime)
+	}
+	if err := f.inject(); err != nil {
+		return err
+	}
+	if err := expiringClient.SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill, tokenCount, currentTime, expiry); err != nil {
+		return err
+	}
+	if f.partialFailure() {
+		return ErrInjectedPartialFailure
+	}
+	return nil
+}
+
+func (f *FaultInjectingClient) HGetAll(key string) (map[string]string, error) {
+	if err := f.inject(); err != nil {
+		return nil, err
+	}
+	return f.client.HGetAll(key)
+}
+
+func (f *FaultInjectingClient) HIncrByWithExpiry(key string, value string, increment int64, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	result, err := f.client.HIncrByWithExpiry(key, value, increment, duration, expiryMode)
+	if err == nil && f.partialFailure() {
+		return 0, ErrInjectedPartialFailure
+	}
+	return result, err
+}
+
+func (f *FaultInjectingClient) HLen(key string) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.HLen(key)
+}
+
+func (f *FaultInjectingClient) HSetWithExpiry(key string, value string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	result, err := f.client.HSetWithExpiry(key, value, duration, expiryMode)
+	if err == nil && f.partialFailure() {
+		return 0, ErrInjectedPartialFailure
+	}
+	return result, err
+}
+
+func (f *FaultInjectingClient) IncrByIfExists(key string, increment int64) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.IncrByIfExists(key, increment)
+}
+
+func (f *FaultInjectingClient) HIncrByIfExists(key string, field string, increment int64) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.HIncrByIfExists(key, field, increment)
+}
+
+func (f *FaultInjectingClient) HDel(key string, fields ...string) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.HDel(key, fields...)
+}
+
+func (f *FaultInjectingClient) Del(keys ...string) (int64, error) {
+	if err := f.inject(); err != nil {
+		return 0, err
+	}
+	return f.client.Del(keys...)
+}
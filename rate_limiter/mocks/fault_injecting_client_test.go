@@ -0,0 +1,115 @@
+package mocks_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+)
+
+var _ = Describe("FaultInjectingClient", func() {
+	var (
+		clock  *rate_limiter.ManualClock
+		memory *rate_limiter.MemoryClient
+	)
+
+	BeforeEach(func() {
+		clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		memory = rate_limiter.NewMemoryClient(clock)
+	})
+
+	failures := func(client *mocks.FaultInjectingClient, calls int) []bool {
+		var failed []bool
+		for range calls {
+			_, err := client.Incr("key")
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+
+	It("should pass calls through when no faults are configured", func() {
+		client := mocks.NewFaultInjectingClient(memory, mocks.FaultConfig{})
+		Expect(client.Set("key", "value")).To(Succeed())
+		Expect(client.Get("key")).To(Equal("value"))
+		Expect(client.Stats()).To(Equal(mocks.FaultStats{Calls: 2}))
+	})
+
+	It("should inject the same errors for the same seed", func() {
+		config := mocks.FaultConfig{Seed: 7, ErrorRate: 0.5}
+		first := failures(mocks.NewFaultInjectingClient(memory, config), 50)
+		second := failures(mocks.NewFaultInjectingClient(memory, config), 50)
+
+		Expect(second).To(Equal(first))
+		Expect(first).To(ContainElement(true))
+		Expect(first).To(ContainElement(false))
+	})
+
+	It("should fail with the configured error", func() {
+		injected := errors.New("connection reset")
+		client := mocks.NewFaultInjectingClient(memory, mocks.FaultConfig{ErrorRate: 1, Err: injected})
+
+		_, err := client.Get("key")
+		Expect(err).To(MatchError(injected))
+		Expect(client.Stats().Errors).To(Equal(1))
+	})
+
+	It("should wait out latency and timeouts on the configured sleep", func() {
+		client := mocks.NewFaultInjectingClient(memory, mocks.FaultConfig{
+			Latency:     10 * time.Millisecond,
+			TimeoutRate: 1,
+			Timeout:     time.Second,
+			Sleep:       clock.Advance,
+		})
+		start := clock.Now()
+
+		_, err := client.Get("key")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(clock.Now().Sub(start)).To(Equal(time.Second + 10*time.Millisecond))
+		Expect(client.Stats().Timeouts).To(Equal(1))
+	})
+
+	It("should fail every call during an outage window", func() {
+		client := mocks.NewFaultInjectingClient(memory, mocks.FaultConfig{
+			Outages: []mocks.Outage{{Start: clock.Now().Add(time.Minute), End: clock.Now().Add(2 * time.Minute)}},
+			Clock:   clock,
+		})
+
+		Expect(client.Set("key", "value")).To(Succeed())
+		clock.Advance(time.Minute)
+		_, err := client.Get("key")
+		Expect(err).To(MatchError(mocks.ErrInjectedOutage))
+		clock.Advance(time.Minute)
+		Expect(client.Get("key")).To(Equal("value"))
+		Expect(client.Stats().OutageErrors).To(Equal(1))
+	})
+
+	It("should apply the increment with its expiry on a partial pipeline failure", func() {
+		client := mocks.NewFaultInjectingClient(memory, mocks.FaultConfig{PartialFailureRate: 1})
+
+		_, err := client.IncrWithExpiry("key", time.Second, rate_limiter.EXPIRY_MODE_NX)
+		Expect(err).To(MatchError(mocks.ErrInjectedPartialFailure))
+		Expect(client.Stats().PartialFailures).To(Equal(1))
+		Expect(memory.Get("key")).To(Equal("1"))
+
+		clock.Advance(time.Second)
+		Expect(memory.Get("key")).To(BeEmpty())
+	})
+
+	It("should write the count and last refill before failing a token bucket write", func() {
+		client := mocks.NewFaultInjectingClient(memory, mocks.FaultConfig{PartialFailureRate: 1})
+
+		err := client.SetCountAndLastRefillWithExpiry("key:count", "key:lastRefill", 7, 1_700_000_000, time.Minute)
+		Expect(err).To(MatchError(mocks.ErrInjectedPartialFailure))
+		Expect(client.Stats().PartialFailures).To(Equal(1))
+		Expect(memory.Get("key:count")).To(Equal("7"))
+		Expect(memory.Get("key:lastRefill")).To(Equal("1700000000"))
+
+		clock.Advance(time.Minute)
+		Expect(memory.Get("key:count")).To(BeEmpty())
+	})
+})
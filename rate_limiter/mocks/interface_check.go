@@ -6,3 +6,11 @@ import (
 
 // This is just a compile-time check to ensure MockRedisClient implements RedisClientInterface
 var _ rate_limiter.RedisClientInterface = (*MockRedisClient)(nil)
+var _ rate_limiter.ScriptRunnerInterface = (*MockRedisClient)(nil)
+var _ rate_limiter.ServerTimeInterface = (*MockRedisClient)(nil)
+var _ rate_limiter.ExpiringTokenBucketClientInterface = (*MockRedisClient)(nil)
+var _ rate_limiter.DeciderInterface = (*MockRateLimiter)(nil)
+var _ rate_limiter.RollbackInterface = (*MockRateLimiter)(nil)
+var _ rate_limiter.MetricsRecorderInterface = (*MockMetricsRecorder)(nil)
+var _ rate_limiter.RedisClientInterface = (*FaultInjectingClient)(nil)
+var _ rate_limiter.ExpiringTokenBucketClientInterface = (*FaultInjectingClient)(nil)
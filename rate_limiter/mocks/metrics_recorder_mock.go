@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"sync"
+)
+
+// MockMetricsRecorder implements the MetricsRecorderInterface and keeps every increment for assertions
+type MockMetricsRecorder struct {
+	mu       sync.Mutex
+	Counters map[string][]map[string]string
+}
+
+// NewMockMetricsRecorder creates a new mock metrics recorder
+func NewMockMetricsRecorder() *MockMetricsRecorder {
+	return &MockMetricsRecorder{Counters: make(map[string][]map[string]string)}
+}
+
+func (m *MockMetricsRecorder) IncCounter(name string, labels map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Counters[name] = append(m.Counters[name], labels)
+}
+
+// Count returns how many times name was incremented
+func (m *MockMetricsRecorder) Count(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Counters[name])
+}
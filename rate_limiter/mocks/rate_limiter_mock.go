@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"sync"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+// MockRateLimiter implements the DeciderInterface and RollbackInterface for testing wrappers around rate limiters
+type MockRateLimiter struct {
+	DecideFunc   func(clientId string) rate_limiter.Decision
+	RollbackFunc func(decision rate_limiter.Decision) error
+	Calls        []string
+	Rollbacks    []rate_limiter.Decision
+	mu           sync.Mutex
+}
+
+// NewMockRateLimiter creates a new mock rate limiter
+func NewMockRateLimiter() *MockRateLimiter {
+	return &MockRateLimiter{}
+}
+
+func (m *MockRateLimiter) LimitRequests(clientId string) bool {
+	return m.Decide(clientId).Allowed
+}
+
+func (m *MockRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	m.mu.Lock()
+	m.Calls = append(m.Calls, clientId)
+	m.mu.Unlock()
+
+	if m.DecideFunc != nil {
+		return m.DecideFunc(clientId)
+	}
+	return rate_limiter.Decision{Key: clientId}
+}
+
+func (m *MockRateLimiter) Rollback(decision rate_limiter.Decision) error {
+	m.mu.Lock()
+	m.Rollbacks = append(m.Rollbacks, decision)
+	m.mu.Unlock()
+
+	if m.RollbackFunc != nil {
+		return m.RollbackFunc(decision)
+	}
+	return nil
+}
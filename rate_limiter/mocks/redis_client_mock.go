@@ -11,16 +11,24 @@ import (
 type MockRedisClient struct {
 	GetCountAndLastRefillFunc func(keyCount, keyLastRefill string) (int64, int, error)
 	SetCountAndLastRefillFunc func(keyCount, keyLastRefill string, tokenCount int, currentTime int64) error
-	GetFunc                   func(key string) (string, error)
-	SetFunc                   func(key string, value string) error
-	IncrFunc                  func(key string) (int64, error)
-	DecrFunc                  func(key string) (int64, error)
-	ExpireFunc                func(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) error
-	IncrWithExpiryFunc        func(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error)
-	HGetAllFunc               func(key string) (map[string]string, error)
-	HIncrByWithExpiryFunc     func(key string, value string, increment int64, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error)
-	HLenFunc                  func(key string) (int64, error)
-	HSetWithExpiryFunc        func(key string, value string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error)
+	// SetCountAndLastRefillWithExpiryFunc falls back to SetCountAndLastRefillFunc when nil
+	SetCountAndLastRefillWithExpiryFunc func(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration) error
+	GetFunc                             func(key string) (string, error)
+	SetFunc                             func(key string, value string) error
+	IncrFunc                            func(key string) (int64, error)
+	DecrFunc                            func(key string) (int64, error)
+	ExpireFunc                          func(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) error
+	IncrWithExpiryFunc                  func(key string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error)
+	HGetAllFunc                         func(key string) (map[string]string, error)
+	HIncrByWithExpiryFunc               func(key string, value string, increment int64, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error)
+	HLenFunc                            func(key string) (int64, error)
+	HSetWithExpiryFunc                  func(key string, value string, duration time.Duration, expiryMode rate_limiter.ExpiryMode) (int64, error)
+	IncrByIfExistsFunc                  func(key string, increment int64) (int64, error)
+	HIncrByIfExistsFunc                 func(key string, field string, increment int64) (int64, error)
+	HDelFunc                            func(key string, fields ...string) (int64, error)
+	DelFunc                             func(keys ...string) (int64, error)
+	EvalFunc                            func(script string, keys []string, args ...interface{}) (interface{}, error)
+	ServerTimeFunc                      func() (time.Time, error)
 }
 
 // NewMockRedisClient creates a new mock Redis client
@@ -44,6 +52,14 @@ func (m *MockRedisClient) SetCountAndLastRefill(keyCount, keyLastRefill string,
 	return errors.New("SetCountAndLastRefill not implemented")
 }
 
+// SetCountAndLastRefillWithExpiry overrides the RedisClient method for testing
+func (m *MockRedisClient) SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration) error {
+	if m.SetCountAndLastRefillWithExpiryFunc != nil {
+		return m.SetCountAndLastRefillWithExpiryFunc(keyCount, keyLastRefill, tokenCount, currentTime, expiry)
+	}
+	return m.SetCountAndLastRefill(keyCount, keyLastRefill, tokenCount, currentTime)
+}
+
 // Get overrides the RedisClient method for testing
 func (m *MockRedisClient) Get(key string) (string, error) {
 	if m.GetFunc != nil {
@@ -118,3 +134,45 @@ func (m *MockRedisClient) HSetWithExpiry(key string, value string, duration time
 	}
 	return 0, errors.New("HSetWithExpiry not implemented")
 }
+
+func (m *MockRedisClient) IncrByIfExists(key string, increment int64) (int64, error) {
+	if m.IncrByIfExistsFunc != nil {
+		return m.IncrByIfExistsFunc(key, increment)
+	}
+	return 0, errors.New("IncrByIfExists not implemented")
+}
+
+func (m *MockRedisClient) HIncrByIfExists(key string, field string, increment int64) (int64, error) {
+	if m.HIncrByIfExistsFunc != nil {
+		return m.HIncrByIfExistsFunc(key, field, increment)
+	}
+	return 0, errors.New("HIncrByIfExists not implemented")
+}
+
+func (m *MockRedisClient) HDel(key string, fields ...string) (int64, error) {
+	if m.HDelFunc != nil {
+		return m.HDelFunc(key, fields...)
+	}
+	return 0, errors.New("HDel not implemented")
+}
+
+func (m *MockRedisClient) Del(keys ...string) (int64, error) {
+	if m.DelFunc != nil {
+		return m.DelFunc(keys...)
+	}
+	return 0, errors.New("Del not implemented")
+}
+
+func (m *MockRedisClient) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	if m.EvalFunc != nil {
+		return m.EvalFunc(script, keys, args...)
+	}
+	return nil, errors.New("Eval not implemented")
+}
+
+func (m *MockRedisClient) ServerTime() (time.Time, error) {
+	if m.ServerTimeFunc != nil {
+		return m.ServerTimeFunc()
+	}
+	return time.Time{}, errors.New("ServerTime not implemented")
+}
@@ -0,0 +1,175 @@
+package rate_limiter
+
+import (
+	"cmp"
+	"time"
+)
+
+const DEFAULT_KEY_PREFIX = "rate_limit:"
+
+// KeyLayout selects how the sliding window counter stores its sub-window counts
+type KeyLayout string
+
+const (
+	// KEY_LAYOUT_HASH keeps every sub-window of a client in one hash whose fields expire on their
+	// own, which needs HEXPIRE from Redis 7.4
+	KEY_LAYOUT_HASH KeyLayout = "hash"
+	// KEY_LAYOUT_STRINGS keeps each sub-window in its own string key with a plain EXPIRE, which
+	// works on every Redis version and on Valkey and Dragonfly, at the cost of more keys to read
+	KEY_LAYOUT_STRINGS KeyLayout = "strings"
+)
+
+// WindowAlignment selects where the fixed window counter starts its windows
+type WindowAlignment string
+
+const (
+	// WINDOW_ALIGNMENT_FIRST_REQUEST starts a client's window at its first request, so every client
+	// resets on its own schedule
+	WINDOW_ALIGNMENT_FIRST_REQUEST WindowAlignment = "first_request"
+	// WINDOW_ALIGNMENT_EPOCH starts windows at multiples of the window size since the Unix epoch, so
+	// every client resets at the same moment
+	WINDOW_ALIGNMENT_EPOCH WindowAlignment = "epoch"
+	// WINDOW_ALIGNMENT_STAGGERED shifts epoch-aligned windows by an offset derived from the client's
+	// key, which spreads resets over the window instead of releasing every client at once
+	WINDOW_ALIGNMENT_STAGGERED WindowAlignment = "staggered"
+)
+
+// Options holds the optional settings shared by every rate limiter
+type Options struct {
+	Logger           *DecisionLogger
+	KeyPrefix        string
+	OverrideResolver OverrideResolverInterface
+	Clock            ClockInterface
+	LimitScale       float64
+	HashTagKeys      bool
+	KeyLayout        KeyLayout
+	DeniedCache      *DeniedCache
+	Location         *time.Location
+	WindowAlignment  WindowAlignment
+	// MillisecondRefill makes the token bucket refill every millisecond instead of every second
+	MillisecondRefill bool
+}
+
+// Option configures a rate limiter at construction time
+type Option func(*Options)
+
+// NewOptions applies opts on top of the default Options
+func NewOptions(opts ...Option) Options {
+	options := Options{KeyPrefix: DEFAULT_KEY_PREFIX, Clock: SystemClock{}, KeyLayout: KEY_LAYOUT_HASH, Location: time.UTC, WindowAlignment: WINDOW_ALIGNMENT_FIRST_REQUEST}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithDecisionLogger attaches a DecisionLogger that records the limiter's decisions
+func WithDecisionLogger(logger *DecisionLogger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithKeyPrefix replaces the "rate_limit:" prefix of every Redis key, so that several limiters
+// can track the same client ids without sharing counters
+func WithKeyPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.KeyPrefix = prefix
+	}
+}
+
+// WithHashTaggedKeys wraps the client id of every key in a {hash tag}, e.g. rate_limit:{client}:count,
+// so that all keys of one client land in the same Redis Cluster slot. Multi-key scripts and
+// transactions need this on Redis Cluster.
+func WithHashTaggedKeys() Option {
+	return func(o *Options) {
+		o.HashTagKeys = true
+	}
+}
+
+// WithKeyLayout replaces the hash layout of the sliding window counter, e.g. with
+// KEY_LAYOUT_STRINGS for servers older than Redis 7.4
+func WithKeyLayout(layout KeyLayout) Option {
+	return func(o *Options) {
+		o.KeyLayout = layout
+	}
+}
+
+// WithWindowAlignment replaces the first-request windows of the fixed window counter, e.g. with
+// WINDOW_ALIGNMENT_EPOCH so that every client resets together
+func WithWindowAlignment(alignment WindowAlignment) Option {
+	return func(o *Options) {
+		o.WindowAlignment = alignment
+	}
+}
+
+// WithDeniedCache makes the limiter remember denied clients in cache until their window resets and
+// deny them locally in the meantime. Share one cache between limiters to bound their memory together.
+func WithDeniedCache(cache *DeniedCache) Option {
+	return func(o *Options) {
+		o.DeniedCache = cache
+	}
+}
+
+// WithMillisecondRefill makes the token bucket refill in milliseconds, so that a bucket refilled at
+// several tokens per second, like a bandwidth budget, earns them back within the second. It stores
+// its refill time under its own key, so switch every instance sharing the bucket at once.
+func WithMillisecondRefill() Option {
+	return func(o *Options) {
+		o.MillisecondRefill = true
+	}
+}
+
+// WithLocation sets the time zone calendar quotas reset in for clients without a TimeZone
+// override, UTC by default
+func WithLocation(location *time.Location) Option {
+	return func(o *Options) {
+		o.Location = location
+	}
+}
+
+// WithClock replaces the wall clock the limiter reads the current time from
+func WithClock(clock ClockInterface) Option {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+// WithOverrideResolver makes the limiter look up per-client limits through resolver, wrap it in a
+// CachedOverrideResolver to avoid a lookup per request
+func WithOverrideResolver(resolver OverrideResolverInterface) Option {
+	return func(o *Options) {
+		o.OverrideResolver = resolver
+	}
+}
+
+// WithLimitScale multiplies every limit and refill rate by scale, after overrides. A per-process
+// limiter standing in for a shared one can use 1/expectedInstances to keep the fleet-wide total.
+func WithLimitScale(scale float64) Option {
+	return func(o *Options) {
+		o.LimitScale = scale
+	}
+}
+
+// Key returns the Redis key of clientId, to which limiters append their own suffixes
+func (o Options) Key(clientId string) string {
+	if o.HashTagKeys {
+		return o.KeyPrefix + "{" + clientId + "}"
+	}
+	return o.KeyPrefix + clientId
+}
+
+// Override returns the override for clientId. Lookup failures fall back to the limiter's defaults,
+// so an unavailable override store never blocks traffic on its own.
+func (o Options) Override(clientId string) Override {
+	var override Override
+	if o.OverrideResolver != nil {
+		resolved, found, err := o.OverrideResolver.Resolve(clientId)
+		if err == nil && found {
+			override = resolved
+		}
+	}
+	if o.LimitScale > 0 {
+		override.Scale = o.LimitScale * cmp.Or(override.Scale, 1)
+	}
+	return override
+}
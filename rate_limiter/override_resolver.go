@@ -0,0 +1,211 @@
+package rate_limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// Override replaces a limiter's construction-time limits for one client. Zero fields keep the default.
+type Override struct {
+	// Limit replaces limit, or bucketCapacity for the token bucket
+	Limit int64 `json:"limit,omitempty"`
+	// RefillRate replaces the token bucket's refillRate
+	RefillRate float64 `json:"refill_rate,omitempty"`
+	// Tier names a plan tier whose limits apply when Limit and RefillRate are unset
+	Tier string `json:"tier,omitempty"`
+	// Scale multiplies the limit and refill rate, overridden or not. Zero leaves them unscaled.
+	Scale float64 `json:"scale,omitempty"`
+	// TimeZone is the IANA time zone calendar quotas reset in, e.g. "Europe/Berlin"
+	TimeZone string `json:"time_zone,omitempty"`
+}
+
+// LimitOr returns the overridden limit, or limit when none is set, scaled by Scale but never below 1
+func (o Override) LimitOr(limit int64) int64 {
+	if o.Limit > 0 {
+		limit = o.Limit
+	}
+	if o.Scale > 0 {
+		limit = max(int64(float64(limit)*o.Scale), 1)
+	}
+	return limit
+}
+
+// RefillRateOr returns the overridden refill rate, or refillRate when none is set, scaled by Scale
+func (o Override) RefillRateOr(refillRate float64) float64 {
+	if o.RefillRate > 0 {
+		refillRate = o.RefillRate
+	}
+	if o.Scale > 0 {
+		refillRate *= o.Scale
+	}
+	return refillRate
+}
+
+// OverrideResolverInterface looks up the limits of a single client. found is false when the
+// client has no override and the limiter's defaults apply.
+type OverrideResolverInterface interface {
+	Resolve(clientId string) (override Override, found bool, err error)
+}
+
+// MemoryOverrideResolver keeps overrides in process memory
+type MemoryOverrideResolver struct {
+	mu        sync.RWMutex
+	overrides map[string]Override
+}
+
+func NewMemoryOverrideResolver(overrides map[string]Override) *MemoryOverrideResolver {
+	resolver := &MemoryOverrideResolver{overrides: make(map[string]Override, len(overrides))}
+	for clientId, override := range overrides {
+		resolver.overrides[clientId] = override
+	}
+	return resolver
+}
+
+func (m *MemoryOverrideResolver) Resolve(clientId string) (Override, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	override, found := m.overrides[clientId]
+	return override, found, nil
+}
+
+func (m *MemoryOverrideResolver) Set(clientId string, override Override) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overrides[clientId] = override
+}
+
+func (m *MemoryOverrideResolver) Delete(clientId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.overrides, clientId)
+}
+
+// TieredOverrideResolver expands an override's Tier into the limits of that plan tier
+type TieredOverrideResolver struct {
+	resolver OverrideResolverInterface
+	tiers    map[string]Override
+}
+
+func NewTieredOverrideResolver(resolver OverrideResolverInterface, tiers map[string]Override) *TieredOverrideResolver {
+	return &TieredOverrideResolver{
+		resolver: resolver,
+		tiers:    tiers,
+	}
+}
+
+func (t *TieredOverrideResolver) Resolve(clientId string) (Override, bool, error) {
+	override, found, err := t.resolver.Resolve(clientId)
+	if err != nil || !found || override.Tier == "" {
+		return override, found, err
+	}
+
+	tier, ok := t.tiers[override.Tier]
+	if !ok {
+		return override, found, nil
+	}
+	if override.Limit <= 0 {
+		override.Limit = tier.Limit
+	}
+	if override.RefillRate <= 0 {
+		override.RefillRate = tier.RefillRate
+	}
+	return override, true, nil
+}
+
+const DEFAULT_MAX_CACHED_OVERRIDES = 100000
+
+// DEFAULT_OVERRIDE_ERROR_TTL caps how long CachedOverrideResolver remembers a failed lookup
+const DEFAULT_OVERRIDE_ERROR_TTL = time.Second
+
+type cachedOverride struct {
+	override   Override
+	found      bool
+	err        error
+	expiresAt  time.Time
+	refreshing bool
+}
+
+// CachedOverrideResolver caches another resolver's answers, including misses, for ttl so that
+// limiters do not hit the backing store on every request. Changes take effect once ttl expires,
+// or immediately for keys passed to Invalidate. An expired answer is refetched by one request
+// while the others keep being served the stale one, and is kept if the refetch fails. Failed
+// lookups of clients with nothing cached are remembered for at most DEFAULT_OVERRIDE_ERROR_TTL,
+// so an unavailable store is not hit by every request either.
+type CachedOverrideResolver struct {
+	resolver OverrideResolverInterface
+	ttl      time.Duration
+	errorTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedOverride
+}
+
+func NewCachedOverrideResolver(resolver OverrideResolverInterface, ttl time.Duration) *CachedOverrideResolver {
+	return &CachedOverrideResolver{
+		resolver: resolver,
+		ttl:      ttl,
+		errorTTL: min(ttl, DEFAULT_OVERRIDE_ERROR_TTL),
+		entries:  make(map[string]cachedOverride),
+	}
+}
+
+func (c *CachedOverrideResolver) Resolve(clientId string) (Override, bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[clientId]
+	if ok && (now.Before(entry.expiresAt) || entry.refreshing) {
+		c.mu.Unlock()
+		return entry.override, entry.found, entry.err
+	}
+	if ok {
+		entry.refreshing = true
+		c.entries[clientId] = entry
+	}
+	c.mu.Unlock()
+
+	override, found, err := c.resolver.Resolve(clientId)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && ok && entry.err == nil {
+		entry.refreshing = false
+		entry.expiresAt = now.Add(c.errorTTL)
+		c.entries[clientId] = entry
+		return entry.override, entry.found, nil
+	}
+	if len(c.entries) >= DEFAULT_MAX_CACHED_OVERRIDES {
+		c.evictExpired(now)
+	}
+	if err != nil {
+		c.entries[clientId] = cachedOverride{err: err, expiresAt: now.Add(c.errorTTL)}
+		return override, found, err
+	}
+	c.entries[clientId] = cachedOverride{override: override, found: found, expiresAt: now.Add(c.ttl)}
+	return override, found, nil
+}
+
+// Invalidate drops the cached override of clientId, or every cached override when none is given
+func (c *CachedOverrideResolver) Invalidate(clientIds ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(clientIds) == 0 {
+		clear(c.entries)
+		return
+	}
+	for _, clientId := range clientIds {
+		delete(c.entries, clientId)
+	}
+}
+
+// evictExpired drops expired entries, and everything if the cache is still full of live ones
+func (c *CachedOverrideResolver) evictExpired(now time.Time) {
+	for clientId, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, clientId)
+		}
+	}
+	if len(c.entries) >= DEFAULT_MAX_CACHED_OVERRIDES {
+		clear(c.entries)
+	}
+}
@@ -0,0 +1,265 @@
+package rate_limiter_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+)
+
+type countingResolver struct {
+	resolver rate_limiter.OverrideResolverInterface
+	calls    int
+}
+
+func (c *countingResolver) Resolve(clientId string) (rate_limiter.Override, bool, error) {
+	c.calls++
+	return c.resolver.Resolve(clientId)
+}
+
+// gatedResolver answers with override once release is closed, or with err when it is set
+type gatedResolver struct {
+	override rate_limiter.Override
+	err      error
+	release  chan struct{}
+	calls    atomic.Int32
+}
+
+func (g *gatedResolver) Resolve(clientId string) (rate_limiter.Override, bool, error) {
+	g.calls.Add(1)
+	if g.release != nil {
+		<-g.release
+	}
+	return g.override, g.err == nil, g.err
+}
+
+var _ = Describe("OverrideResolver", func() {
+	Describe("MemoryOverrideResolver", func() {
+		It("should resolve, update and delete overrides", func() {
+			resolver := rate_limiter.NewMemoryOverrideResolver(map[string]rate_limiter.Override{
+				"paid": {Limit: 100},
+			})
+
+			override, found, err := resolver.Resolve("paid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(override.LimitOr(10)).To(Equal(int64(100)))
+
+			resolver.Set("abuser", rate_limiter.Override{Limit: 1})
+			override, found, _ = resolver.Resolve("abuser")
+			Expect(found).To(BeTrue())
+			Expect(override.Limit).To(Equal(int64(1)))
+
+			resolver.Delete("paid")
+			_, found, _ = resolver.Resolve("paid")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Describe("Override scaling", func() {
+		It("should scale overridden and default limits alike, never below one", func() {
+			Expect(rate_limiter.Override{Scale: 0.25}.LimitOr(100)).To(Equal(int64(25)))
+			Expect(rate_limiter.Override{Limit: 40, Scale: 0.25}.LimitOr(100)).To(Equal(int64(10)))
+			Expect(rate_limiter.Override{Scale: 0.01}.LimitOr(10)).To(Equal(int64(1)))
+			Expect(rate_limiter.Override{Scale: 0.5}.RefillRateOr(3)).To(Equal(1.5))
+		})
+
+		It("should apply WithLimitScale on top of resolved overrides", func() {
+			options := rate_limiter.NewOptions(
+				rate_limiter.WithLimitScale(0.5),
+				rate_limiter.WithOverrideResolver(rate_limiter.NewMemoryOverrideResolver(map[string]rate_limiter.Override{
+					"paid": {Limit: 100},
+				})),
+			)
+			Expect(options.Override("paid").LimitOr(10)).To(Equal(int64(50)))
+			Expect(options.Override("free").LimitOr(10)).To(Equal(int64(5)))
+		})
+	})
+
+	Describe("TieredOverrideResolver", func() {
+		It("should expand plan tiers without replacing explicit limits", func() {
+			resolver := rate_limiter.NewTieredOverrideResolver(
+				rate_limiter.NewMemoryOverrideResolver(map[string]rate_limiter.Override{
+					"pro-customer":    {Tier: "pro"},
+					"custom-customer": {Tier: "pro", Limit: 5000},
+				}),
+				map[string]rate_limiter.Override{"pro": {Limit: 1000, RefillRate: 20}},
+			)
+
+			override, _, _ := resolver.Resolve("pro-customer")
+			Expect(override.Limit).To(Equal(int64(1000)))
+			Expect(override.RefillRateOr(1)).To(Equal(20.0))
+
+			override, _, _ = resolver.Resolve("custom-customer")
+			Expect(override.Limit).To(Equal(int64(5000)))
+		})
+	})
+
+	Describe("CachedOverrideResolver", func() {
+		It("should cache answers, including misses, until invalidated", func() {
+			memory := rate_limiter.NewMemoryOverrideResolver(nil)
+			counting := &countingResolver{resolver: memory}
+			resolver := rate_limiter.NewCachedOverrideResolver(counting, time.Hour)
+
+			_, found, _ := resolver.Resolve("client")
+			Expect(found).To(BeFalse())
+
+			memory.Set("client", rate_limiter.Override{Limit: 50})
+			_, found, _ = resolver.Resolve("client")
+			Expect(found).To(BeFalse())
+			Expect(counting.calls).To(Equal(1))
+
+			resolver.Invalidate("client")
+			override, found, _ := resolver.Resolve("client")
+			Expect(found).To(BeTrue())
+			Expect(override.Limit).To(Equal(int64(50)))
+			Expect(counting.calls).To(Equal(2))
+		})
+
+		It("should expire cached answers after the ttl", func() {
+			counting := &countingResolver{resolver: rate_limiter.NewMemoryOverrideResolver(nil)}
+			resolver := rate_limiter.NewCachedOverrideResolver(counting, time.Millisecond)
+
+			_, _, _ = resolver.Resolve("client")
+			time.Sleep(5 * time.Millisecond)
+			_, _, _ = resolver.Resolve("client")
+
+			Expect(counting.calls).To(Equal(2))
+		})
+
+		It("should serve the stale answer while one request refetches it", func() {
+			backend := &gatedResolver{override: rate_limiter.Override{Limit: 50}}
+			resolver := rate_limiter.NewCachedOverrideResolver(backend, time.Millisecond)
+			_, _, _ = resolver.Resolve("client")
+			time.Sleep(5 * time.Millisecond)
+
+			backend.override = rate_limiter.Override{Limit: 100}
+			backend.release = make(chan struct{})
+			refetched := make(chan rate_limiter.Override)
+			go func() {
+				override, _, _ := resolver.Resolve("client")
+				refetched <- override
+			}()
+			Eventually(backend.calls.Load).Should(Equal(int32(2)))
+
+			override, found, err := resolver.Resolve("client")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(override.Limit).To(Equal(int64(50)))
+			Expect(backend.calls.Load()).To(Equal(int32(2)))
+
+			close(backend.release)
+			Eventually(refetched).Should(Receive(Equal(rate_limiter.Override{Limit: 100})))
+			override, _, _ = resolver.Resolve("client")
+			Expect(override.Limit).To(Equal(int64(100)))
+		})
+
+		It("should keep the stale answer when the refetch fails", func() {
+			backend := &gatedResolver{override: rate_limiter.Override{Limit: 50}}
+			resolver := rate_limiter.NewCachedOverrideResolver(backend, time.Millisecond)
+			_, _, _ = resolver.Resolve("client")
+			time.Sleep(5 * time.Millisecond)
+
+			backend.err = errors.New("store unavailable")
+			override, found, err := resolver.Resolve("client")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(override.Limit).To(Equal(int64(50)))
+		})
+
+		It("should remember failed lookups briefly", func() {
+			backend := &gatedResolver{err: errors.New("store unavailable")}
+			resolver := rate_limiter.NewCachedOverrideResolver(backend, time.Hour)
+
+			_, _, err := resolver.Resolve("client")
+			Expect(err).To(HaveOccurred())
+			_, _, err = resolver.Resolve("client")
+			Expect(err).To(HaveOccurred())
+			Expect(backend.calls.Load()).To(Equal(int32(1)))
+		})
+	})
+
+	Describe("FileOverrideResolver", func() {
+		It("should pick up changes to the file without a restart", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "overrides.json")
+			Expect(os.WriteFile(path, []byte(`{"paid": {"limit": 100}}`), 0o600)).To(Succeed())
+
+			resolver, err := rate_limiter.NewFileOverrideResolverWithInterval(path, time.Millisecond)
+			Expect(err).NotTo(HaveOccurred())
+
+			override, found, err := resolver.Resolve("paid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(override.Limit).To(Equal(int64(100)))
+
+			Expect(os.WriteFile(path, []byte(`{"paid": {"limit": 200, "tier": "pro"}}`), 0o600)).To(Succeed())
+			Expect(os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute))).To(Succeed())
+			time.Sleep(5 * time.Millisecond)
+
+			override, _, _ = resolver.Resolve("paid")
+			Expect(override.Limit).To(Equal(int64(200)))
+			Expect(override.Tier).To(Equal("pro"))
+		})
+
+		It("should not touch the file between checks", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "overrides.json")
+			Expect(os.WriteFile(path, []byte(`{"paid": {"limit": 100}}`), 0o600)).To(Succeed())
+
+			resolver, err := rate_limiter.NewFileOverrideResolverWithInterval(path, time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Remove(path)).To(Succeed())
+
+			override, found, err := resolver.Resolve("paid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(override.Limit).To(Equal(int64(100)))
+		})
+
+		It("should fail to construct from an invalid file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "overrides.json")
+			Expect(os.WriteFile(path, []byte(`not json`), 0o600)).To(Succeed())
+
+			_, err := rate_limiter.NewFileOverrideResolver(path)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("RedisOverrideResolver", func() {
+		It("should read overrides from a hash per client", func() {
+			mockRedisClient := mocks.NewMockRedisClient()
+			mockRedisClient.HGetAllFunc = func(key string) (map[string]string, error) {
+				if key == "rate_limit_override:paid" {
+					return map[string]string{"limit": "100", "refill_rate": "2.5", "tier": "pro"}, nil
+				}
+				return map[string]string{}, nil
+			}
+			resolver := rate_limiter.NewRedisOverrideResolver(mockRedisClient, "")
+
+			override, found, err := resolver.Resolve("paid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(override).To(Equal(rate_limiter.Override{Limit: 100, RefillRate: 2.5, Tier: "pro"}))
+
+			_, found, err = resolver.Resolve("free")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should return Redis errors", func() {
+			mockRedisClient := mocks.NewMockRedisClient()
+			mockRedisClient.HGetAllFunc = func(key string) (map[string]string, error) {
+				return nil, errors.New("redis connection error")
+			}
+
+			_, _, err := rate_limiter.NewRedisOverrideResolver(mockRedisClient, "").Resolve("paid")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
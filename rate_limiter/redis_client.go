@@ -4,16 +4,27 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisClient struct {
-	client *redis.Client
+	client redis.UniversalClient
 	ctx context.Context
+
+	mu           sync.Mutex
+	capabilities *Capabilities
 }
 
+// CAPABILITY_PROBE_KEY is never written, the probes only check whether the server accepts the syntax
+const CAPABILITY_PROBE_KEY = "rate_limit:capability_probe"
+
+// ErrCrossSlot is returned on Redis Cluster for multi-key scripts whose keys live in different slots
+var ErrCrossSlot = errors.New("keys map to different cluster slots, use WithHashTaggedKeys to keep a client's keys together")
+
 type ExpiryMode string
 const (
 	EXPIRY_MODE_DEFAULT ExpiryMode = ""
@@ -23,20 +34,32 @@ const (
 	EXPIRY_MODE_LT ExpiryMode = "LT"
 )
 
-func NewRedisClient(client *redis.Client) *RedisClient {
+// NewRedisClient wraps a single-node, Sentinel (failover) or Cluster client. On Redis Cluster,
+// build limiters with WithHashTaggedKeys so that their multi-key operations stay in one slot.
+func NewRedisClient(client redis.UniversalClient) *RedisClient {
 	return &RedisClient{
 		client: client,
 		ctx: context.Background(),
 	}
 }
 
+// GetCountAndLastRefill reads both keys in one round trip
 func (r *RedisClient) GetCountAndLastRefill(keyCount, keyLastRefill string) (int64, int, error) {
-	lastRefillStr, err := r.client.Get(r.ctx, keyLastRefill).Result()
+	var lastRefillCmd, tokenCountCmd *redis.StringCmd
+	_, err := r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		lastRefillCmd = pipe.Get(r.ctx, keyLastRefill)
+		tokenCountCmd = pipe.Get(r.ctx, keyCount)
+		return nil
+	})
 	if err != nil && err != redis.Nil {
 		return 0, 0, err
 	}
 
-	tokenCountStr, err := r.client.Get(r.ctx, keyCount).Result()
+	lastRefillStr, err := lastRefillCmd.Result()
+	if err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	tokenCountStr, err := tokenCountCmd.Result()
 	if err != nil && err != redis.Nil {
 		return 0, 0, err
 	}
@@ -53,18 +76,29 @@ func (r *RedisClient) GetCountAndLastRefill(keyCount, keyLastRefill string) (int
 	return lastRefill, tokenCount, nil
 }
 
+// SetCountAndLastRefill writes both keys in one transaction, which is only atomic on Redis Cluster
+// when the keys share a slot
 func (r *RedisClient) SetCountAndLastRefill(keyCount, keyLastRefill string, tokenCount int, currentTime int64) error {
-	if err := r.client.Set(r.ctx, keyLastRefill, strconv.FormatInt(currentTime, 10), 0).Err(); err != nil {
-		return err
-	}
-	if err := r.client.Set(r.ctx, keyCount, strconv.Itoa(tokenCount), 0).Err(); err != nil {
-		return err
-	}
-	return nil
+	return r.SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill, tokenCount, currentTime, 0)
+}
+
+// SetCountAndLastRefillWithExpiry writes both keys like SetCountAndLastRefill and expires them after expiry
+func (r *RedisClient) SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(r.ctx, keyLastRefill, strconv.FormatInt(currentTime, 10), expiry)
+		pipe.Set(r.ctx, keyCount, strconv.Itoa(tokenCount), expiry)
+		return nil
+	})
+	return err
 }
 
+// Get returns an empty string for a missing key, like GetCountAndLastRefill does
 func (r *RedisClient) Get(key string) (string, error) {
-	return r.client.Get(r.ctx, key).Result()
+	value, err := r.client.Get(r.ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
 }
 
 func (r *RedisClient) Set(key string, value string) error {
@@ -80,6 +114,14 @@ func (r *RedisClient) Decr(key string) (int64, error) {
 }
 
 func (r *RedisClient) Expire(key string, duration time.Duration, expiryMode ExpiryMode) error {
+	emulate, err := r.emulateExpiryMode(expiryMode)
+	if err != nil {
+		return err
+	}
+	if emulate {
+		return expireScript.Run(r.ctx, r.client, []string{key}, duration.Milliseconds(), string(expiryMode)).Err()
+	}
+
 	switch expiryMode {
 		case EXPIRY_MODE_DEFAULT:
 			return r.client.Expire(r.ctx, key, duration).Err()
@@ -97,9 +139,17 @@ func (r *RedisClient) Expire(key string, duration time.Duration, expiryMode Expi
 }
 
 func (r *RedisClient) IncrWithExpiry(key string, duration time.Duration, expiryMode ExpiryMode) (int64, error) {
+	emulate, err := r.emulateExpiryMode(expiryMode)
+	if err != nil {
+		return 0, err
+	}
+	if emulate {
+		return incrWithExpiryScript.Run(r.ctx, r.client, []string{key}, duration.Milliseconds(), string(expiryMode)).Int64()
+	}
+
 	var incrCmd *redis.IntCmd
 
-	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
 		incrCmd = pipe.Incr(r.ctx, key)
 
 		switch expiryMode {
@@ -135,23 +185,33 @@ func (r *RedisClient) HGetAll(key string) (map[string]string, error) {
 	return r.client.HGetAll(r.ctx, key).Result()
 }
 
+// HIncrByWithExpiry fails with ErrHashFieldExpiryUnsupported on servers without HEXPIRE, rather
+// than leaving fields that never expire
 func (r *RedisClient) HIncrByWithExpiry(key string, value string, increment int64, duration time.Duration, expiryMode ExpiryMode) (int64, error) {
+	capabilities, err := r.detectedCapabilities()
+	if err != nil {
+		return 0, err
+	}
+	if !capabilities.HashFieldExpiry {
+		return 0, ErrHashFieldExpiryUnsupported
+	}
+
 	var incrCmd *redis.IntCmd
 
-	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
 		incrCmd = pipe.HIncrBy(r.ctx, key, value, increment)
 
 		switch expiryMode {
 		case EXPIRY_MODE_DEFAULT:
-			pipe.HExpire(r.ctx, key, duration)
+			pipe.HExpire(r.ctx, key, duration, value)
 		case EXPIRY_MODE_NX:
-			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{NX: true})
+			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{NX: true}, value)
 		case EXPIRY_MODE_XX:
-			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{XX: true})
+			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{XX: true}, value)
 		case EXPIRY_MODE_GT:
-			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{GT: true})
+			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{GT: true}, value)
 		case EXPIRY_MODE_LT:
-			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{LT: true})
+			pipe.HExpireWithArgs(r.ctx, key, duration, redis.HExpireArgs{LT: true}, value)
 		default:
 			return errors.New("INVALID EXPIRY MODE")
 		}
@@ -175,8 +235,16 @@ func (r *RedisClient) HLen(key string) (int64, error) {
 }
 
 func (r *RedisClient) HSetWithExpiry(key string, value string, duration time.Duration, expiryMode ExpiryMode) (int64, error) {
+	emulate, err := r.emulateExpiryMode(expiryMode)
+	if err != nil {
+		return 0, err
+	}
+	if emulate {
+		return hSetWithExpiryScript.Run(r.ctx, r.client, []string{key}, duration.Milliseconds(), string(expiryMode), value).Int64()
+	}
+
 	var setCmd *redis.IntCmd
-	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
 		setCmd = pipe.HSet(r.ctx, key, value, 1)
 
 		switch expiryMode {
@@ -206,4 +274,460 @@ func (r *RedisClient) HSetWithExpiry(key string, value string, duration time.Dur
 	}
 
 	return setResult, nil
-}
\ No newline at end of file
+}
+var incrByIfExistsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+`)
+
+var hIncrByIfExistsScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+`)
+
+func (r *RedisClient) IncrByIfExists(key string, increment int64) (int64, error) {
+	return incrByIfExistsScript.Run(r.ctx, r.client, []string{key}, increment).Int64()
+}
+
+func (r *RedisClient) HIncrByIfExists(key string, field string, increment int64) (int64, error) {
+	return hIncrByIfExistsScript.Run(r.ctx, r.client, []string{key}, field, increment).Int64()
+}
+
+func (r *RedisClient) HDel(key string, fields ...string) (int64, error) {
+	return r.client.HDel(r.ctx, key, fields...).Result()
+}
+
+// Del pipelines one DEL per key rather than sending a single DEL, so the keys need not share a
+// Redis Cluster slot
+func (r *RedisClient) Del(keys ...string) (int64, error) {
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Del(r.ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return deleted, nil
+}
+
+func (r *RedisClient) Eval(script string, keys []string, args ...interface{}) (interface{}, error) {
+	if err := r.checkSlots(keys); err != nil {
+		return nil, err
+	}
+	return redis.NewScript(script).Run(r.ctx, r.client, keys, args...).Result()
+}
+
+var takeTokenScript = redis.NewScript(`
+local bucketCapacity, refillRate, currentTime = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local lastRefill = tonumber(redis.call('GET', KEYS[2]) or '0')
+local tokenCount = tonumber(redis.call('GET', KEYS[1]) or '0')
+
+if lastRefill == 0 then
+	lastRefill = currentTime
+	tokenCount = bucketCapacity
+else
+	local tokensToAdd = math.floor((currentTime - lastRefill) * refillRate)
+	if tokensToAdd > 0 then
+		tokenCount = math.min(bucketCapacity, tokenCount + tokensToAdd)
+		if tokenCount >= bucketCapacity then
+			lastRefill = currentTime
+		else
+			lastRefill = lastRefill + math.floor(tokensToAdd / refillRate)
+		end
+	end
+	tokenCount = math.min(tokenCount, bucketCapacity)
+end
+
+local taken = math.min(tonumber(ARGV[5]), math.max(tokenCount, 0))
+tokenCount = tokenCount - taken
+
+local expiry = tonumber(ARGV[4])
+if expiry > 0 then
+	redis.call('SET', KEYS[2], lastRefill, 'PX', expiry)
+	redis.call('SET', KEYS[1], tokenCount, 'PX', expiry)
+else
+	redis.call('SET', KEYS[2], lastRefill)
+	redis.call('SET', KEYS[1], tokenCount)
+end
+return {taken, tokenCount}
+`)
+
+func (r *RedisClient) TakeTokens(keyCount, keyLastRefill string, bucketCapacity int, refillRate float64, currentTime int64, expiry time.Duration, requested int) (int, int, error) {
+	if err := r.checkSlots([]string{keyCount, keyLastRefill}); err != nil {
+		return 0, 0, err
+	}
+	result, err := takeTokenScript.Run(r.ctx, r.client, []string{keyCount, keyLastRefill}, bucketCapacity, refillRate, currentTime, expiry.Milliseconds(), requested).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(result[0]), int(result[1]), nil
+}
+
+var hSetIfLenBelowScript = redis.NewScript(expireWithModeFunction + `
+local length = redis.call('HLEN', KEYS[1])
+if length >= tonumber(ARGV[2]) then
+	return {0, length}
+end
+redis.call('HSET', KEYS[1], ARGV[1], 1)
+expireWithMode(KEYS[1], ARGV[3], ARGV[4])
+return {1, length + 1}
+`)
+
+func (r *RedisClient) HSetIfLenBelow(key string, value string, limit int64, duration time.Duration, expiryMode ExpiryMode) (bool, int64, error) {
+	var mode string
+	switch expiryMode {
+	case EXPIRY_MODE_DEFAULT:
+	case EXPIRY_MODE_NX, EXPIRY_MODE_XX, EXPIRY_MODE_GT, EXPIRY_MODE_LT:
+		mode = string(expiryMode)
+	default:
+		return false, 0, errors.New("INVALID EXPIRY MODE")
+	}
+
+	result, err := hSetIfLenBelowScript.Run(r.ctx, r.client, []string{key}, value, limit, duration.Milliseconds(), mode).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, result[1], nil
+}
+
+// acquireSlotScript keeps the leases of a key in a sorted set scored by their expiry in ms. The
+// key expires with its last lease, so keys of clients that crashed holding leases go away too.
+var acquireSlotScript = redis.NewScript(`
+local limit, expiresAt, now = tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+local inFlight = redis.call('ZCARD', KEYS[1])
+if inFlight >= limit then
+	return {0, inFlight}
+end
+redis.call('ZADD', KEYS[1], expiresAt, ARGV[1])
+local last = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+-- Relative to the caller's clock, so that a server clock running ahead cannot expire the key early
+redis.call('PEXPIRE', KEYS[1], math.max(tonumber(last[2]) - now, 1))
+return {1, inFlight + 1}
+`)
+
+// AcquireSlot adds a lease to the sorted set at key when fewer than limit leases are live
+func (r *RedisClient) AcquireSlot(key string, leaseId string, limit int64, expiresAt time.Time, now time.Time) (bool, int64, error) {
+	result, err := acquireSlotScript.Run(r.ctx, r.client, []string{key}, leaseId, limit, expiresAt.UnixMilli(), now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, result[1], nil
+}
+
+func (r *RedisClient) ReleaseSlot(key string, leaseId string) error {
+	return r.client.ZRem(r.ctx, key, leaseId).Err()
+}
+
+// checkSlots fails early on Redis Cluster when a script's keys would be rejected with CROSSSLOT
+func (r *RedisClient) checkSlots(keys []string) error {
+	if _, ok := r.client.(*redis.ClusterClient); ok && !SameSlot(keys) {
+		return ErrCrossSlot
+	}
+	return nil
+}
+
+// expireWithModeFunction defines expireWithMode(key, milliseconds, mode), which applies the EXPIRE
+// NX, XX, GT and LT rules itself so that scripts using it also run on servers older than Redis 7.0
+const expireWithModeFunction = `
+local function expireWithMode(key, milliseconds, mode)
+	milliseconds = tonumber(milliseconds)
+	local ttl = redis.call('PTTL', key)
+	if ttl == -2 then
+		return 0
+	end
+	if (mode == 'NX' and ttl ~= -1) or (mode == 'XX' and ttl == -1) or
+		(mode == 'GT' and (ttl == -1 or milliseconds <= ttl)) or
+		(mode == 'LT' and ttl ~= -1 and milliseconds >= ttl) then
+		return 0
+	end
+	return redis.call('PEXPIRE', key, milliseconds)
+end
+`
+
+var expireScript = redis.NewScript(expireWithModeFunction + `
+return expireWithMode(KEYS[1], ARGV[1], ARGV[2])
+`)
+
+var incrWithExpiryScript = redis.NewScript(expireWithModeFunction + `
+local result = redis.call('INCR', KEYS[1])
+expireWithMode(KEYS[1], ARGV[1], ARGV[2])
+return result
+`)
+
+var hSetWithExpiryScript = redis.NewScript(expireWithModeFunction + `
+local result = redis.call('HSET', KEYS[1], ARGV[3], 1)
+expireWithMode(KEYS[1], ARGV[1], ARGV[2])
+return result
+`)
+
+// MGet reads several string keys in one round trip, returning an empty string for each missing key.
+// It pipelines GETs rather than sending MGET, so the keys need not share a Redis Cluster slot.
+func (r *RedisClient) MGet(keys ...string) ([]string, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(r.ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	values := make([]string, len(keys))
+	for i, cmd := range cmds {
+		value, err := cmd.Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// DetectCapabilities asks the server what it supports. The first successful detection is cached
+// and steers every later call, so run it once at startup, e.g. through CheckServer.
+func (r *RedisClient) DetectCapabilities() (Capabilities, error) {
+	return r.detectedCapabilities()
+}
+
+// SetCapabilities pins the capabilities instead of detecting them, for servers that refuse the
+// probes or to exercise the fallbacks in tests
+func (r *RedisClient) SetCapabilities(capabilities Capabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.capabilities = &capabilities
+}
+
+func (r *RedisClient) detectedCapabilities() (Capabilities, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.capabilities != nil {
+		return *r.capabilities, nil
+	}
+
+	var capabilities Capabilities
+	// INFO is often restricted on managed servers, and only names the server anyway
+	if info, err := r.client.Info(r.ctx, "server").Result(); err == nil {
+		capabilities.Server, capabilities.Version = parseServerInfo(info)
+	}
+
+	var err error
+	if capabilities.ExpireOptions, err = r.probe(r.client.ExpireNX(r.ctx, CAPABILITY_PROBE_KEY, time.Second).Err()); err != nil {
+		return Capabilities{}, err
+	}
+	if capabilities.HashFieldExpiry, err = r.probe(r.client.HExpire(r.ctx, CAPABILITY_PROBE_KEY, time.Second, "probe").Err()); err != nil {
+		return Capabilities{}, err
+	}
+
+	r.capabilities = &capabilities
+	return capabilities, nil
+}
+
+// probe reads the outcome of a probe command: an ERR reply (unknown command or syntax error)
+// means the server does not support it, while any other error means it could not be asked
+func (r *RedisClient) probe(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) && strings.HasPrefix(redisErr.Error(), "ERR") {
+		return false, nil
+	}
+	return false, err
+}
+
+// emulateExpiryMode reports whether expiryMode must be applied by a script, because the server
+// predates the EXPIRE options
+func (r *RedisClient) emulateExpiryMode(expiryMode ExpiryMode) (bool, error) {
+	switch expiryMode {
+	case EXPIRY_MODE_DEFAULT:
+		return false, nil
+	case EXPIRY_MODE_NX, EXPIRY_MODE_XX, EXPIRY_MODE_GT, EXPIRY_MODE_LT:
+	default:
+		return false, errors.New("INVALID EXPIRY MODE")
+	}
+	capabilities, err := r.detectedCapabilities()
+	if err != nil {
+		return false, err
+	}
+	return !capabilities.ExpireOptions, nil
+}
+
+func (r *RedisClient) PTTL(key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(r.ctx, key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+// IncrWithExpiryAt counts like IncrWithExpiry and reads the counter's expiry in the same
+// transaction. Servers before Redis 7.0 lack PEXPIRETIME and take a second round trip.
+func (r *RedisClient) IncrWithExpiryAt(key string, duration time.Duration, expiryMode ExpiryMode) (int64, time.Time, error) {
+	capabilities, err := r.detectedCapabilities()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if !capabilities.ExpireOptions {
+		count, err := r.IncrWithExpiry(key, duration, expiryMode)
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		expiresAt, err := r.PExpireTime(key)
+		return count, expiresAt, err
+	}
+
+	var incrCmd *redis.IntCmd
+	var expireTimeCmd *redis.DurationCmd
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		incrCmd = pipe.Incr(r.ctx, key)
+		switch expiryMode {
+		case EXPIRY_MODE_NX:
+			pipe.ExpireNX(r.ctx, key, duration)
+		case EXPIRY_MODE_XX:
+			pipe.ExpireXX(r.ctx, key, duration)
+		case EXPIRY_MODE_GT:
+			pipe.ExpireGT(r.ctx, key, duration)
+		case EXPIRY_MODE_LT:
+			pipe.ExpireLT(r.ctx, key, duration)
+		case EXPIRY_MODE_DEFAULT:
+			pipe.Expire(r.ctx, key, duration)
+		default:
+			return errors.New("INVALID EXPIRY MODE")
+		}
+		expireTimeCmd = pipe.PExpireTime(r.ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return incrCmd.Val(), expireTime(expireTimeCmd.Val()), nil
+}
+
+// PExpireTime reads when key expires with PEXPIRETIME, or from TIME and PTTL on servers before
+// Redis 7.0, which may be a millisecond off
+func (r *RedisClient) PExpireTime(key string) (time.Time, error) {
+	capabilities, err := r.detectedCapabilities()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if capabilities.ExpireOptions {
+		expiresAt, err := r.client.PExpireTime(r.ctx, key).Result()
+		return expireTime(expiresAt), err
+	}
+
+	var timeCmd *redis.TimeCmd
+	var ttlCmd *redis.DurationCmd
+	_, err = r.client.Pipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		timeCmd = pipe.Time(r.ctx)
+		ttlCmd = pipe.PTTL(r.ctx, key)
+		return nil
+	})
+	if err != nil || ttlCmd.Val() < 0 {
+		return time.Time{}, err
+	}
+	return timeCmd.Val().Add(ttlCmd.Val()), nil
+}
+
+// expireTime turns a PEXPIRETIME reply, the time since the epoch, into a time, zero for missing
+// keys and keys without expiry
+func expireTime(sinceEpoch time.Duration) time.Time {
+	if sinceEpoch < 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(sinceEpoch.Milliseconds())
+}
+
+// ServerTime reads the server clock with TIME
+func (r *RedisClient) ServerTime() (time.Time, error) {
+	return r.client.Time(r.ctx).Result()
+}
+
+// Publish sends message to every subscriber of channel. On Redis Cluster it reaches subscribers on every node.
+func (r *RedisClient) Publish(channel string, message string) error {
+	return r.client.Publish(r.ctx, channel, message).Err()
+}
+
+// Subscribe waits for the server to confirm the subscription, then hands messages to handle on
+// their own goroutine until ctx is done. go-redis reconnects a dropped subscription on its own.
+func (r *RedisClient) Subscribe(ctx context.Context, channel string, handle func(message string)) error {
+	pubsub := r.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+	messages := pubsub.Channel()
+	go func() {
+		defer pubsub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				handle(message.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+// EvalBatch pipelines calls with EVALSHA, and sends the scripts the server has not cached yet
+// again with EVAL in a second round trip. On Redis Cluster go-redis sends each node its share.
+func (r *RedisClient) EvalBatch(ctx context.Context, calls []ScriptCall) []ScriptResult {
+	results := make([]ScriptResult, len(calls))
+	pending := make([]int, 0, len(calls))
+	for i, call := range calls {
+		if err := r.checkSlots(call.Keys); err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	var missing []int
+	r.pipelineScripts(ctx, calls, pending, results, func(pipe redis.Pipeliner, call ScriptCall) *redis.Cmd {
+		return redis.NewScript(call.Script).EvalSha(ctx, pipe, call.Keys, call.Args...)
+	})
+	for _, i := range pending {
+		if results[i].Err != nil && redis.HasErrorPrefix(results[i].Err, "NOSCRIPT") {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		r.pipelineScripts(ctx, calls, missing, results, func(pipe redis.Pipeliner, call ScriptCall) *redis.Cmd {
+			return pipe.Eval(ctx, call.Script, call.Keys, call.Args...)
+		})
+	}
+	return results
+}
+
+// pipelineScripts sends the calls at indexes in one pipeline and records their results
+func (r *RedisClient) pipelineScripts(ctx context.Context, calls []ScriptCall, indexes []int, results []ScriptResult, send func(pipe redis.Pipeliner, call ScriptCall) *redis.Cmd) {
+	cmds := make([]*redis.Cmd, len(indexes))
+	_, _ = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for j, i := range indexes {
+			cmds[j] = send(pipe, calls[i])
+		}
+		return nil
+	})
+	for j, i := range indexes {
+		results[i].Value, results[i].Err = cmds[j].Result()
+	}
+}
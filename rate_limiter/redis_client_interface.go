@@ -1,6 +1,9 @@
 package rate_limiter
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // RedisClientInterface defines the interface for Redis client operations needed by rate limiters
 type RedisClientInterface interface {
@@ -16,4 +19,79 @@ type RedisClientInterface interface {
 	HIncrByWithExpiry(key string, value string, increment int64, duration time.Duration, expiryMode ExpiryMode) (int64, error)
 	HLen(key string) (int64, error)
 	HSetWithExpiry(key string, value string, duration time.Duration, expiryMode ExpiryMode) (int64, error)
+	// IncrByIfExists and HIncrByIfExists leave missing keys and fields untouched and return 0,
+	// so a rollback never resurrects a counter whose window has already expired
+	IncrByIfExists(key string, increment int64) (int64, error)
+	HIncrByIfExists(key string, field string, increment int64) (int64, error)
+	HDel(key string, fields ...string) (int64, error)
+	// Del removes keys of any type and returns how many existed. The keys need not share a slot.
+	Del(keys ...string) (int64, error)
+}
+
+// ScriptRunnerInterface is implemented by clients that can run Lua scripts atomically on the server
+type ScriptRunnerInterface interface {
+	Eval(script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// BatchScriptRunnerInterface is implemented by clients that can pipeline many script evaluations
+// into a single round trip. Each call succeeds or fails on its own.
+type BatchScriptRunnerInterface interface {
+	EvalBatch(ctx context.Context, calls []ScriptCall) []ScriptResult
+}
+
+// TokenBucketClientInterface is implemented by clients that can refill a bucket and take up to
+// requested tokens in one atomic step. The token bucket prefers it over GetCountAndLastRefill and SetCountAndLastRefill,
+// whose read-modify-write lets concurrent requests spend the same token.
+type TokenBucketClientInterface interface {
+	TakeTokens(keyCount, keyLastRefill string, bucketCapacity int, refillRate float64, currentTime int64, expiry time.Duration, requested int) (taken int, tokenCount int, err error)
+}
+
+// ExpiringTokenBucketClientInterface is implemented by clients that can expire the keys of a bucket
+// as they write them, after expiry or never when expiry is zero. The token bucket prefers it over
+// SetCountAndLastRefill, whose keys live until they are deleted.
+type ExpiringTokenBucketClientInterface interface {
+	SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill string, tokenCount int, currentTime int64, expiry time.Duration) error
+}
+
+// SlidingWindowLogClientInterface is implemented by clients that can check the size of a log and
+// append to it in one atomic step. The sliding window log prefers it over HLen and HSetWithExpiry,
+// between which concurrent requests can all pass the same check.
+type SlidingWindowLogClientInterface interface {
+	HSetIfLenBelow(key string, value string, limit int64, duration time.Duration, expiryMode ExpiryMode) (added bool, length int64, err error)
+}
+
+// ConcurrencyClientInterface is implemented by clients that can hand out a bounded number of
+// leases per key. Leases past their expiry no longer hold a slot and are dropped on the next acquire.
+type ConcurrencyClientInterface interface {
+	AcquireSlot(key string, leaseId string, limit int64, expiresAt time.Time, now time.Time) (acquired bool, inFlight int64, err error)
+	ReleaseSlot(key string, leaseId string) error
+}
+
+// TTLClientInterface is implemented by clients that can tell how long a key has left to live, zero
+// when it is missing or never expires. Limiters use it to learn when a window resets.
+type TTLClientInterface interface {
+	PTTL(key string) (time.Duration, error)
+}
+
+// WindowClientInterface is implemented by clients that can tell when a counter expires. Counters
+// written under the same key at different times expire at different times, so the fixed window
+// records the expiry when it charges a request and compares it before refunding, which keeps
+// refunds out of later windows. Both return the zero time for missing keys and keys without expiry.
+type WindowClientInterface interface {
+	IncrWithExpiryAt(key string, duration time.Duration, expiryMode ExpiryMode) (count int64, expiresAt time.Time, err error)
+	PExpireTime(key string) (time.Time, error)
+}
+
+// PubSubClientInterface is implemented by clients that can broadcast messages to every process
+// subscribed to a channel. Subscribe returns once the subscription is live and calls handle for each
+// message until ctx is done. Delivery is at most once: messages sent while a subscriber reconnects are lost.
+type PubSubClientInterface interface {
+	Publish(channel string, message string) error
+	Subscribe(ctx context.Context, channel string, handle func(message string)) error
+}
+
+// MultiGetClientInterface is implemented by clients that can read several keys in one round trip.
+// Limiters that read many keys prefer it over one Get per key.
+type MultiGetClientInterface interface {
+	MGet(keys ...string) ([]string, error)
 }
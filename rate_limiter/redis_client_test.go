@@ -0,0 +1,152 @@
+package rate_limiter_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("RedisClient", func() {
+	var server *miniredis.Miniredis
+
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+	})
+
+	Context("with a single-node client", func() {
+		var client *rate_limiter.RedisClient
+
+		BeforeEach(func() {
+			redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			DeferCleanup(redisClient.Close)
+			client = rate_limiter.NewRedisClient(redisClient)
+		})
+
+		It("should read missing token bucket keys as zero", func() {
+			lastRefill, tokenCount, err := client.GetCountAndLastRefill("missing:count", "missing:lastRefill")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastRefill).To(BeZero())
+			Expect(tokenCount).To(BeZero())
+		})
+
+		It("should write and read back both token bucket keys", func() {
+			Expect(client.SetCountAndLastRefill("client:count", "client:lastRefill", 7, 1_700_000_000)).To(Succeed())
+
+			lastRefill, tokenCount, err := client.GetCountAndLastRefill("client:count", "client:lastRefill")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastRefill).To(Equal(int64(1_700_000_000)))
+			Expect(tokenCount).To(Equal(7))
+		})
+
+		It("should expire token bucket keys written with an expiry", func() {
+			_, _, err := client.TakeTokens("client:count", "client:lastRefill", 10, 1, 1_700_000_000, time.Minute, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(server.TTL("client:count")).To(Equal(time.Minute))
+			Expect(server.TTL("client:lastRefill")).To(Equal(time.Minute))
+
+			Expect(client.SetCountAndLastRefillWithExpiry("client:count", "client:lastRefill", 7, 1_700_000_000, time.Hour)).To(Succeed())
+			Expect(server.TTL("client:count")).To(Equal(time.Hour))
+		})
+
+		It("should hand published messages to subscribers until their context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			messages := make(chan string, 1)
+			Expect(client.Subscribe(ctx, "channel", func(message string) { messages <- message })).To(Succeed())
+
+			Expect(client.Publish("channel", "rate_limit:client")).To(Succeed())
+
+			Eventually(messages).Should(Receive(Equal("rate_limit:client")))
+			cancel()
+			Eventually(func() int { return len(server.PubSubChannels("")) }).Should(BeZero())
+		})
+
+		Describe("ExpireOrphanedKeys", func() {
+			BeforeEach(func() {
+				Expect(server.Set("rate_limit:a:count", "1")).To(Succeed())
+				Expect(server.Set("rate_limit:a:lastRefill", "1700000000")).To(Succeed())
+				Expect(server.Set("rate_limit:b:count", "1")).To(Succeed())
+				server.SetTTL("rate_limit:b:count", time.Minute)
+				Expect(server.Set("other:c:count", "1")).To(Succeed())
+			})
+
+			It("should expire matching keys that have no expiry", func() {
+				stats, err := client.ExpireOrphanedKeys(rate_limiter.CleanupConfig{
+					Patterns: token_bucket_ratelimiter.KeyPatterns("rate_limit:"),
+					Expiry:   time.Hour,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stats).To(Equal(rate_limiter.CleanupStats{Scanned: 3, Expired: 2}))
+				Expect(server.TTL("rate_limit:a:count")).To(Equal(time.Hour))
+				Expect(server.TTL("rate_limit:a:lastRefill")).To(Equal(time.Hour))
+				Expect(server.TTL("rate_limit:b:count")).To(Equal(time.Minute))
+				Expect(server.TTL("other:c:count")).To(BeZero())
+			})
+
+			It("should only count the keys on a dry run", func() {
+				stats, err := client.ExpireOrphanedKeys(rate_limiter.CleanupConfig{
+					Patterns: token_bucket_ratelimiter.KeyPatterns("rate_limit:"),
+					Expiry:   time.Hour,
+					DryRun:   true,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stats.Expired).To(Equal(2))
+				Expect(server.TTL("rate_limit:a:count")).To(BeZero())
+			})
+
+			It("should refuse to run without an expiry", func() {
+				_, err := client.ExpireOrphanedKeys(rate_limiter.CleanupConfig{Patterns: []string{"rate_limit:*"}})
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with a cluster client", func() {
+		var client *rate_limiter.RedisClient
+
+		BeforeEach(func() {
+			clusterClient := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{server.Addr()}})
+			DeferCleanup(clusterClient.Close)
+			client = rate_limiter.NewRedisClient(clusterClient)
+		})
+
+		It("should run token bucket scripts when keys are hash-tagged", func() {
+			limiter := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 2, 1, rate_limiter.WithHashTaggedKeys())
+
+			Expect(limiter.LimitRequests("client")).To(BeTrue())
+			Expect(server.Exists("rate_limit:{client}:count")).To(BeTrue())
+			Expect(server.Exists("rate_limit:{client}:lastRefill")).To(BeTrue())
+		})
+
+		It("should expire orphaned keys on every master", func() {
+			Expect(server.Set("rate_limit:{a}:count", "1")).To(Succeed())
+
+			stats, err := client.ExpireOrphanedKeys(rate_limiter.CleanupConfig{
+				Patterns: token_bucket_ratelimiter.KeyPatterns("rate_limit:"),
+				Expiry:   time.Hour,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stats.Expired).To(Equal(1))
+			Expect(server.TTL("rate_limit:{a}:count")).To(Equal(time.Hour))
+		})
+
+		It("should refuse scripts whose keys span slots", func() {
+			limiter := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 2, 1)
+
+			decision := limiter.Decide("client")
+			Expect(decision.Allowed).To(BeFalse())
+			Expect(decision.Err).To(MatchError(rate_limiter.ErrCrossSlot))
+		})
+	})
+})
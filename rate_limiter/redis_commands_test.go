@@ -0,0 +1,46 @@
+package rate_limiter_test
+
+import (
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("RedisClient commands", func() {
+	var (
+		server *miniredis.Miniredis
+		client *rate_limiter.RedisClient
+	)
+
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+		redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		DeferCleanup(redisClient.Close)
+		client = rate_limiter.NewRedisClient(redisClient)
+	})
+
+	It("should read a missing key as an empty string rather than an error", func() {
+		value, err := client.Get("missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(BeEmpty())
+
+		Expect(client.Set("present", "3")).To(Succeed())
+		Expect(client.Get("present")).To(Equal("3"))
+	})
+
+	It("should expire only the hash field it increments", func() {
+		Expect(client.HIncrByWithExpiry("hash", "old", 1, time.Minute, rate_limiter.EXPIRY_MODE_NX)).To(Equal(int64(1)))
+		Expect(client.HIncrByWithExpiry("hash", "new", 1, time.Second, rate_limiter.EXPIRY_MODE_NX)).To(Equal(int64(1)))
+
+		server.FastForward(2 * time.Second)
+
+		Expect(client.HGetAll("hash")).To(Equal(map[string]string{"old": "1"}))
+	})
+})
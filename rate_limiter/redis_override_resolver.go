@@ -0,0 +1,49 @@
+package rate_limiter
+
+import (
+	"strconv"
+)
+
+const DEFAULT_OVERRIDE_KEY_PREFIX = "rate_limit_override:"
+
+// RedisOverrideResolver reads overrides from one Redis hash per client, with the optional
+// fields "limit", "refill_rate", "tier" and "time_zone":
+//
+//	HSET rate_limit_override:customer-1 limit 1000 refill_rate 20
+type RedisOverrideResolver struct {
+	redisClient RedisClientInterface
+	keyPrefix   string
+}
+
+func NewRedisOverrideResolver(redisClient RedisClientInterface, keyPrefix string) *RedisOverrideResolver {
+	if keyPrefix == "" {
+		keyPrefix = DEFAULT_OVERRIDE_KEY_PREFIX
+	}
+	return &RedisOverrideResolver{
+		redisClient: redisClient,
+		keyPrefix:   keyPrefix,
+	}
+}
+
+func (r *RedisOverrideResolver) Resolve(clientId string) (Override, bool, error) {
+	fields, err := r.redisClient.HGetAll(r.keyPrefix + clientId)
+	if err != nil || len(fields) == 0 {
+		return Override{}, false, err
+	}
+
+	var override Override
+	if limit, ok := fields["limit"]; ok {
+		if override.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil {
+			return Override{}, false, err
+		}
+	}
+	if refillRate, ok := fields["refill_rate"]; ok {
+		if override.RefillRate, err = strconv.ParseFloat(refillRate, 64); err != nil {
+			return Override{}, false, err
+		}
+	}
+	override.Tier = fields["tier"]
+	override.TimeZone = fields["time_zone"]
+
+	return override, true, nil
+}
@@ -0,0 +1,73 @@
+package rate_limiter
+
+import "context"
+
+// ScriptStep describes how a Redis-backed limiter checks and consumes quota for one key,
+// so several limiters can be evaluated together inside a single Lua script
+type ScriptStep struct {
+	Client RedisClientInterface
+	Keys   []string
+	Args   []interface{}
+	// Decision is filled in with everything but the outcome, which the script reports
+	Decision Decision
+	// Denied is set when the limiter knows without Redis that the request is denied, e.g. from its
+	// denied cache, so that callers may skip the step and use Decision as it is
+	Denied bool
+	// Done, when set, receives the decision once its outcome is known, so that the limiter can log
+	// it and remember denials the way its own Decide does
+	Done func(decision Decision)
+}
+
+// CompleteStep hands decision back to the limiter that built step and returns it
+func CompleteStep(step ScriptStep, decision Decision) Decision {
+	if step.Done != nil {
+		step.Done(decision)
+	}
+	return decision
+}
+
+// ScriptableInterface is implemented by limiters whose decision can be expressed as a ScriptStep
+type ScriptableInterface interface {
+	DeciderInterface
+	ScriptStep(clientId string) ScriptStep
+}
+
+// RollbackInterface is implemented by limiters that can give back the quota consumed by an allowed Decision
+type RollbackInterface interface {
+	Rollback(decision Decision) error
+}
+
+// RefundInterface is implemented by limiters that can give back up to n units of the quota charged
+// to key, e.g. when the request failed upstream. The refund only reaches the key's current window
+// or bucket, so it never raises a client above its limit and never frees up a later window.
+type RefundInterface interface {
+	Refund(ctx context.Context, key string, n int) error
+}
+
+// DecisionRefundInterface is implemented by limiters that can give back up to n units of the quota
+// charged by an allowed Decision to the window or refill it charged, and drop the refund once that
+// has passed. Use it when the refund may arrive after the key's window has moved on.
+type DecisionRefundInterface interface {
+	RefundDecision(ctx context.Context, decision Decision, n int) error
+}
+
+// Rollback gives back the quota consumed by decision when limiter supports it
+func Rollback(limiter RateLimiterInterface, decision Decision) error {
+	if rollback, ok := limiter.(RollbackInterface); ok && decision.Allowed {
+		return rollback.Rollback(decision)
+	}
+	return nil
+}
+
+// ScriptCall is one script evaluation in a batch
+type ScriptCall struct {
+	Script string
+	Keys   []string
+	Args   []interface{}
+}
+
+// ScriptResult is the outcome of one ScriptCall
+type ScriptResult struct {
+	Value interface{}
+	Err   error
+}
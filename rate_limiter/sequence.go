@@ -0,0 +1,42 @@
+package rate_limiter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeyFor maps clientId to a member's key through keyFunc, or uses it as-is when keyFunc is nil
+func KeyFor(keyFunc func(clientId string) string, clientId string) string {
+	if keyFunc == nil {
+		return clientId
+	}
+	return keyFunc(clientId)
+}
+
+// EvaluateSequence decides keys[i] with limiters[i] in order until one denies, and then rolls back
+// the quota the earlier ones consumed. It returns the decisions taken, the index of the denying
+// limiter or -1 when all allowed, and the error of RollbackSequence.
+func EvaluateSequence(limiters []RateLimiterInterface, keys []string, name func(i int) string) ([]Decision, int, error) {
+	decisions := make([]Decision, 0, len(limiters))
+	for i, limiter := range limiters {
+		decision := Evaluate(limiter, keys[i])
+		decisions = append(decisions, decision)
+		if !decision.Allowed {
+			return decisions, i, RollbackSequence(limiters, decisions, decision.Err, name)
+		}
+	}
+	return decisions, -1, nil
+}
+
+// RollbackSequence gives back, from the last to the first, the quota consumed by every allowed
+// decisions[i] of limiters[i]. cause is the error of the decision that stopped the sequence; it is
+// joined with every failed rollback, each named by name(i).
+func RollbackSequence(limiters []RateLimiterInterface, decisions []Decision, cause error, name func(i int) string) error {
+	errs := []error{cause}
+	for i := len(decisions) - 1; i >= 0; i-- {
+		if err := Rollback(limiters[i], decisions[i]); err != nil {
+			errs = append(errs, fmt.Errorf("rolling back %s: %w", name(i), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package rate_limiter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("EvaluateSequence", func() {
+	var client *rate_limiter.MemoryClient
+
+	BeforeEach(func() {
+		client = rate_limiter.NewMemoryClient(rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0)))
+	})
+
+	It("should stop at the first denial and roll back the limiters before it", func() {
+		open := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 10, rate_limiter.WithKeyPrefix("open:"))
+		closed := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 0, rate_limiter.WithKeyPrefix("closed:"))
+		never := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 10, rate_limiter.WithKeyPrefix("never:"))
+
+		decisions, deniedBy, err := rate_limiter.EvaluateSequence(
+			[]rate_limiter.RateLimiterInterface{open, closed, never},
+			[]string{"client", "client", "client"},
+			func(i int) string { return "step" },
+		)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deniedBy).To(Equal(1))
+		Expect(decisions).To(HaveLen(2))
+		Expect(client.Get("open:client")).To(Equal("0"))
+		Expect(client.Get("never:client")).To(BeEmpty())
+	})
+
+	It("should allow when every limiter allows", func() {
+		limiter := fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, 10)
+
+		decisions, deniedBy, err := rate_limiter.EvaluateSequence(
+			[]rate_limiter.RateLimiterInterface{limiter, limiter},
+			[]string{"user", "org"},
+			func(i int) string { return "step" },
+		)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deniedBy).To(Equal(-1))
+		Expect(decisions).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,145 @@
+package rate_limiter
+
+import (
+	"sync"
+	"time"
+)
+
+const DEFAULT_SYNC_RETRY_DELAY = time.Second
+
+// ServerTimeInterface is implemented by clients that can read their server's clock
+type ServerTimeInterface interface {
+	ServerTime() (time.Time, error)
+}
+
+// ServerClockConfig tunes a ServerClock
+type ServerClockConfig struct {
+	// SyncInterval is how long an offset measured against the server is reused. Zero asks the
+	// server on every call, which costs a round trip per decision.
+	SyncInterval time.Duration
+	// Local is the clock the offset is applied to, SystemClock when nil
+	Local ClockInterface
+	// RetryDelay is how long a failed sync waits before the server is asked again, the smaller of
+	// DEFAULT_SYNC_RETRY_DELAY and SyncInterval when zero
+	RetryDelay time.Duration
+	// OnError is called when the server cannot be asked. The clock then keeps its last offset,
+	// or falls back to the local clock before the first successful sync.
+	OnError func(err error)
+}
+
+// ServerClock reads the time from the Redis server rather than the local host, so that every
+// instance agrees on window boundaries and refills however far their own clocks have drifted.
+// Between syncs it adds the last measured offset to the local clock.
+type ServerClock struct {
+	client ServerTimeInterface
+	config ServerClockConfig
+
+	mu       sync.Mutex
+	offset   time.Duration
+	syncedAt time.Time
+	synced   bool
+	syncing  bool
+	retryAt  time.Time
+}
+
+func NewServerClock(client ServerTimeInterface, config ServerClockConfig) *ServerClock {
+	if config.Local == nil {
+		config.Local = SystemClock{}
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = min(DEFAULT_SYNC_RETRY_DELAY, config.SyncInterval)
+	}
+	return &ServerClock{
+		client: client,
+		config: config,
+	}
+}
+
+func (s *ServerClock) Now() time.Time {
+	// Asking on every call must not serialize concurrent decisions behind one round trip
+	if s.config.SyncInterval <= 0 {
+		offset, syncedAt, err := s.measure()
+		if err != nil {
+			s.reportError(err)
+			return s.config.Local.Now().Add(s.Offset())
+		}
+		s.mu.Lock()
+		s.store(offset, syncedAt)
+		s.mu.Unlock()
+		return s.config.Local.Now().Add(offset)
+	}
+
+	// One caller syncs at a time, outside mu, while the others keep adding the last offset
+	s.mu.Lock()
+	local := s.config.Local.Now()
+	due := !s.syncing && !local.Before(s.retryAt) && (!s.synced || local.Sub(s.syncedAt) >= s.config.SyncInterval)
+	if due {
+		s.syncing = true
+	}
+	offset := s.offset
+	s.mu.Unlock()
+	if !due {
+		return local.Add(offset)
+	}
+
+	offset, err := s.sync()
+	s.mu.Lock()
+	s.syncing = false
+	s.mu.Unlock()
+	if err != nil {
+		s.reportError(err)
+	}
+	return s.config.Local.Now().Add(offset)
+}
+
+// Sync measures the offset to the server clock now, instead of waiting for the next sync
+func (s *ServerClock) Sync() error {
+	_, err := s.sync()
+	return err
+}
+
+// Offset is how far the server clock was ahead of the local one at the last sync
+func (s *ServerClock) Offset() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// sync measures and stores the offset, returning the offset in use afterwards. A failed sync is
+// retried after RetryDelay rather than on the next call, so that an unreachable server does not
+// cost every decision a round trip.
+func (s *ServerClock) sync() (time.Duration, error) {
+	offset, syncedAt, err := s.measure()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.retryAt = s.config.Local.Now().Add(s.config.RetryDelay)
+		return s.offset, err
+	}
+	s.store(offset, syncedAt)
+	return offset, nil
+}
+
+// measure assumes the server read its clock halfway through the round trip
+func (s *ServerClock) measure() (time.Duration, time.Time, error) {
+	before := s.config.Local.Now()
+	serverNow, err := s.client.ServerTime()
+	after := s.config.Local.Now()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return serverNow.Sub(before.Add(after.Sub(before) / 2)), after, nil
+}
+
+// store records a measured offset. Callers hold mu.
+func (s *ServerClock) store(offset time.Duration, syncedAt time.Time) {
+	s.offset = offset
+	s.syncedAt = syncedAt
+	s.synced = true
+}
+
+func (s *ServerClock) reportError(err error) {
+	if s.config.OnError != nil {
+		s.config.OnError(err)
+	}
+}
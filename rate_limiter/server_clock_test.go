@@ -0,0 +1,137 @@
+package rate_limiter_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+)
+
+var _ = Describe("ServerClock", func() {
+	var (
+		localClock  *rate_limiter.ManualClock
+		serverClock *rate_limiter.ManualClock
+		server      *rate_limiter.MemoryClient
+	)
+
+	BeforeEach(func() {
+		localClock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		// The server runs five seconds ahead of this host
+		serverClock = rate_limiter.NewManualClock(time.Unix(1_700_000_005, 0))
+		server = rate_limiter.NewMemoryClient(serverClock)
+	})
+
+	It("should read the server time on every call without a sync interval", func() {
+		clock := rate_limiter.NewServerClock(server, rate_limiter.ServerClockConfig{Local: localClock})
+
+		Expect(clock.Now()).To(Equal(serverClock.Now()))
+		serverClock.Advance(time.Second)
+		Expect(clock.Now()).To(Equal(serverClock.Now()))
+	})
+
+	It("should reuse the measured offset until the sync interval has passed", func() {
+		clock := rate_limiter.NewServerClock(server, rate_limiter.ServerClockConfig{Local: localClock, SyncInterval: time.Minute})
+		Expect(clock.Now()).To(Equal(time.Unix(1_700_000_005, 0)))
+		Expect(clock.Offset()).To(Equal(5 * time.Second))
+
+		serverClock.Advance(time.Hour)
+		localClock.Advance(30 * time.Second)
+		Expect(clock.Now()).To(Equal(time.Unix(1_700_000_035, 0)))
+
+		localClock.Advance(30 * time.Second)
+		Expect(clock.Now()).To(Equal(serverClock.Now()))
+	})
+
+	It("should keep the last offset and report the error when the server cannot be asked", func() {
+		failing := mocks.NewMockRedisClient()
+		failing.ServerTimeFunc = func() (time.Time, error) { return serverClock.Now(), nil }
+		var reported []error
+		clock := rate_limiter.NewServerClock(failing, rate_limiter.ServerClockConfig{
+			Local:   localClock,
+			OnError: func(err error) { reported = append(reported, err) },
+		})
+		Expect(clock.Sync()).To(Succeed())
+
+		failing.ServerTimeFunc = func() (time.Time, error) { return time.Time{}, errors.New("connection refused") }
+		localClock.Advance(time.Second)
+
+		Expect(clock.Now()).To(Equal(time.Unix(1_700_000_006, 0)))
+		Expect(reported).To(HaveLen(1))
+	})
+
+	It("should wait for the retry delay before asking again after a failed sync", func() {
+		failing := mocks.NewMockRedisClient()
+		calls := 0
+		failing.ServerTimeFunc = func() (time.Time, error) {
+			calls++
+			return time.Time{}, errors.New("connection refused")
+		}
+		clock := rate_limiter.NewServerClock(failing, rate_limiter.ServerClockConfig{
+			Local:        localClock,
+			SyncInterval: time.Minute,
+			RetryDelay:   5 * time.Second,
+		})
+
+		Expect(clock.Now()).To(Equal(localClock.Now()))
+		Expect(clock.Now()).To(Equal(localClock.Now()))
+		Expect(calls).To(Equal(1))
+
+		localClock.Advance(5 * time.Second)
+		clock.Now()
+		Expect(calls).To(Equal(2))
+	})
+
+	It("should keep serving the last offset while another caller syncs", func() {
+		blocking := mocks.NewMockRedisClient()
+		blocking.ServerTimeFunc = func() (time.Time, error) { return serverClock.Now(), nil }
+		clock := rate_limiter.NewServerClock(blocking, rate_limiter.ServerClockConfig{Local: localClock, SyncInterval: time.Minute})
+		Expect(clock.Sync()).To(Succeed())
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		blocking.ServerTimeFunc = func() (time.Time, error) {
+			close(started)
+			<-release
+			return serverClock.Now(), nil
+		}
+		localClock.Advance(time.Minute)
+		serverClock.Advance(time.Minute)
+		synced := make(chan time.Time, 1)
+		go func() { synced <- clock.Now() }()
+		Eventually(started).Should(BeClosed())
+
+		Expect(clock.Now()).To(Equal(time.Unix(1_700_000_065, 0)))
+		close(release)
+		Eventually(synced).Should(Receive(Equal(time.Unix(1_700_000_065, 0))))
+	})
+
+	It("should read TIME from Redis", func() {
+		redisServer := miniredis.NewMiniRedis()
+		Expect(redisServer.Start()).To(Succeed())
+		DeferCleanup(redisServer.Close)
+		redisServer.SetTime(time.Unix(1_700_000_005, 0))
+		redisClient := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+		DeferCleanup(redisClient.Close)
+
+		now, err := rate_limiter.NewRedisClient(redisClient).ServerTime()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(now).To(BeTemporally("==", time.Unix(1_700_000_005, 0)))
+	})
+
+	It("should make instances with skewed clocks agree on sub-windows", func() {
+		skewedClock := rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0).Add(-3 * time.Second))
+		first := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(server, 10, 10, 10,
+			rate_limiter.WithClock(rate_limiter.NewServerClock(server, rate_limiter.ServerClockConfig{Local: localClock})))
+		second := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(server, 10, 10, 10,
+			rate_limiter.WithClock(rate_limiter.NewServerClock(server, rate_limiter.ServerClockConfig{Local: skewedClock})))
+
+		Expect(first.Decide("client").Charge).To(Equal(second.Decide("client").Charge))
+	})
+})
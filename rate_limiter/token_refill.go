@@ -0,0 +1,21 @@
+package rate_limiter
+
+// RefillTokens adds the whole tokens earned since lastRefill, capped at bucketCapacity. It returns
+// the new token count and refill time; the refill time only advances by the time those whole tokens
+// took, so refill rates below one token per second still accumulate across calls.
+func RefillTokens(tokenCount int, lastRefill int64, bucketCapacity int, refillRate float64, currentTime int64) (int, int64) {
+	if lastRefill == 0 {
+		return bucketCapacity, currentTime
+	}
+
+	tokensToAdd := int(float64(currentTime-lastRefill) * refillRate)
+	if tokensToAdd <= 0 {
+		return min(tokenCount, bucketCapacity), lastRefill
+	}
+
+	tokenCount = min(bucketCapacity, tokenCount+tokensToAdd)
+	if tokenCount >= bucketCapacity {
+		return tokenCount, currentTime
+	}
+	return tokenCount, lastRefill + int64(float64(tokensToAdd)/refillRate)
+}
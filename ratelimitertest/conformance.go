@@ -0,0 +1,365 @@
+// Package ratelimitertest provides a Ginkgo conformance suite that runs any limiter against any
+// backend, in the spirit of net/http/httptest. Import it from tests only.
+package ratelimitertest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_log_rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+const (
+	LIMIT  = 5
+	WINDOW = 10 * time.Second
+)
+
+// START_TIME is where every spec's clock starts, on a whole second so refills are predictable
+var START_TIME = time.Unix(1_700_000_000, 0)
+
+// Backend creates a fresh store for every spec
+type Backend struct {
+	Name string
+	// New returns a client whose expiries follow clock, and a function that moves both forward
+	New func(clock *rate_limiter.ManualClock) (client rate_limiter.RedisClientInterface, advance func(time.Duration), cleanup func())
+}
+
+// Limiter builds the limiter under test, allowing limit requests per window
+type Limiter struct {
+	Name string
+	New  func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface
+	// Refills is set for limiters that recover capacity gradually rather than all at once
+	Refills bool
+}
+
+func MemoryBackend() Backend {
+	return Backend{
+		Name: "in-memory",
+		New: func(clock *rate_limiter.ManualClock) (rate_limiter.RedisClientInterface, func(time.Duration), func()) {
+			return rate_limiter.NewMemoryClient(clock), clock.Advance, func() {}
+		},
+	}
+}
+
+func MiniredisBackend() Backend {
+	return Backend{
+		Name: "miniredis",
+		New: func(clock *rate_limiter.ManualClock) (rate_limiter.RedisClientInterface, func(time.Duration), func()) {
+			server := miniredis.NewMiniRedis()
+			Expect(server.Start()).To(Succeed())
+			server.SetTime(clock.Now())
+
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			advance := func(duration time.Duration) {
+				clock.Advance(duration)
+				server.SetTime(clock.Now())
+				server.FastForward(duration)
+			}
+			cleanup := func() {
+				_ = client.Close()
+				server.Close()
+			}
+			return rate_limiter.NewRedisClient(client), advance, cleanup
+		},
+	}
+}
+
+// LegacyMiniredisBackend runs miniredis as a Redis 6.2 server, without the EXPIRE options and
+// HEXPIRE, so the RedisClient falls back to emulating them
+func LegacyMiniredisBackend() Backend {
+	backend := MiniredisBackend()
+	return Backend{
+		Name: "miniredis as Redis 6.2",
+		New: func(clock *rate_limiter.ManualClock) (rate_limiter.RedisClientInterface, func(time.Duration), func()) {
+			client, advance, cleanup := backend.New(clock)
+			client.(*rate_limiter.RedisClient).SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "6.2.0"})
+			return client, advance, cleanup
+		},
+	}
+}
+
+// DefaultLimiters returns the four algorithms shipped with this module, the fixed window counter
+// once more with staggered windows and the sliding window counter once more in its string key layout
+func DefaultLimiters() []Limiter {
+	return []Limiter{
+		{
+			Name: "token bucket",
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, limit, float64(limit)/window.Seconds(), opts...)
+			},
+			Refills: true,
+		},
+		{
+			Name: "fixed window counter",
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, int(window.Seconds()), limit, opts...)
+			},
+		},
+		{
+			Name: "fixed window counter with staggered windows",
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				opts = append(opts, rate_limiter.WithWindowAlignment(rate_limiter.WINDOW_ALIGNMENT_STAGGERED))
+				return fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, int(window.Seconds()), limit, opts...)
+			},
+		},
+		{
+			Name: "sliding window log",
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return sliding_window_log_rate_limiter.NewSlidingWindowLogRateLimiter(client, limit, int64(window.Seconds()), opts...)
+			},
+		},
+		{
+			Name: "sliding window counter",
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, limit, int64(window.Seconds()), 1, opts...)
+			},
+		},
+		{
+			Name: "sliding window counter with string keys",
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				opts = append(opts, rate_limiter.WithKeyLayout(rate_limiter.KEY_LAYOUT_STRINGS))
+				return sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, limit, int64(window.Seconds()), 1, opts...)
+			},
+		},
+	}
+}
+
+// LimitersFor keeps the limiters that can run on a server with capabilities
+func LimitersFor(capabilities rate_limiter.Capabilities, limiters []Limiter) []Limiter {
+	var supported []Limiter
+	for _, limiter := range limiters {
+		built := limiter.New(rate_limiter.NewMemoryClient(nil), LIMIT, WINDOW)
+		if requirer, ok := built.(rate_limiter.RequirementsInterface); ok && len(capabilities.Missing(requirer.Requirements())) > 0 {
+			continue
+		}
+		supported = append(supported, limiter)
+	}
+	return supported
+}
+
+// DescribeConformance registers the conformance specs for every backend and limiter pair
+func DescribeConformance(backends []Backend, limiters []Limiter) {
+	for _, backend := range backends {
+		for _, limiter := range limiters {
+			describePair(backend, limiter)
+		}
+	}
+}
+
+func describePair(backend Backend, limiter Limiter) {
+	Describe(limiter.Name+" on "+backend.Name, func() {
+		var (
+			clock       *rate_limiter.ManualClock
+			client      rate_limiter.RedisClientInterface
+			advance     func(time.Duration)
+			rateLimiter rate_limiter.RateLimiterInterface
+		)
+
+		allowedOf := func(requests int, clientId string) int {
+			allowed := 0
+			for range requests {
+				if rateLimiter.LimitRequests(clientId) {
+					allowed++
+				}
+			}
+			return allowed
+		}
+
+		BeforeEach(func() {
+			clock = rate_limiter.NewManualClock(START_TIME)
+			var cleanup func()
+			client, advance, cleanup = backend.New(clock)
+			DeferCleanup(cleanup)
+			rateLimiter = limiter.New(client, LIMIT, WINDOW, rate_limiter.WithClock(clock))
+		})
+
+		It("should allow a brand-new client", func() {
+			Expect(rateLimiter.LimitRequests("new-client")).To(BeTrue())
+		})
+
+		It("should allow exactly the limit and deny the rest", func() {
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+		})
+
+		It("should track clients independently", func() {
+			Expect(allowedOf(LIMIT*2, "client-a")).To(Equal(LIMIT))
+			Expect(allowedOf(LIMIT, "client-b")).To(Equal(LIMIT))
+		})
+
+		It("should not recover the full limit part-way through the window", func() {
+			Expect(allowedOf(LIMIT, "client")).To(Equal(LIMIT))
+			advance(WINDOW / 2)
+
+			allowed := allowedOf(LIMIT, "client")
+			if limiter.Refills {
+				Expect(allowed).To(BeNumerically(">", 0))
+				Expect(allowed).To(BeNumerically("<", LIMIT))
+			} else {
+				Expect(allowed).To(BeZero())
+			}
+		})
+
+		It("should reset once the window has passed", func() {
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+			advance(WINDOW)
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+		})
+
+		It("should never allow more than the limit under concurrent requests", func() {
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				allowed int
+			)
+			for range LIMIT * 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if rateLimiter.LimitRequests("client") {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+
+			Expect(allowed).To(BeNumerically(">", 0))
+			Expect(allowed).To(BeNumerically("<=", LIMIT))
+		})
+
+		It("should deny during a backend outage and recover once it ends", func() {
+			faulty := mocks.NewFaultInjectingClient(client, mocks.FaultConfig{
+				Outages: []mocks.Outage{{Start: START_TIME, End: START_TIME.Add(WINDOW)}},
+				Clock:   clock,
+			})
+			rateLimiter = limiter.New(faulty, LIMIT, WINDOW, rate_limiter.WithClock(clock))
+
+			Expect(allowedOf(LIMIT, "client")).To(BeZero())
+			advance(WINDOW)
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+		})
+
+		It("should never allow more than the limit when backend calls fail at random", func() {
+			faulty := mocks.NewFaultInjectingClient(client, mocks.FaultConfig{
+				Seed:               1,
+				ErrorRate:          0.3,
+				PartialFailureRate: 0.3,
+			})
+			rateLimiter = limiter.New(faulty, LIMIT, WINDOW, rate_limiter.WithClock(clock))
+
+			Expect(allowedOf(LIMIT*10, "client")).To(BeNumerically("<=", LIMIT))
+		})
+
+		It("should peek at the remaining quota without consuming it", func() {
+			inspector, ok := rateLimiter.(rate_limiter.InspectorInterface)
+			if !ok {
+				Skip("the limiter cannot be inspected")
+			}
+			Expect(allowedOf(2, "client")).To(Equal(2))
+
+			for range 2 {
+				state, err := inspector.Peek(context.Background(), "client")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(state.Limit).To(Equal(int64(LIMIT)))
+				Expect(state.Remaining).To(Equal(int64(LIMIT - 2)))
+				Expect(state.ResetAfter).To(BeNumerically(">", 0))
+				Expect(state.ResetAfter).To(BeNumerically("<=", WINDOW+time.Second))
+			}
+			Expect(allowedOf(LIMIT, "client")).To(Equal(LIMIT - 2))
+		})
+
+		It("should give a client its full limit back on Reset", func() {
+			inspector, ok := rateLimiter.(rate_limiter.InspectorInterface)
+			if !ok {
+				Skip("the limiter cannot be inspected")
+			}
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+			Expect(allowedOf(LIMIT, "other-client")).To(Equal(LIMIT))
+
+			Expect(inspector.Reset(context.Background(), "client")).To(Succeed())
+
+			state, err := inspector.Peek(context.Background(), "client")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.Remaining).To(Equal(int64(LIMIT)))
+			Expect(state.ResetAfter).To(BeZero())
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+			Expect(allowedOf(LIMIT, "other-client")).To(BeZero())
+		})
+
+		It("should give refunded quota back within the charged window", func() {
+			refunder, ok := rateLimiter.(rate_limiter.RefundInterface)
+			if !ok {
+				Skip("the limiter cannot refund")
+			}
+			Expect(allowedOf(LIMIT, "client")).To(Equal(LIMIT))
+
+			Expect(refunder.Refund(context.Background(), "client", 2)).To(Succeed())
+
+			Expect(allowedOf(LIMIT, "client")).To(Equal(2))
+		})
+
+		It("should never refund a client above its limit", func() {
+			refunder, ok := rateLimiter.(rate_limiter.RefundInterface)
+			if !ok {
+				Skip("the limiter cannot refund")
+			}
+			Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+
+			Expect(refunder.Refund(context.Background(), "client", LIMIT*2)).To(Succeed())
+
+			Expect(allowedOf(LIMIT*3, "client")).To(Equal(LIMIT))
+		})
+
+		It("should give a decision's refund back within the window it charged", func() {
+			refunder, ok := rateLimiter.(rate_limiter.DecisionRefundInterface)
+			if !ok {
+				Skip("the limiter cannot refund decisions")
+			}
+			decisions := make([]rate_limiter.Decision, LIMIT)
+			for i := range decisions {
+				decisions[i] = rate_limiter.Evaluate(rateLimiter, "client")
+				Expect(decisions[i].Allowed).To(BeTrue())
+			}
+
+			Expect(refunder.RefundDecision(context.Background(), decisions[0], 2)).To(Succeed())
+
+			Expect(allowedOf(LIMIT, "client")).To(Equal(2))
+		})
+
+		It("should not let a decision's refund leak into the next window", func() {
+			refunder, ok := rateLimiter.(rate_limiter.DecisionRefundInterface)
+			if !ok {
+				Skip("the limiter cannot refund decisions")
+			}
+			decision := rate_limiter.Evaluate(rateLimiter, "client")
+			advance(WINDOW)
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+
+			Expect(refunder.RefundDecision(context.Background(), decision, LIMIT)).To(Succeed())
+
+			Expect(allowedOf(LIMIT, "client")).To(BeZero())
+		})
+
+		It("should deny and report the error when the backend fails", func() {
+			failing := limiter.New(mocks.NewMockRedisClient(), LIMIT, WINDOW, rate_limiter.WithClock(clock))
+
+			decision := rate_limiter.Evaluate(failing, "client")
+			Expect(decision.Allowed).To(BeFalse())
+			if _, ok := failing.(rate_limiter.DeciderInterface); ok {
+				Expect(decision.Err).To(HaveOccurred())
+			}
+		})
+	})
+}
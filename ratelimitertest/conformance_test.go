@@ -0,0 +1,19 @@
+package ratelimitertest_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/ratelimitertest"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+var _ = Describe("Conformance", func() {
+	ratelimitertest.DescribeConformance(
+		[]ratelimitertest.Backend{ratelimitertest.MemoryBackend(), ratelimitertest.MiniredisBackend()},
+		ratelimitertest.DefaultLimiters(),
+	)
+	ratelimitertest.DescribeConformance(
+		[]ratelimitertest.Backend{ratelimitertest.LegacyMiniredisBackend()},
+		ratelimitertest.LimitersFor(rate_limiter.Capabilities{}, ratelimitertest.DefaultLimiters()),
+	)
+})
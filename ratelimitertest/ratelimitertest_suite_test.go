@@ -0,0 +1,13 @@
+package ratelimitertest_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRateLimiterTest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RateLimiterTest Suite")
+}
@@ -0,0 +1,6 @@
+package resilient_rate_limiter
+
+import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+
+var _ rate_limiter.RateLimiterInterface = (*ResilientRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*ResilientRateLimiter)(nil)
@@ -0,0 +1,214 @@
+package resilient_rate_limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const (
+	METRIC_CIRCUIT_STATE_CHANGES = "rate_limiter_circuit_state_changes_total"
+	METRIC_FALLBACK_DECISIONS    = "rate_limiter_fallback_decisions_total"
+
+	DEFAULT_FAILURE_THRESHOLD = 5
+	DEFAULT_OPEN_TIMEOUT      = 5 * time.Second
+)
+
+// ErrInvalidInstances is returned by NewScaledResilientRateLimiter for fewer than one expected instance
+var ErrInvalidInstances = errors.New("expected instances must be at least one")
+
+// LimiterFactory builds a limiter on client with opts, so that a primary and its fallback can be built alike
+type LimiterFactory func(client rate_limiter.RedisClientInterface, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface
+
+type CircuitState string
+
+const (
+	// CIRCUIT_CLOSED sends every request to the primary limiter
+	CIRCUIT_CLOSED CircuitState = "closed"
+	// CIRCUIT_OPEN sends every request to the fallback limiter
+	CIRCUIT_OPEN CircuitState = "open"
+	// CIRCUIT_HALF_OPEN lets a single probe through to the primary limiter to see if it has recovered
+	CIRCUIT_HALF_OPEN CircuitState = "half_open"
+)
+
+// ResilientConfig tunes the circuit breaker of a ResilientRateLimiter
+type ResilientConfig struct {
+	// FailureThreshold is how many consecutive primary errors open the circuit, DEFAULT_FAILURE_THRESHOLD when zero
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before probing the primary, DEFAULT_OPEN_TIMEOUT when zero
+	OpenTimeout time.Duration
+	Clock       rate_limiter.ClockInterface
+	Metrics     rate_limiter.MetricsRecorderInterface
+	// OnStateChange is called after every circuit state transition
+	OnStateChange func(from CircuitState, to CircuitState)
+}
+
+// ResilientRateLimiter enforces a Redis-backed primary limiter and switches to an in-process
+// fallback while the primary keeps failing, instead of denying every request. Only limiters that
+// implement DeciderInterface report errors, so the primary should be one of them.
+type ResilientRateLimiter struct {
+	primary  rate_limiter.RateLimiterInterface
+	fallback rate_limiter.RateLimiterInterface
+	config   ResilientConfig
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewResilientRateLimiter guards primary with a circuit breaker that fails over to fallback. Build
+// the fallback on a MemoryClient with WithLimitScale(1/expectedInstances), so that the instances
+// together still admit roughly the primary's limit, or let NewScaledResilientRateLimiter build it.
+func NewResilientRateLimiter(primary rate_limiter.RateLimiterInterface, fallback rate_limiter.RateLimiterInterface, config ResilientConfig) *ResilientRateLimiter {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DEFAULT_FAILURE_THRESHOLD
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DEFAULT_OPEN_TIMEOUT
+	}
+	if config.Clock == nil {
+		config.Clock = rate_limiter.SystemClock{}
+	}
+	return &ResilientRateLimiter{
+		primary:  primary,
+		fallback: fallback,
+		config:   config,
+		state:    CIRCUIT_CLOSED,
+	}
+}
+
+// NewScaledResilientRateLimiter builds both limiters with newLimiter and opts: the primary on client,
+// and the fallback on a MemoryClient with WithLimitScale(1/expectedInstances). The fallback then
+// always has the primary's limits, scaled to one instance's share.
+func NewScaledResilientRateLimiter(client rate_limiter.RedisClientInterface, newLimiter LimiterFactory, expectedInstances int, config ResilientConfig, opts ...rate_limiter.Option) (*ResilientRateLimiter, error) {
+	if expectedInstances < 1 {
+		return nil, ErrInvalidInstances
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = rate_limiter.SystemClock{}
+	}
+	fallbackOpts := append(opts[:len(opts):len(opts)], rate_limiter.WithLimitScale(1/float64(expectedInstances)))
+	return NewResilientRateLimiter(
+		newLimiter(client, opts...),
+		newLimiter(rate_limiter.NewMemoryClient(clock), fallbackOpts...),
+		config,
+	), nil
+}
+
+func (r *ResilientRateLimiter) LimitRequests(clientId string) bool {
+	return r.Decide(clientId).Allowed
+}
+
+func (r *ResilientRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	usePrimary, changes := r.usePrimary()
+	r.notify(changes)
+	if !usePrimary {
+		return r.decideFallback(clientId)
+	}
+
+	decision := rate_limiter.Evaluate(r.primary, clientId)
+	if decision.Err != nil {
+		r.notify(r.recordFailure())
+		return r.decideFallback(clientId)
+	}
+	r.notify(r.recordSuccess())
+	return decision
+}
+
+// State returns the current circuit state
+func (r *ResilientRateLimiter) State() CircuitState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+type stateChange struct {
+	from CircuitState
+	to   CircuitState
+}
+
+// usePrimary reports whether this request should go to the primary, moving an open circuit whose
+// timeout has passed to half-open and claiming its single probe
+func (r *ResilientRateLimiter) usePrimary() (bool, []stateChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var changes []stateChange
+	switch r.state {
+	case CIRCUIT_CLOSED:
+		return true, nil
+	case CIRCUIT_OPEN:
+		if r.config.Clock.Now().Sub(r.openedAt) < r.config.OpenTimeout {
+			return false, nil
+		}
+		changes = r.transition(CIRCUIT_HALF_OPEN)
+	}
+
+	if r.probing {
+		return false, changes
+	}
+	r.probing = true
+	return true, changes
+}
+
+func (r *ResilientRateLimiter) recordFailure() []stateChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures++
+	if r.state == CIRCUIT_HALF_OPEN || (r.state == CIRCUIT_CLOSED && r.failures >= r.config.FailureThreshold) {
+		r.probing = false
+		r.openedAt = r.config.Clock.Now()
+		return r.transition(CIRCUIT_OPEN)
+	}
+	return nil
+}
+
+func (r *ResilientRateLimiter) recordSuccess() []stateChange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = 0
+	if r.state == CIRCUIT_HALF_OPEN {
+		r.probing = false
+		return r.transition(CIRCUIT_CLOSED)
+	}
+	return nil
+}
+
+// transition must be called with mu held, and its result passed to notify once mu is released
+func (r *ResilientRateLimiter) transition(to CircuitState) []stateChange {
+	from := r.state
+	if from == to {
+		return nil
+	}
+	r.state = to
+	return []stateChange{{from: from, to: to}}
+}
+
+func (r *ResilientRateLimiter) notify(changes []stateChange) {
+	for _, change := range changes {
+		if r.config.Metrics != nil {
+			r.config.Metrics.IncCounter(METRIC_CIRCUIT_STATE_CHANGES, map[string]string{
+				"from": string(change.from),
+				"to":   string(change.to),
+			})
+		}
+		if r.config.OnStateChange != nil {
+			r.config.OnStateChange(change.from, change.to)
+		}
+	}
+}
+
+func (r *ResilientRateLimiter) decideFallback(clientId string) rate_limiter.Decision {
+	decision := rate_limiter.Evaluate(r.fallback, clientId)
+	if r.config.Metrics != nil {
+		r.config.Metrics.IncCounter(METRIC_FALLBACK_DECISIONS, rate_limiter.DecisionLabels(decision))
+	}
+	return decision
+}
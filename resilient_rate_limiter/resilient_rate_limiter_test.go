@@ -0,0 +1,151 @@
+package resilient_rate_limiter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/resilient_rate_limiter"
+)
+
+var _ = Describe("ResilientRateLimiter", func() {
+	const (
+		LIMIT        = 10
+		OPEN_TIMEOUT = 5 * time.Second
+	)
+
+	var (
+		clock       *rate_limiter.ManualClock
+		outageStart time.Time
+		primary     *mocks.FaultInjectingClient
+		metrics     *mocks.MockMetricsRecorder
+		changes     []resilient_rate_limiter.CircuitState
+		rateLimiter *resilient_rate_limiter.ResilientRateLimiter
+	)
+
+	allowedOf := func(requests int, clientId string) int {
+		allowed := 0
+		for range requests {
+			if rateLimiter.LimitRequests(clientId) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	BeforeEach(func() {
+		clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		outageStart = clock.Now().Add(time.Minute)
+		primary = mocks.NewFaultInjectingClient(rate_limiter.NewMemoryClient(clock), mocks.FaultConfig{
+			Outages: []mocks.Outage{{Start: outageStart, End: outageStart.Add(time.Minute)}},
+			Clock:   clock,
+		})
+		metrics = mocks.NewMockMetricsRecorder()
+		changes = nil
+
+		rateLimiter = resilient_rate_limiter.NewResilientRateLimiter(
+			fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(primary, 60, LIMIT, rate_limiter.WithClock(clock)),
+			fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(rate_limiter.NewMemoryClient(clock), 60, LIMIT,
+				rate_limiter.WithClock(clock), rate_limiter.WithLimitScale(0.5)),
+			resilient_rate_limiter.ResilientConfig{
+				FailureThreshold: 3,
+				OpenTimeout:      OPEN_TIMEOUT,
+				Clock:            clock,
+				Metrics:          metrics,
+				OnStateChange: func(from, to resilient_rate_limiter.CircuitState) {
+					changes = append(changes, to)
+				},
+			},
+		)
+	})
+
+	Context("when the primary is healthy", func() {
+		It("should enforce the primary limit with the circuit closed", func() {
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+			Expect(rateLimiter.State()).To(Equal(resilient_rate_limiter.CIRCUIT_CLOSED))
+			Expect(metrics.Count(resilient_rate_limiter.METRIC_FALLBACK_DECISIONS)).To(BeZero())
+		})
+	})
+
+	Context("when the primary fails", func() {
+		BeforeEach(func() {
+			clock.Set(outageStart)
+		})
+
+		It("should answer failed requests from the fallback before the circuit opens", func() {
+			Expect(allowedOf(2, "client")).To(Equal(2))
+			Expect(rateLimiter.State()).To(Equal(resilient_rate_limiter.CIRCUIT_CLOSED))
+			Expect(metrics.Count(resilient_rate_limiter.METRIC_FALLBACK_DECISIONS)).To(Equal(2))
+		})
+
+		It("should open the circuit after consecutive failures and stop calling the primary", func() {
+			allowedOf(3, "client")
+			Expect(rateLimiter.State()).To(Equal(resilient_rate_limiter.CIRCUIT_OPEN))
+			Expect(changes).To(Equal([]resilient_rate_limiter.CircuitState{resilient_rate_limiter.CIRCUIT_OPEN}))
+			Expect(metrics.Counters[resilient_rate_limiter.METRIC_CIRCUIT_STATE_CHANGES]).To(ConsistOf(
+				map[string]string{"from": "closed", "to": "open"},
+			))
+
+			calls := primary.Stats().Calls
+			allowedOf(5, "client")
+			Expect(primary.Stats().Calls).To(Equal(calls))
+		})
+
+		It("should enforce the scaled limit from the fallback", func() {
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT / 2))
+		})
+
+		It("should close the circuit once a probe succeeds after the open timeout", func() {
+			allowedOf(3, "client")
+			clock.Set(outageStart.Add(time.Minute))
+
+			Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+			Expect(rateLimiter.State()).To(Equal(resilient_rate_limiter.CIRCUIT_CLOSED))
+			Expect(changes).To(Equal([]resilient_rate_limiter.CircuitState{
+				resilient_rate_limiter.CIRCUIT_OPEN,
+				resilient_rate_limiter.CIRCUIT_HALF_OPEN,
+				resilient_rate_limiter.CIRCUIT_CLOSED,
+			}))
+		})
+
+		It("should reopen the circuit when the probe fails", func() {
+			allowedOf(3, "client")
+			clock.Advance(OPEN_TIMEOUT)
+
+			rateLimiter.LimitRequests("client")
+			Expect(rateLimiter.State()).To(Equal(resilient_rate_limiter.CIRCUIT_OPEN))
+			Expect(changes).To(Equal([]resilient_rate_limiter.CircuitState{
+				resilient_rate_limiter.CIRCUIT_OPEN,
+				resilient_rate_limiter.CIRCUIT_HALF_OPEN,
+				resilient_rate_limiter.CIRCUIT_OPEN,
+			}))
+		})
+	})
+
+	Context("with a fallback built from the primary", func() {
+		newLimiter := func(client rate_limiter.RedisClientInterface, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+			return fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, 60, LIMIT, opts...)
+		}
+
+		It("should scale the primary's limit to one instance's share", func() {
+			var err error
+			rateLimiter, err = resilient_rate_limiter.NewScaledResilientRateLimiter(primary, newLimiter, 2,
+				resilient_rate_limiter.ResilientConfig{FailureThreshold: 3, Clock: clock}, rate_limiter.WithClock(clock))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT))
+
+			clock.Set(outageStart)
+			Expect(allowedOf(LIMIT*2, "client")).To(Equal(LIMIT / 2))
+			Expect(rateLimiter.State()).To(Equal(resilient_rate_limiter.CIRCUIT_OPEN))
+		})
+
+		It("should reject fewer than one expected instance", func() {
+			_, err := resilient_rate_limiter.NewScaledResilientRateLimiter(primary, newLimiter, 0, resilient_rate_limiter.ResilientConfig{})
+			Expect(err).To(MatchError(resilient_rate_limiter.ErrInvalidInstances))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package simulator
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	PATTERN_STEADY   = "steady"
+	PATTERN_BURSTY   = "bursty"
+	PATTERN_BOUNDARY = "boundary"
+	PATTERN_POISSON  = "poisson"
+	PATTERN_DIURNAL  = "diurnal"
+)
+
+// Pattern generates request arrival times, as offsets from the start of the simulation
+type Pattern struct {
+	Name     string
+	Arrivals func(config Config, rng *rand.Rand) []time.Duration
+}
+
+// Patterns returns the built-in traffic patterns
+func Patterns() []Pattern {
+	return []Pattern{
+		{Name: PATTERN_STEADY, Arrivals: steadyArrivals},
+		{Name: PATTERN_BURSTY, Arrivals: burstyArrivals},
+		{Name: PATTERN_BOUNDARY, Arrivals: boundaryArrivals},
+		{Name: PATTERN_POISSON, Arrivals: poissonArrivals},
+		{Name: PATTERN_DIURNAL, Arrivals: diurnalArrivals},
+	}
+}
+
+// steadyArrivals spaces requests evenly at config.Rate per second
+func steadyArrivals(config Config, _ *rand.Rand) []time.Duration {
+	if config.Rate <= 0 {
+		return nil
+	}
+	// rates above one per nanosecond would round the interval to zero and never advance
+	interval := max(time.Duration(float64(time.Second)/config.Rate), time.Nanosecond)
+	var arrivals []time.Duration
+	for at := time.Duration(0); at < config.Duration; at += interval {
+		arrivals = append(arrivals, at)
+	}
+	return arrivals
+}
+
+// burstyArrivals sends the requests of a whole window at once, at the mean rate of config.Rate
+func burstyArrivals(config Config, _ *rand.Rand) []time.Duration {
+	burstSize := int(config.Rate * config.Window.Seconds())
+	var arrivals []time.Duration
+	for at := time.Duration(0); at < config.Duration; at += config.Window {
+		for range burstSize {
+			arrivals = append(arrivals, at)
+		}
+	}
+	return arrivals
+}
+
+// boundaryArrivals opens a window with a single request, then sends the rest of the limit just
+// before the window ends and a full limit just after, which a fixed window admits in full
+func boundaryArrivals(config Config, _ *rand.Rand) []time.Duration {
+	var arrivals []time.Duration
+	for start := time.Duration(0); start+config.Window < config.Duration; start += 2 * config.Window {
+		arrivals = append(arrivals, start)
+		for range config.Limit - 1 {
+			arrivals = append(arrivals, start+config.Window-time.Millisecond)
+		}
+		for range config.Limit {
+			arrivals = append(arrivals, start+config.Window)
+		}
+	}
+	return arrivals
+}
+
+// poissonArrivals draws exponential gaps between requests at a mean of config.Rate per second
+func poissonArrivals(config Config, rng *rand.Rand) []time.Duration {
+	return thinnedArrivals(config, rng, func(time.Duration) float64 { return 1 })
+}
+
+// diurnalArrivals follows a single day-like cycle over the simulation, peaking at twice config.Rate
+// halfway through and falling to nothing at either end
+func diurnalArrivals(config Config, rng *rand.Rand) []time.Duration {
+	return thinnedArrivals(config, rng, func(at time.Duration) float64 {
+		return 1 - math.Cos(2*math.Pi*at.Seconds()/config.Duration.Seconds())
+	})
+}
+
+// thinnedArrivals generates a Poisson process whose rate at each instant is config.Rate scaled by
+// weight, which must stay within [0, 2]
+func thinnedArrivals(config Config, rng *rand.Rand, weight func(time.Duration) float64) []time.Duration {
+	const MAX_WEIGHT = 2
+	if config.Rate <= 0 {
+		return nil
+	}
+	var arrivals []time.Duration
+	peakRate := config.Rate * MAX_WEIGHT
+	at := time.Duration(0)
+	for {
+		at += time.Duration(rng.ExpFloat64() / peakRate * float64(time.Second))
+		if at >= config.Duration {
+			return arrivals
+		}
+		if rng.Float64()*MAX_WEIGHT < weight(at) {
+			arrivals = append(arrivals, at)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package simulator
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/composite_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+)
+
+const DEFAULT_TIMELINE_BUCKET = time.Minute
+
+// ReplayLimit is one limit of the set a trace is replayed through
+type ReplayLimit struct {
+	Algorithm Algorithm
+	Limit     int
+	Window    time.Duration
+}
+
+// Name writes the limit in the form ParseReplayLimit reads
+func (r ReplayLimit) Name() string {
+	return fmt.Sprintf("%s:%d/%ds", r.Algorithm.Name, r.Limit, int64(r.Window.Seconds()))
+}
+
+// ParseReplayLimit reads a limit written as algorithm:limit/window, e.g. token_bucket:10/1s
+func ParseReplayLimit(spec string) (ReplayLimit, error) {
+	algorithmName, rest, ok := strings.Cut(spec, ":")
+	limitStr, windowStr, ok2 := strings.Cut(rest, "/")
+	if !ok || !ok2 {
+		return ReplayLimit{}, fmt.Errorf("limit %q is not of the form algorithm:limit/window", spec)
+	}
+
+	index := slices.IndexFunc(Algorithms(), func(algorithm Algorithm) bool {
+		return algorithm.Name == algorithmName
+	})
+	if index < 0 {
+		return ReplayLimit{}, fmt.Errorf("unknown algorithm %q", algorithmName)
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return ReplayLimit{}, fmt.Errorf("limit %q must be a positive integer", limitStr)
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window < time.Second || window%time.Second != 0 {
+		return ReplayLimit{}, fmt.Errorf("window %q must be a whole number of seconds", windowStr)
+	}
+
+	return ReplayLimit{Algorithm: Algorithms()[index], Limit: limit, Window: window}, nil
+}
+
+// ReplayConfig controls the shape of a replay report
+type ReplayConfig struct {
+	// TimelineBucket is the width of each timeline bucket, DEFAULT_TIMELINE_BUCKET when zero
+	TimelineBucket time.Duration
+}
+
+// TimelineBucket counts the requests that arrived in [Start, Start+bucket width)
+type TimelineBucket struct {
+	Start    time.Time `json:"start"`
+	Requests int       `json:"requests"`
+	Denied   int       `json:"denied"`
+}
+
+// KeyReport describes what the limits would have done to one client
+type KeyReport struct {
+	Key      string `json:"key"`
+	Requests int    `json:"requests"`
+	Denied   int    `json:"denied"`
+	// DeniedBy counts denials by the name of the limit that denied them
+	DeniedBy    map[string]int   `json:"denied_by,omitempty"`
+	FirstDenied time.Time        `json:"first_denied,omitzero"`
+	LastDenied  time.Time        `json:"last_denied,omitzero"`
+	Timeline    []TimelineBucket `json:"timeline,omitempty"`
+}
+
+func (k KeyReport) DenialRate() float64 {
+	if k.Requests == 0 {
+		return 0
+	}
+	return float64(k.Denied) / float64(k.Requests)
+}
+
+// ReplayReport is the outcome of replaying a trace
+type ReplayReport struct {
+	Limits   []string `json:"limits"`
+	Requests int      `json:"requests"`
+	Denied   int      `json:"denied"`
+	// Keys lists every client, the most denied first
+	Keys     []KeyReport      `json:"keys"`
+	Timeline []TimelineBucket `json:"timeline"`
+}
+
+// Top returns the n most denied clients that had at least one denial
+func (r ReplayReport) Top(n int) []KeyReport {
+	index := slices.IndexFunc(r.Keys, func(key KeyReport) bool { return key.Denied == 0 })
+	if index < 0 {
+		index = len(r.Keys)
+	}
+	return r.Keys[:min(n, index)]
+}
+
+// Replay runs every event of trace through the set of limits on simulated time. A request is
+// denied when any limit denies it; limits earlier in the set take precedence in DeniedBy.
+func Replay(trace Trace, limits []ReplayLimit, config ReplayConfig) (ReplayReport, error) {
+	if len(limits) == 0 {
+		return ReplayReport{}, errors.New("at least one limit is required")
+	}
+	if config.TimelineBucket <= 0 {
+		config.TimelineBucket = DEFAULT_TIMELINE_BUCKET
+	}
+
+	report := ReplayReport{Requests: len(trace.Events)}
+	if len(trace.Events) == 0 {
+		return report, nil
+	}
+
+	start := trace.Events[0].Time
+	clock := rate_limiter.NewManualClock(start)
+	client := rate_limiter.NewMemoryClient(clock)
+
+	var members []composite_rate_limiter.CompositeMember
+	for i, limit := range limits {
+		report.Limits = append(report.Limits, limit.Name())
+		// Limits of the same algorithm would otherwise share keys in the one backend
+		limiter := limit.Algorithm.New(client, limit.Limit, limit.Window,
+			rate_limiter.WithClock(clock), rate_limiter.WithKeyPrefix(fmt.Sprintf("replay:%d:", i)))
+		members = append(members, composite_rate_limiter.CompositeMember{Limiter: limiter})
+	}
+	limiter := composite_rate_limiter.NewCompositeRateLimiter(members)
+
+	keys := make(map[string]*KeyReport)
+	keyTimelines := make(map[string]map[int]*TimelineBucket)
+	timeline := make(map[int]*TimelineBucket)
+	for _, event := range trace.Events {
+		clock.Set(event.Time)
+		decision := limiter.DecideAll(event.Key)
+		if decision.Err != nil {
+			return ReplayReport{}, decision.Err
+		}
+
+		key, ok := keys[event.Key]
+		if !ok {
+			key = &KeyReport{Key: event.Key}
+			keys[event.Key] = key
+			keyTimelines[event.Key] = make(map[int]*TimelineBucket)
+		}
+		key.Requests++
+
+		bucket := int(event.Time.Sub(start) / config.TimelineBucket)
+		buckets := []*TimelineBucket{
+			timelineBucket(timeline, bucket, start, config.TimelineBucket),
+			timelineBucket(keyTimelines[event.Key], bucket, start, config.TimelineBucket),
+		}
+		for _, b := range buckets {
+			b.Requests++
+		}
+
+		if decision.Allowed {
+			continue
+		}
+		report.Denied++
+		key.Denied++
+		if key.DeniedBy == nil {
+			key.DeniedBy = make(map[string]int)
+			key.FirstDenied = event.Time
+		}
+		key.DeniedBy[report.Limits[decision.DeniedBy]]++
+		key.LastDenied = event.Time
+		for _, b := range buckets {
+			b.Denied++
+		}
+	}
+
+	for _, key := range keys {
+		// Timelines of clients that were never denied add nothing but bulk
+		if key.Denied > 0 {
+			key.Timeline = sortedTimeline(keyTimelines[key.Key])
+		}
+		report.Keys = append(report.Keys, *key)
+	}
+	slices.SortFunc(report.Keys, func(a, b KeyReport) int {
+		return cmp.Or(cmp.Compare(b.Denied, a.Denied), cmp.Compare(b.Requests, a.Requests), strings.Compare(a.Key, b.Key))
+	})
+	report.Timeline = sortedTimeline(timeline)
+	return report, nil
+}
+
+func timelineBucket(timeline map[int]*TimelineBucket, index int, start time.Time, width time.Duration) *TimelineBucket {
+	bucket, ok := timeline[index]
+	if !ok {
+		bucket = &TimelineBucket{Start: start.Add(time.Duration(index) * width)}
+		timeline[index] = bucket
+	}
+	return bucket
+}
+
+func sortedTimeline(timeline map[int]*TimelineBucket) []TimelineBucket {
+	buckets := make([]TimelineBucket, 0, len(timeline))
+	for _, bucket := range timeline {
+		buckets = append(buckets, *bucket)
+	}
+	slices.SortFunc(buckets, func(a, b TimelineBucket) int {
+		return a.Start.Compare(b.Start)
+	})
+	return buckets
+}
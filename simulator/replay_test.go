@@ -0,0 +1,155 @@
+package simulator_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/simulator"
+)
+
+var _ = Describe("Trace replay", func() {
+	start := time.Date(2023, 10, 10, 13, 55, 36, 0, time.UTC)
+
+	Context("when reading access logs", func() {
+		It("should read common and combined log lines by host or user", func() {
+			log := strings.Join([]string{
+				`10.0.0.1 - alice [10/Oct/2023:13:55:37 +0000] "GET / HTTP/1.1" 200 10 "-" "curl/8.0"`,
+				`10.0.0.2 - bob [10/Oct/2023:13:55:36 +0000] "GET / HTTP/1.1" 200 10`,
+				`not a log line`,
+			}, "\n")
+
+			trace, err := simulator.ReadTrace(strings.NewReader(log), simulator.TraceConfig{Format: simulator.TRACE_FORMAT_COMMON})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trace.Skipped).To(Equal(1))
+			Expect(trace.Events).To(HaveLen(2))
+			Expect(trace.Events[0].Key).To(Equal("10.0.0.2"))
+			Expect(trace.Events[0].Time.Equal(start)).To(BeTrue())
+
+			trace, err = simulator.ReadTrace(strings.NewReader(log), simulator.TraceConfig{
+				Format:   simulator.TRACE_FORMAT_COMMON,
+				KeyField: simulator.COMMON_LOG_KEY_USER,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trace.Events[1].Key).To(Equal("alice"))
+		})
+
+		It("should read JSON lines with RFC 3339 or Unix timestamps", func() {
+			log := strings.Join([]string{
+				`{"ts": "2023-10-10T13:55:36Z", "api_key": "a"}`,
+				`{"ts": 1696946137.5, "api_key": "b"}`,
+				`{"ts": "yesterday", "api_key": "c"}`,
+				`{"api_key": "d"`,
+			}, "\n")
+
+			trace, err := simulator.ReadTrace(strings.NewReader(log), simulator.TraceConfig{
+				Format:    simulator.TRACE_FORMAT_JSON,
+				TimeField: "ts",
+				KeyField:  "api_key",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trace.Skipped).To(Equal(2))
+			Expect(trace.Events).To(HaveLen(2))
+			Expect(trace.Events[1].Key).To(Equal("b"))
+			Expect(trace.Events[1].Time.Sub(start)).To(Equal(1500 * time.Millisecond))
+		})
+
+		It("should read CSV by header name", func() {
+			log := "path,key,timestamp\n/a,a,2023-10-10T13:55:36Z\n/b,b,1696946136\n/c,,1696946136\n"
+
+			trace, err := simulator.ReadTrace(strings.NewReader(log), simulator.TraceConfig{Format: simulator.TRACE_FORMAT_CSV})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(trace.Skipped).To(Equal(1))
+			Expect(trace.Events).To(HaveLen(2))
+			Expect(trace.Events[1].Time.Equal(start)).To(BeTrue())
+		})
+
+		It("should reject CSV without the configured columns", func() {
+			_, err := simulator.ReadTrace(strings.NewReader("when,who\n"), simulator.TraceConfig{Format: simulator.TRACE_FORMAT_CSV})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject an unknown format", func() {
+			_, err := simulator.ReadTrace(strings.NewReader(""), simulator.TraceConfig{Format: "xml"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when parsing limits", func() {
+		It("should accept algorithm:limit/window", func() {
+			limit, err := simulator.ParseReplayLimit("token_bucket:10/1s")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(limit.Limit).To(Equal(10))
+			Expect(limit.Window).To(Equal(time.Second))
+			Expect(limit.Name()).To(Equal("token_bucket:10/1s"))
+		})
+
+		DescribeTable("should reject malformed limits",
+			func(spec string) {
+				_, err := simulator.ParseReplayLimit(spec)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("missing window", "token_bucket:10"),
+			Entry("unknown algorithm", "leaky_bucket:10/1s"),
+			Entry("non-positive limit", "token_bucket:0/1s"),
+			Entry("fractional window", "token_bucket:10/1500ms"),
+		)
+	})
+
+	Context("when replaying a trace", func() {
+		var (
+			trace  simulator.Trace
+			limits []simulator.ReplayLimit
+		)
+
+		BeforeEach(func() {
+			trace = simulator.Trace{}
+			for i := range 5 {
+				trace.Events = append(trace.Events, simulator.TraceEvent{Time: start.Add(time.Duration(i) * time.Second), Key: "heavy"})
+			}
+			trace.Events = append(trace.Events, simulator.TraceEvent{Time: start.Add(90 * time.Second), Key: "light"})
+
+			perMinute, err := simulator.ParseReplayLimit("fixed_window_counter:3/60s")
+			Expect(err).NotTo(HaveOccurred())
+			perSecond, err := simulator.ParseReplayLimit("token_bucket:5/1s")
+			Expect(err).NotTo(HaveOccurred())
+			limits = []simulator.ReplayLimit{perMinute, perSecond}
+		})
+
+		It("should report would-be denials per client, most denied first", func() {
+			report, err := simulator.Replay(trace, limits, simulator.ReplayConfig{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Requests).To(Equal(6))
+			Expect(report.Denied).To(Equal(2))
+			Expect(report.Keys).To(HaveLen(2))
+
+			heavy := report.Keys[0]
+			Expect(heavy.Key).To(Equal("heavy"))
+			Expect(heavy.Denied).To(Equal(2))
+			Expect(heavy.DeniedBy).To(Equal(map[string]int{"fixed_window_counter:3/60s": 2}))
+			Expect(heavy.FirstDenied).To(Equal(start.Add(3 * time.Second)))
+			Expect(heavy.LastDenied).To(Equal(start.Add(4 * time.Second)))
+
+			Expect(report.Top(10)).To(HaveLen(1))
+		})
+
+		It("should bucket requests and denials into timelines", func() {
+			report, err := simulator.Replay(trace, limits, simulator.ReplayConfig{TimelineBucket: time.Minute})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Timeline).To(Equal([]simulator.TimelineBucket{
+				{Start: start, Requests: 5, Denied: 2},
+				{Start: start.Add(time.Minute), Requests: 1, Denied: 0},
+			}))
+			Expect(report.Keys[0].Timeline).To(Equal([]simulator.TimelineBucket{{Start: start, Requests: 5, Denied: 2}}))
+			Expect(report.Keys[1].Timeline).To(BeEmpty())
+		})
+
+		It("should require at least one limit", func() {
+			_, err := simulator.Replay(trace, nil, simulator.ReplayConfig{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,82 @@
+package simulator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+var CSV_HEADER = []string{"pattern", "algorithm", "limit", "offered", "admitted", "denied", "peak_admitted", "overshoot"}
+
+// WriteCSV writes one row per result, after a header row
+func WriteCSV(w io.Writer, results []Result) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CSV_HEADER); err != nil {
+		return err
+	}
+	for _, result := range results {
+		row := []string{
+			result.Pattern,
+			result.Algorithm,
+			strconv.Itoa(result.Limit),
+			strconv.Itoa(result.Offered),
+			strconv.Itoa(result.Admitted),
+			strconv.Itoa(result.Denied),
+			strconv.Itoa(result.PeakAdmitted),
+			strconv.Itoa(result.Overshoot),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the results as an indented JSON array
+func WriteJSON(w io.Writer, results []Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+var REPLAY_CSV_HEADER = []string{"key", "requests", "denied", "denial_rate", "first_denied", "last_denied"}
+
+// WriteReplayCSV writes one row per client, the most denied first. Timelines are only in the JSON report.
+func WriteReplayCSV(w io.Writer, keys []KeyReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(REPLAY_CSV_HEADER); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		row := []string{
+			key.Key,
+			strconv.Itoa(key.Requests),
+			strconv.Itoa(key.Denied),
+			strconv.FormatFloat(key.DenialRate(), 'f', 4, 64),
+			formatReportTime(key.FirstDenied),
+			formatReportTime(key.LastDenied),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteReplayJSON writes the whole report, timelines included, as indented JSON
+func WriteReplayJSON(w io.Writer, report ReplayReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,156 @@
+package simulator
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	fixed_window_counter_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/fixed_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_log_rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+const SIMULATION_CLIENT_ID = "simulated-client"
+
+// START_TIME is where the virtual clock starts. It falls on the hour, so epoch-aligned windows that
+// divide an hour line up with the start of the simulation.
+var START_TIME = time.Unix(1_699_999_200, 0)
+
+// Config describes one simulated workload, shared by every pattern and algorithm
+type Config struct {
+	// Limit is the number of requests each algorithm admits per Window
+	Limit int
+	// Window must be a whole number of seconds, the resolution of the limiters
+	Window time.Duration
+	// Rate is the mean offered load in requests per second
+	Rate float64
+	// Duration is how much virtual time the simulation covers
+	Duration time.Duration
+	// Seed makes random patterns reproducible
+	Seed int64
+}
+
+func (c Config) Validate() error {
+	if c.Limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	if c.Window < time.Second || c.Window%time.Second != 0 {
+		return errors.New("window must be a whole number of seconds")
+	}
+	if c.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	if c.Duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	return nil
+}
+
+// Algorithm builds a limiter that admits limit requests per window
+type Algorithm struct {
+	Name string
+	New  func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface
+}
+
+// Algorithms returns the four algorithms shipped with this module
+func Algorithms() []Algorithm {
+	return []Algorithm{
+		{
+			Name: string(rate_limiter.ALGORITHM_TOKEN_BUCKET),
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, limit, float64(limit)/window.Seconds(), opts...)
+			},
+		},
+		{
+			Name: string(rate_limiter.ALGORITHM_FIXED_WINDOW_COUNTER),
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return fixed_window_counter_ratelimiter.NewFixedWindowCounterRateLimiter(client, int(window.Seconds()), limit, opts...)
+			},
+		},
+		{
+			Name: string(rate_limiter.ALGORITHM_SLIDING_WINDOW_LOG),
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				return sliding_window_log_rate_limiter.NewSlidingWindowLogRateLimiter(client, limit, int64(window.Seconds()), opts...)
+			},
+		},
+		{
+			Name: string(rate_limiter.ALGORITHM_SLIDING_WINDOW_COUNTER),
+			New: func(client rate_limiter.RedisClientInterface, limit int, window time.Duration, opts ...rate_limiter.Option) rate_limiter.RateLimiterInterface {
+				windowSize := int64(window.Seconds())
+				return sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, limit, windowSize, max(windowSize/10, 1), opts...)
+			},
+		},
+	}
+}
+
+// Result summarises how one algorithm handled one pattern
+type Result struct {
+	Pattern   string `json:"pattern"`
+	Algorithm string `json:"algorithm"`
+	Limit     int    `json:"limit"`
+	Offered   int    `json:"offered"`
+	Admitted  int    `json:"admitted"`
+	Denied    int    `json:"denied"`
+	// PeakAdmitted is the most requests admitted within any Window-long interval, not just the
+	// intervals the algorithm itself tracks
+	PeakAdmitted int `json:"peak_admitted"`
+	// Overshoot is how far PeakAdmitted went past Limit
+	Overshoot int `json:"overshoot"`
+}
+
+// Run replays every pattern against every algorithm, each on a fresh in-memory backend
+func Run(config Config, patterns []Pattern, algorithms []Algorithm) ([]Result, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, pattern := range patterns {
+		// Every algorithm sees the same arrivals so that their results are comparable
+		arrivals := pattern.Arrivals(config, rand.New(rand.NewSource(config.Seed)))
+		for _, algorithm := range algorithms {
+			results = append(results, simulate(config, pattern.Name, arrivals, algorithm))
+		}
+	}
+	return results, nil
+}
+
+func simulate(config Config, patternName string, arrivals []time.Duration, algorithm Algorithm) Result {
+	clock := rate_limiter.NewManualClock(START_TIME)
+	limiter := algorithm.New(rate_limiter.NewMemoryClient(clock), config.Limit, config.Window, rate_limiter.WithClock(clock))
+
+	result := Result{
+		Pattern:   patternName,
+		Algorithm: algorithm.Name,
+		Limit:     config.Limit,
+		Offered:   len(arrivals),
+	}
+	var admitted []time.Duration
+	for _, at := range arrivals {
+		clock.Set(START_TIME.Add(at))
+		if limiter.LimitRequests(SIMULATION_CLIENT_ID) {
+			admitted = append(admitted, at)
+		}
+	}
+
+	result.Admitted = len(admitted)
+	result.Denied = result.Offered - result.Admitted
+	result.PeakAdmitted = peakInWindow(admitted, config.Window)
+	result.Overshoot = max(result.PeakAdmitted-config.Limit, 0)
+	return result
+}
+
+// peakInWindow returns the most arrivals within any half-open interval of length window. arrivals
+// must be sorted.
+func peakInWindow(arrivals []time.Duration, window time.Duration) int {
+	peak, start := 0, 0
+	for end, at := range arrivals {
+		for at-arrivals[start] >= window {
+			start++
+		}
+		peak = max(peak, end-start+1)
+	}
+	return peak
+}
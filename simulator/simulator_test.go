@@ -0,0 +1,149 @@
+package simulator_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/simulator"
+)
+
+var _ = Describe("Simulator", func() {
+	var config simulator.Config
+
+	BeforeEach(func() {
+		config = simulator.Config{
+			Limit:    10,
+			Window:   10 * time.Second,
+			Rate:     2,
+			Duration: 5 * time.Minute,
+			Seed:     42,
+		}
+	})
+
+	resultFor := func(results []simulator.Result, algorithm rate_limiter.Algorithm) simulator.Result {
+		for _, result := range results {
+			if result.Algorithm == string(algorithm) {
+				return result
+			}
+		}
+		Fail("no result for " + string(algorithm))
+		return simulator.Result{}
+	}
+
+	patternNamed := func(name string) []simulator.Pattern {
+		for _, pattern := range simulator.Patterns() {
+			if pattern.Name == name {
+				return []simulator.Pattern{pattern}
+			}
+		}
+		Fail("no pattern " + name)
+		return nil
+	}
+
+	Context("when running every pattern", func() {
+		It("should report one consistent result per pattern and algorithm", func() {
+			results, err := simulator.Run(config, simulator.Patterns(), simulator.Algorithms())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results).To(HaveLen(len(simulator.Patterns()) * len(simulator.Algorithms())))
+
+			for _, result := range results {
+				Expect(result.Offered).To(BeNumerically(">", 0))
+				Expect(result.Admitted + result.Denied).To(Equal(result.Offered))
+				Expect(result.PeakAdmitted).To(BeNumerically("<=", result.Admitted))
+				Expect(result.Overshoot).To(Equal(max(result.PeakAdmitted-result.Limit, 0)))
+			}
+		})
+
+		It("should be reproducible for the same seed", func() {
+			first, err := simulator.Run(config, simulator.Patterns(), simulator.Algorithms())
+			Expect(err).NotTo(HaveOccurred())
+			second, err := simulator.Run(config, simulator.Patterns(), simulator.Algorithms())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second).To(Equal(first))
+		})
+	})
+
+	Context("when traffic straddles window boundaries", func() {
+		It("should show the fixed window admitting almost twice its limit", func() {
+			results, err := simulator.Run(config, patternNamed(simulator.PATTERN_BOUNDARY), simulator.Algorithms())
+			Expect(err).NotTo(HaveOccurred())
+
+			fixedWindow := resultFor(results, rate_limiter.ALGORITHM_FIXED_WINDOW_COUNTER)
+			Expect(fixedWindow.PeakAdmitted).To(Equal(2*config.Limit - 1))
+			Expect(fixedWindow.Overshoot).To(Equal(config.Limit - 1))
+
+			slidingCounter := resultFor(results, rate_limiter.ALGORITHM_SLIDING_WINDOW_COUNTER)
+			Expect(slidingCounter.Overshoot).To(BeZero())
+		})
+	})
+
+	Context("when traffic is steady and within the limit", func() {
+		It("should admit every request", func() {
+			config.Rate = 0.5
+			results, err := simulator.Run(config, patternNamed(simulator.PATTERN_STEADY), simulator.Algorithms())
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, result := range results {
+				Expect(result.Denied).To(BeZero(), result.Algorithm)
+			}
+		})
+
+		It("should space requests a nanosecond apart at rates above one per nanosecond", func() {
+			config.Rate = 2e9
+			config.Duration = 10 * time.Nanosecond
+			arrivals := patternNamed(simulator.PATTERN_STEADY)[0].Arrivals(config, nil)
+			Expect(arrivals).To(HaveLen(10))
+			Expect(arrivals[9]).To(Equal(9 * time.Nanosecond))
+		})
+	})
+
+	Context("when the config is invalid", func() {
+		It("should reject a window that is not a whole number of seconds", func() {
+			config.Window = 1500 * time.Millisecond
+			_, err := simulator.Run(config, simulator.Patterns(), simulator.Algorithms())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a non-positive limit", func() {
+			config.Limit = 0
+			_, err := simulator.Run(config, simulator.Patterns(), simulator.Algorithms())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when exporting results", func() {
+		var results []simulator.Result
+
+		BeforeEach(func() {
+			var err error
+			results, err = simulator.Run(config, patternNamed(simulator.PATTERN_BURSTY), simulator.Algorithms())
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should write a CSV header followed by one row per result", func() {
+			var buffer bytes.Buffer
+			Expect(simulator.WriteCSV(&buffer, results)).To(Succeed())
+
+			rows, err := csv.NewReader(&buffer).ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rows).To(HaveLen(len(results) + 1))
+			Expect(rows[0]).To(Equal(simulator.CSV_HEADER))
+			Expect(rows[1][0]).To(Equal(simulator.PATTERN_BURSTY))
+		})
+
+		It("should write JSON that decodes back to the same results", func() {
+			var buffer bytes.Buffer
+			Expect(simulator.WriteJSON(&buffer, results)).To(Succeed())
+
+			var decoded []simulator.Result
+			Expect(json.Unmarshal(buffer.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded).To(Equal(results))
+		})
+	})
+})
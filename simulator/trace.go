@@ -0,0 +1,217 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type TraceFormat string
+
+const (
+	// TRACE_FORMAT_COMMON reads Common and Combined Log Format, whose leading fields are the same
+	TRACE_FORMAT_COMMON TraceFormat = "common"
+	TRACE_FORMAT_JSON   TraceFormat = "json"
+	TRACE_FORMAT_CSV    TraceFormat = "csv"
+)
+
+const (
+	DEFAULT_TRACE_TIME_FIELD = "timestamp"
+	DEFAULT_TRACE_KEY_FIELD  = "key"
+
+	// Keys that common log lines can be grouped by
+	COMMON_LOG_KEY_HOST = "host"
+	COMMON_LOG_KEY_USER = "user"
+
+	COMMON_LOG_TIME_LAYOUT = "02/Jan/2006:15:04:05 -0700"
+)
+
+var commonLogPattern = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\]`)
+
+// TraceEvent is one request read from an access log
+type TraceEvent struct {
+	Time time.Time
+	Key  string
+}
+
+// TraceConfig says how to read an access log
+type TraceConfig struct {
+	Format TraceFormat
+	// TimeField is the JSON field or CSV column holding the timestamp, DEFAULT_TRACE_TIME_FIELD when empty.
+	// Timestamps are RFC 3339 strings or Unix seconds.
+	TimeField string
+	// KeyField is the JSON field or CSV column holding the client key, DEFAULT_TRACE_KEY_FIELD when
+	// empty. For common logs it is COMMON_LOG_KEY_HOST (the default) or COMMON_LOG_KEY_USER.
+	KeyField string
+}
+
+// Trace holds the events of an access log in time order
+type Trace struct {
+	Events []TraceEvent
+	// Skipped counts lines that could not be parsed and were left out
+	Skipped int
+}
+
+// ReadTrace parses an access log. Malformed lines are skipped and counted rather than failing the
+// whole replay, since real logs routinely contain a few.
+func ReadTrace(r io.Reader, config TraceConfig) (Trace, error) {
+	if config.TimeField == "" {
+		config.TimeField = DEFAULT_TRACE_TIME_FIELD
+	}
+
+	var (
+		trace Trace
+		err   error
+	)
+	switch config.Format {
+	case TRACE_FORMAT_COMMON:
+		trace, err = readCommonTrace(r, config)
+	case TRACE_FORMAT_JSON:
+		trace, err = readJSONTrace(r, config)
+	case TRACE_FORMAT_CSV:
+		trace, err = readCSVTrace(r, config)
+	default:
+		return Trace{}, fmt.Errorf("unknown trace format %q", config.Format)
+	}
+	if err != nil {
+		return Trace{}, err
+	}
+
+	// Logs written by several workers are only roughly ordered
+	slices.SortStableFunc(trace.Events, func(a, b TraceEvent) int {
+		return a.Time.Compare(b.Time)
+	})
+	return trace, nil
+}
+
+func readCommonTrace(r io.Reader, config TraceConfig) (Trace, error) {
+	keyGroup := 1
+	switch config.KeyField {
+	case "", COMMON_LOG_KEY_HOST:
+	case COMMON_LOG_KEY_USER:
+		keyGroup = 2
+	default:
+		return Trace{}, fmt.Errorf("common logs can only be keyed by %s or %s", COMMON_LOG_KEY_HOST, COMMON_LOG_KEY_USER)
+	}
+
+	var trace Trace
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		match := commonLogPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			trace.Skipped++
+			continue
+		}
+		at, err := time.Parse(COMMON_LOG_TIME_LAYOUT, match[3])
+		if err != nil {
+			trace.Skipped++
+			continue
+		}
+		trace.Events = append(trace.Events, TraceEvent{Time: at, Key: match[keyGroup]})
+	}
+	return trace, scanner.Err()
+}
+
+func readJSONTrace(r io.Reader, config TraceConfig) (Trace, error) {
+	keyField := config.KeyField
+	if keyField == "" {
+		keyField = DEFAULT_TRACE_KEY_FIELD
+	}
+
+	var trace Trace
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			trace.Skipped++
+			continue
+		}
+		at, err := parseTraceTime(record[config.TimeField])
+		key, ok := record[keyField].(string)
+		if err != nil || !ok || key == "" {
+			trace.Skipped++
+			continue
+		}
+		trace.Events = append(trace.Events, TraceEvent{Time: at, Key: key})
+	}
+	return trace, scanner.Err()
+}
+
+func readCSVTrace(r io.Reader, config TraceConfig) (Trace, error) {
+	keyField := config.KeyField
+	if keyField == "" {
+		keyField = DEFAULT_TRACE_KEY_FIELD
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return Trace{}, err
+	}
+	timeColumn, keyColumn := slices.Index(header, config.TimeField), slices.Index(header, keyField)
+	if timeColumn < 0 || keyColumn < 0 {
+		return Trace{}, fmt.Errorf("csv header needs %q and %q columns", config.TimeField, keyField)
+	}
+
+	var trace Trace
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return trace, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				trace.Skipped++
+				continue
+			}
+			return Trace{}, err
+		}
+		if max(timeColumn, keyColumn) >= len(row) || row[keyColumn] == "" {
+			trace.Skipped++
+			continue
+		}
+		at, err := parseTraceTime(row[timeColumn])
+		if err != nil {
+			trace.Skipped++
+			continue
+		}
+		trace.Events = append(trace.Events, TraceEvent{Time: at, Key: row[keyColumn]})
+	}
+}
+
+// parseTraceTime accepts RFC 3339 strings and Unix seconds, as a number or a string
+func parseTraceTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return unixSeconds(v), nil
+	case string:
+		if at, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return at, nil
+		}
+		seconds, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unrecognised timestamp %q", v)
+		}
+		return unixSeconds(seconds), nil
+	default:
+		return time.Time{}, fmt.Errorf("unrecognised timestamp %v", value)
+	}
+}
+
+func unixSeconds(seconds float64) time.Time {
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second)))
+}
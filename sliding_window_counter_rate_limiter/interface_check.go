@@ -3,3 +3,10 @@ package sliding_window_counter_rate_limiter
 import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 
 var _ rate_limiter.RateLimiterInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.ScriptableInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.RollbackInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.RequirementsInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.InspectorInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.RefundInterface = (*SlidingWindowCounterRateLimiter)(nil)
+var _ rate_limiter.DecisionRefundInterface = (*SlidingWindowCounterRateLimiter)(nil)
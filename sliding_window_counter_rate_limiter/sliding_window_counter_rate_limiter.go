@@ -1,55 +1,365 @@
 package sliding_window_counter_rate_limiter
 
 import (
+	"context"
 	"strconv"
 	"time"
 
 	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 )
 
+// refundScript takes up to ARGV[1] requests off a sub-window count, never below zero. The hash
+// layout passes the sub-window as ARGV[2], the strings layout its key alone. A count that has
+// expired is never recreated.
+const refundScript = `
+local count
+if ARGV[2] then
+	count = tonumber(redis.call('HGET', KEYS[1], ARGV[2]) or '0')
+else
+	count = tonumber(redis.call('GET', KEYS[1]) or '0')
+end
+local refund = math.min(tonumber(ARGV[1]), count)
+if refund <= 0 then
+	return 0
+end
+if ARGV[2] then
+	redis.call('HINCRBY', KEYS[1], ARGV[2], -refund)
+else
+	redis.call('DECRBY', KEYS[1], refund)
+end
+return refund
+`
+
 type SlidingWindowCounterRateLimiter struct {
-    redisClient rate_limiter.RedisClientInterface
-	limit int
-	windowSize int64
+	redisClient   rate_limiter.RedisClientInterface
+	limit         int
+	windowSize    int64
 	subWindowSize int64
+	options       rate_limiter.Options
+}
+
+// NewSlidingWindowCounterRateLimiter keeps the sub-window counts of a client in one hash, which
+// needs Redis 7.4. On clients that detect capabilities, it falls back to KEY_LAYOUT_STRINGS when the
+// server lacks hash field expiry. Clients that cannot tell, or fail to, keep the chosen layout.
+func NewSlidingWindowCounterRateLimiter(redisClient rate_limiter.RedisClientInterface, limit int, windowSize int64, subWindowSize int64, opts ...rate_limiter.Option) *SlidingWindowCounterRateLimiter {
+	limiter := newSlidingWindowCounterRateLimiter(redisClient, limit, windowSize, subWindowSize, opts...)
+	if limiter.options.KeyLayout == rate_limiter.KEY_LAYOUT_STRINGS {
+		return limiter
+	}
+	if detector, ok := redisClient.(rate_limiter.CapabilityDetectorInterface); ok {
+		if capabilities, err := detector.DetectCapabilities(); err == nil && !capabilities.HashFieldExpiry {
+			limiter.options.KeyLayout = rate_limiter.KEY_LAYOUT_STRINGS
+		}
+	}
+	return limiter
 }
 
-func NewSlidingWindowCounterRateLimiter(redisClient rate_limiter.RedisClientInterface, limit int, windowSize int64, subWindowSize int64) *SlidingWindowCounterRateLimiter {
+func newSlidingWindowCounterRateLimiter(redisClient rate_limiter.RedisClientInterface, limit int, windowSize int64, subWindowSize int64, opts ...rate_limiter.Option) *SlidingWindowCounterRateLimiter {
 	return &SlidingWindowCounterRateLimiter{
-		redisClient: redisClient,
-		limit: limit,
-		windowSize: windowSize,
+		redisClient:   redisClient,
+		limit:         limit,
+		windowSize:    windowSize,
 		subWindowSize: subWindowSize,
+		options:       rate_limiter.NewOptions(opts...),
 	}
 }
 
+// NewSlidingWindowCounterRateLimiterChecked builds the limiter and runs rate_limiter.CheckServer
+// against it, failing with an error wrapping rate_limiter.ErrUnsupportedServer when the chosen key
+// layout needs something the server lacks. Unlike NewSlidingWindowCounterRateLimiter, it never
+// switches layouts, so every instance is sure to count under the same keys.
+func NewSlidingWindowCounterRateLimiterChecked(redisClient rate_limiter.RedisClientInterface, limit int, windowSize int64, subWindowSize int64, opts ...rate_limiter.Option) (*SlidingWindowCounterRateLimiter, error) {
+	limiter := newSlidingWindowCounterRateLimiter(redisClient, limit, windowSize, subWindowSize, opts...)
+	if err := rate_limiter.CheckServer(redisClient, limiter); err != nil {
+		return nil, err
+	}
+	return limiter, nil
+}
+
 func (s *SlidingWindowCounterRateLimiter) LimitRequests(clientId string) bool {
-	key := "rate_limit:" + clientId
-	subWindowCounts, err := s.redisClient.HGetAll(key)
+	return s.Decide(clientId).Allowed
+}
+
+func (s *SlidingWindowCounterRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	decision := s.decide(clientId)
+	s.options.Logger.Log(decision)
+	return decision
+}
+
+// Requirements reports that the hash layout needs per-field expiry
+func (s *SlidingWindowCounterRateLimiter) Requirements() rate_limiter.Requirements {
+	return rate_limiter.Requirements{HashFieldExpiry: s.options.KeyLayout != rate_limiter.KEY_LAYOUT_STRINGS}
+}
+
+func (s *SlidingWindowCounterRateLimiter) decide(clientId string) rate_limiter.Decision {
+	decision := s.newDecision(clientId)
+	currentSubWindow := s.currentSubWindow()
+
+	subWindowCounts, err := s.counts(clientId, currentSubWindow)
 	if err != nil {
-		return false
+		decision.Err = err
+		return decision
 	}
-
 	var totalCount int64
 	for _, count := range subWindowCounts {
 		c, err := strconv.Atoi(count)
 		if err != nil {
-			return false
+			decision.Err = err
+			return decision
 		}
-
 		totalCount += int64(c)
 	}
 
-	isAllowed := totalCount < int64(s.limit)
+	isAllowed := totalCount < decision.Limit
 	if isAllowed {
-		currentTime := time.Now().Unix()
-		currentSubWindow := currentTime / s.subWindowSize
+		previousCount, _ := strconv.ParseInt(subWindowCounts[currentSubWindow], 10, 64)
+		incrementResult, err := s.increment(clientId, currentSubWindow, decision.Window)
+		if err != nil {
+			decision.Err = err
+			return decision
+		}
+		if incrementResult == 0 {
+			return decision
+		}
+		// Increments by concurrent requests since the counts were read show up in incrementResult,
+		// so the window total as of this increment is known exactly
+		totalCount += incrementResult - previousCount
+		if totalCount > decision.Limit {
+			if err := s.decrement(clientId, currentSubWindow); err != nil {
+				decision.Err = err
+			}
+			return decision
+		}
+		decision.Charge = currentSubWindow
+	}
+	decision.Allowed = isAllowed
+	decision.Remaining = max(decision.Limit-totalCount, 0)
+	return decision
+}
+
+// counts returns the count of every sub-window still alive, by sub-window
+func (s *SlidingWindowCounterRateLimiter) counts(clientId string, currentSubWindow string) (map[string]string, error) {
+	if s.options.KeyLayout != rate_limiter.KEY_LAYOUT_STRINGS {
+		return s.redisClient.HGetAll(s.options.Key(clientId))
+	}
+
+	subWindows := s.subWindows(currentSubWindow)
+	keys := make([]string, len(subWindows))
+	for i, subWindow := range subWindows {
+		keys[i] = s.subWindowKey(clientId, subWindow)
+	}
+
+	var values []string
+	if multiGet, ok := s.redisClient.(rate_limiter.MultiGetClientInterface); ok {
+		var err error
+		if values, err = multiGet.MGet(keys...); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, key := range keys {
+			value, err := s.redisClient.Get(key)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	}
 
-		incrementResult, err := s.redisClient.HIncrByWithExpiry(key, strconv.FormatInt(currentSubWindow, 10), 1, time.Duration(s.subWindowSize)*time.Second, rate_limiter.EXPIRY_MODE_NX)
-		if err != nil || incrementResult == 0 {
-			return false
+	counts := make(map[string]string, len(subWindows))
+	for i, value := range values {
+		if value != "" {
+			counts[subWindows[i]] = value
 		}
 	}
+	return counts, nil
+}
+
+// increment counts a request in subWindow. Sub-window counts must outlive the sub-window itself,
+// or the window would only ever count the current sub-window.
+func (s *SlidingWindowCounterRateLimiter) increment(clientId string, subWindow string, window time.Duration) (int64, error) {
+	if s.options.KeyLayout == rate_limiter.KEY_LAYOUT_STRINGS {
+		return s.redisClient.IncrWithExpiry(s.subWindowKey(clientId, subWindow), window, rate_limiter.EXPIRY_MODE_NX)
+	}
+	return s.redisClient.HIncrByWithExpiry(s.options.Key(clientId), subWindow, 1, window, rate_limiter.EXPIRY_MODE_NX)
+}
+
+// decrement gives back a request counted in subWindow, unless it has expired
+func (s *SlidingWindowCounterRateLimiter) decrement(clientId string, subWindow string) error {
+	_, err := s.addToSubWindow(clientId, subWindow, -1)
+	return err
+}
+
+// ScriptStep lets the sub-window counters be checked and incremented inside a multi-limiter Lua script
+func (s *SlidingWindowCounterRateLimiter) ScriptStep(clientId string) rate_limiter.ScriptStep {
+	decision := s.newDecision(clientId)
+	decision.Charge = s.currentSubWindow()
+	step := rate_limiter.ScriptStep{
+		Client:   s.redisClient,
+		Keys:     []string{s.options.Key(clientId)},
+		Args:     []interface{}{decision.Limit, s.windowSize, decision.Charge, string(rate_limiter.KEY_LAYOUT_HASH)},
+		Decision: decision,
+		Done:     s.options.Logger.Log,
+	}
+	if s.options.KeyLayout == rate_limiter.KEY_LAYOUT_STRINGS {
+		// The current sub-window is the last key, the one the script increments
+		step.Keys = step.Keys[:0]
+		for _, subWindow := range s.subWindows(decision.Charge) {
+			step.Keys = append(step.Keys, s.subWindowKey(clientId, subWindow))
+		}
+		step.Args[3] = string(rate_limiter.KEY_LAYOUT_STRINGS)
+	}
+	return step
+}
+
+// Rollback decrements the sub-window counter charged by an allowed decision, unless it has expired
+func (s *SlidingWindowCounterRateLimiter) Rollback(decision rate_limiter.Decision) error {
+	if !decision.Allowed || decision.Charge == "" {
+		return nil
+	}
+	return s.decrement(decision.Key, decision.Charge)
+}
 
-	return isAllowed
-}
\ No newline at end of file
+// Peek sums the live sub-window counts without counting a request. Each count expires a window
+// after the first request of its sub-window, so ResetAfter is an upper bound, measured from the end
+// of the newest sub-window with requests in it.
+func (s *SlidingWindowCounterRateLimiter) Peek(ctx context.Context, clientId string) (rate_limiter.KeyState, error) {
+	decision := s.newDecision(clientId)
+	state := rate_limiter.KeyState{Algorithm: decision.Algorithm, Key: clientId, Limit: decision.Limit, Remaining: decision.Limit}
+	if err := ctx.Err(); err != nil {
+		return state, err
+	}
+
+	subWindowCounts, err := s.counts(clientId, s.currentSubWindow())
+	if err != nil {
+		return state, err
+	}
+	var totalCount, newest int64
+	for subWindow, count := range subWindowCounts {
+		c, err := strconv.ParseInt(count, 10, 64)
+		if err != nil {
+			return state, err
+		}
+		if start, _ := strconv.ParseInt(subWindow, 10, 64); c > 0 && start > newest {
+			newest = start
+		}
+		totalCount += c
+	}
+	state.Remaining = max(decision.Limit-totalCount, 0)
+	if newest > 0 {
+		resetAt := time.Unix((newest+1)*s.subWindowSize+s.windowSize, 0)
+		state.ResetAfter = max(resetAt.Sub(s.options.Clock.Now()), 0)
+	}
+	return state, nil
+}
+
+// Reset deletes the hash of the hash layout, or every live sub-window key of the strings layout
+func (s *SlidingWindowCounterRateLimiter) Reset(ctx context.Context, clientId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	keys := []string{s.options.Key(clientId)}
+	if s.options.KeyLayout == rate_limiter.KEY_LAYOUT_STRINGS {
+		keys = keys[:0]
+		for _, subWindow := range s.subWindows(s.currentSubWindow()) {
+			keys = append(keys, s.subWindowKey(clientId, subWindow))
+		}
+	}
+	_, err := s.redisClient.Del(keys...)
+	return err
+}
+
+// Refund takes up to n requests off clientId's current sub-window, never below zero
+func (s *SlidingWindowCounterRateLimiter) Refund(ctx context.Context, clientId string, n int) error {
+	decision := s.newDecision(clientId)
+	decision.Allowed = true
+	decision.Charge = s.currentSubWindow()
+	return s.RefundDecision(ctx, decision, n)
+}
+
+// RefundDecision takes up to n requests off the sub-window charged by decision, never below zero.
+// The count of a sub-window expires a window after its first request, so once it stops counting
+// towards the limit a refund has nothing left to give back.
+func (s *SlidingWindowCounterRateLimiter) RefundDecision(ctx context.Context, decision rate_limiter.Decision, n int) error {
+	if !decision.Allowed || decision.Charge == "" || n <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stringsLayout := s.options.KeyLayout == rate_limiter.KEY_LAYOUT_STRINGS
+
+	if runner, ok := s.redisClient.(rate_limiter.ScriptRunnerInterface); ok {
+		if stringsLayout {
+			_, err := runner.Eval(refundScript, []string{s.subWindowKey(decision.Key, decision.Charge)}, n)
+			return err
+		}
+		_, err := runner.Eval(refundScript, []string{s.options.Key(decision.Key)}, n, decision.Charge)
+		return err
+	}
+
+	var count string
+	if stringsLayout {
+		var err error
+		if count, err = s.redisClient.Get(s.subWindowKey(decision.Key, decision.Charge)); err != nil {
+			return err
+		}
+	} else {
+		counts, err := s.redisClient.HGetAll(s.options.Key(decision.Key))
+		if err != nil {
+			return err
+		}
+		count = counts[decision.Charge]
+	}
+	current, _ := strconv.ParseInt(count, 10, 64)
+	refund := min(int64(n), current)
+	if refund <= 0 {
+		return nil
+	}
+
+	// Concurrent refunds may have drained the count in the meantime, so put back what went below zero
+	result, err := s.addToSubWindow(decision.Key, decision.Charge, -refund)
+	if err != nil || result >= 0 {
+		return err
+	}
+	_, err = s.addToSubWindow(decision.Key, decision.Charge, -result)
+	return err
+}
+
+// addToSubWindow adds increment to the count of subWindow, unless it has expired
+func (s *SlidingWindowCounterRateLimiter) addToSubWindow(clientId string, subWindow string, increment int64) (int64, error) {
+	if s.options.KeyLayout == rate_limiter.KEY_LAYOUT_STRINGS {
+		return s.redisClient.IncrByIfExists(s.subWindowKey(clientId, subWindow), increment)
+	}
+	return s.redisClient.HIncrByIfExists(s.options.Key(clientId), subWindow, increment)
+}
+
+func (s *SlidingWindowCounterRateLimiter) currentSubWindow() string {
+	return strconv.FormatInt(s.options.Clock.Now().Unix()/s.subWindowSize, 10)
+}
+
+// subWindows lists every sub-window that can still hold live counts, oldest first and ending with
+// currentSubWindow. Like the fields of the hash layout, each one's key expires a full window
+// after its first request, so the oldest may already be gone.
+func (s *SlidingWindowCounterRateLimiter) subWindows(currentSubWindow string) []string {
+	current, _ := strconv.ParseInt(currentSubWindow, 10, 64)
+	count := s.windowSize / s.subWindowSize
+	subWindows := make([]string, 0, count+1)
+	for subWindow := current - count; subWindow <= current; subWindow++ {
+		subWindows = append(subWindows, strconv.FormatInt(subWindow, 10))
+	}
+	return subWindows
+}
+
+// subWindowKey is the string key of one sub-window in the strings layout
+func (s *SlidingWindowCounterRateLimiter) subWindowKey(clientId string, subWindow string) string {
+	return s.options.Key(clientId) + ":" + subWindow
+}
+
+func (s *SlidingWindowCounterRateLimiter) newDecision(clientId string) rate_limiter.Decision {
+	return rate_limiter.Decision{
+		Algorithm: rate_limiter.ALGORITHM_SLIDING_WINDOW_COUNTER,
+		Key:       clientId,
+		Limit:     s.options.Override(clientId).LimitOr(int64(s.limit)),
+		Window:    time.Duration(s.windowSize) * time.Second,
+	}
+}
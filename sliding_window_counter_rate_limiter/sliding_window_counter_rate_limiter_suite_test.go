@@ -0,0 +1,13 @@
+package sliding_window_counter_rate_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSlidingWindowCounterRateLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SlidingWindowCounterRateLimiter Suite")
+}
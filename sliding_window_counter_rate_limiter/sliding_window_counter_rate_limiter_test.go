@@ -0,0 +1,90 @@
+package sliding_window_counter_rate_limiter_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/sliding_window_counter_rate_limiter"
+)
+
+var _ = Describe("SlidingWindowCounterRateLimiter", func() {
+	var (
+		server *miniredis.Miniredis
+		client *rate_limiter.RedisClient
+	)
+
+	BeforeEach(func() {
+		server = miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+		redisClient := redis.NewClient(&redis.Options{Addr: server.Addr(), PoolSize: 20})
+		DeferCleanup(redisClient.Close)
+		client = rate_limiter.NewRedisClient(redisClient)
+	})
+
+	It("should refuse to build the hash layout on servers without hash field expiry", func() {
+		client.SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "7.2.4", ExpireOptions: true})
+
+		_, err := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiterChecked(client, 10, 60, 1)
+		Expect(err).To(MatchError(rate_limiter.ErrUnsupportedServer))
+
+		rateLimiter, err := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiterChecked(client, 10, 60, 1,
+			rate_limiter.WithKeyLayout(rate_limiter.KEY_LAYOUT_STRINGS))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+	})
+
+	It("should fall back to the strings layout on servers without hash field expiry", func() {
+		client.SetCapabilities(rate_limiter.Capabilities{Server: "redis", Version: "7.2.4", ExpireOptions: true})
+
+		rateLimiter := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 10, 60, 1)
+
+		Expect(rateLimiter.Requirements().HashFieldExpiry).To(BeFalse())
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		Expect(server.Keys()).To(HaveExactElements(HavePrefix("rate_limit:client:")))
+	})
+
+	It("should never allow more than the limit to concurrent requests", func() {
+		rateLimiter := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 10, 60, 60)
+		var (
+			allowed atomic.Int64
+			wg      sync.WaitGroup
+		)
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if rateLimiter.LimitRequests("client") {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(allowed.Load()).To(Equal(int64(10)))
+	})
+
+	It("should keep counting earlier sub-windows until the whole window has passed", func() {
+		clock := rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+		rateLimiter := sliding_window_counter_rate_limiter.NewSlidingWindowCounterRateLimiter(client, 2, 10, 1, rate_limiter.WithClock(clock))
+		advance := func(duration time.Duration) {
+			clock.Advance(duration)
+			server.FastForward(duration)
+		}
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+
+		advance(3 * time.Second)
+		Expect(rateLimiter.LimitRequests("client")).To(BeFalse())
+
+		advance(8 * time.Second)
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+	})
+})
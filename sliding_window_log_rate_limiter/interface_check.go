@@ -3,3 +3,7 @@ package sliding_window_log_rate_limiter
 import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 
 var _ rate_limiter.RateLimiterInterface = (*SlidingWindowLogRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*SlidingWindowLogRateLimiter)(nil)
+var _ rate_limiter.ScriptableInterface = (*SlidingWindowLogRateLimiter)(nil)
+var _ rate_limiter.RollbackInterface = (*SlidingWindowLogRateLimiter)(nil)
+var _ rate_limiter.InspectorInterface = (*SlidingWindowLogRateLimiter)(nil)
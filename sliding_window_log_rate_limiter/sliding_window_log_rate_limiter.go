@@ -1,6 +1,7 @@
 package sliding_window_log_rate_limiter
 
 import (
+	"context"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,42 +9,148 @@ import (
 	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 )
 
-
 type SlidingWindowLogRateLimiter struct {
 	redisClient rate_limiter.RedisClientInterface
-	limit int
-	windowSize int64
+	limit       int
+	windowSize  int64
+	options     rate_limiter.Options
 }
 
-func NewSlidingWindowLogRateLimiter(redisClient rate_limiter.RedisClientInterface, limit int, windowSize int64) *SlidingWindowLogRateLimiter {
+func NewSlidingWindowLogRateLimiter(redisClient rate_limiter.RedisClientInterface, limit int, windowSize int64, opts ...rate_limiter.Option) *SlidingWindowLogRateLimiter {
 	return &SlidingWindowLogRateLimiter{
 		redisClient: redisClient,
-		limit: limit,
-		windowSize: windowSize,
+		limit:       limit,
+		windowSize:  windowSize,
+		options:     rate_limiter.NewOptions(opts...),
 	}
 }
 
 func (s *SlidingWindowLogRateLimiter) LimitRequests(clientId string) bool {
-	key := "rate_limit:" + clientId
+	return s.Decide(clientId).Allowed
+}
+
+func (s *SlidingWindowLogRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	decision := s.decide(clientId)
+	s.options.Logger.Log(decision)
+	return decision
+}
+
+func (s *SlidingWindowLogRateLimiter) decide(clientId string) rate_limiter.Decision {
+	decision := s.newDecision(clientId)
+
+	key := s.options.Key(clientId)
 	fieldKey := uuid.NewString()
 
+	// Clients that can append atomically avoid the race between the length check and the append below
+	if logClient, ok := s.redisClient.(rate_limiter.SlidingWindowLogClientInterface); ok {
+		isAllowed, requestCount, err := logClient.HSetIfLenBelow(key, fieldKey, decision.Limit, decision.Window, rate_limiter.EXPIRY_MODE_NX)
+		if err != nil {
+			decision.Err = err
+			return decision
+		}
+		if isAllowed {
+			decision.Charge = fieldKey
+		}
+		decision.Allowed = isAllowed
+		decision.Remaining = max(decision.Limit-requestCount, 0)
+		return decision
+	}
+
 	requestCount, err := s.redisClient.HLen(key)
 	if err != nil {
-		return false
+		decision.Err = err
+		return decision
 	}
 
-	isAllowed := requestCount < int64(s.limit)
+	isAllowed := requestCount < decision.Limit
 	if isAllowed {
 		_, err := s.redisClient.HSetWithExpiry(
 			key,
 			fieldKey,
-			time.Duration(s.windowSize)*time.Second,
+			decision.Window,
 			rate_limiter.EXPIRY_MODE_NX,
 		)
 		if err != nil {
-			return false
+			decision.Err = err
+			return decision
+		}
+
+		// Concurrent requests may have passed the same HLen check, so confirm the log is still
+		// within the limit with this entry in it
+		requestCount, err = s.redisClient.HLen(key)
+		if err != nil {
+			decision.Err = err
+			return decision
+		}
+		if requestCount > decision.Limit {
+			if _, err := s.redisClient.HDel(key, fieldKey); err != nil {
+				decision.Err = err
+			}
+			return decision
 		}
+		decision.Charge = fieldKey
+	}
+
+	decision.Allowed = isAllowed
+	decision.Remaining = max(decision.Limit-requestCount, 0)
+	return decision
+}
+
+// ScriptStep lets the log be checked and appended to inside a multi-limiter Lua script
+func (s *SlidingWindowLogRateLimiter) ScriptStep(clientId string) rate_limiter.ScriptStep {
+	decision := s.newDecision(clientId)
+	decision.Charge = uuid.NewString()
+	return rate_limiter.ScriptStep{
+		Client:   s.redisClient,
+		Keys:     []string{s.options.Key(clientId)},
+		Args:     []interface{}{decision.Limit, s.windowSize, decision.Charge},
+		Decision: decision,
+		Done:     s.options.Logger.Log,
 	}
+}
 
-	return isAllowed
-}
\ No newline at end of file
+// Rollback removes the log entry written for an allowed decision
+func (s *SlidingWindowLogRateLimiter) Rollback(decision rate_limiter.Decision) error {
+	if !decision.Allowed || decision.Charge == "" {
+		return nil
+	}
+	_, err := s.redisClient.HDel(s.options.Key(decision.Key), decision.Charge)
+	return err
+}
+
+// Peek counts the entries in the log without appending one. The whole log expires a window after
+// its first entry, which is when the client has its full quota back.
+func (s *SlidingWindowLogRateLimiter) Peek(ctx context.Context, clientId string) (rate_limiter.KeyState, error) {
+	decision := s.newDecision(clientId)
+	state := rate_limiter.KeyState{Algorithm: decision.Algorithm, Key: clientId, Limit: decision.Limit, Remaining: decision.Limit}
+	if err := ctx.Err(); err != nil {
+		return state, err
+	}
+
+	key := s.options.Key(clientId)
+	requestCount, err := s.redisClient.HLen(key)
+	if err != nil || requestCount == 0 {
+		return state, err
+	}
+	state.Remaining = max(decision.Limit-requestCount, 0)
+	state.ResetAfter, err = rate_limiter.PTTL(s.redisClient, key)
+	return state, err
+}
+
+// Reset deletes the log hash
+func (s *SlidingWindowLogRateLimiter) Reset(ctx context.Context, clientId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := s.redisClient.Del(s.options.Key(clientId))
+	return err
+}
+
+func (s *SlidingWindowLogRateLimiter) newDecision(clientId string) rate_limiter.Decision {
+	return rate_limiter.Decision{
+		Algorithm: rate_limiter.ALGORITHM_SLIDING_WINDOW_LOG,
+		Key:       clientId,
+		Limit:     s.options.Override(clientId).LimitOr(int64(s.limit)),
+		Window:    time.Duration(s.windowSize) * time.Second,
+	}
+}
@@ -0,0 +1,13 @@
+package sliding_window_log_rate_limiter_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSlidingWindowLogRateLimiter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SlidingWindowLogRateLimiter Suite")
+}
@@ -3,3 +3,10 @@ package token_bucket_ratelimiter
 import "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 
 var _ rate_limiter.RateLimiterInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.DeciderInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.ScriptableInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.RollbackInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.LeasableInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.InspectorInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.RefundInterface = (*TokenBucketRateLimiter)(nil)
+var _ rate_limiter.DecisionRefundInterface = (*TokenBucketRateLimiter)(nil)
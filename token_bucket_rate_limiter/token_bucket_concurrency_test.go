@@ -0,0 +1,47 @@
+package token_bucket_ratelimiter_test
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("TokenBucketRatelimiter under concurrency", func() {
+	var client *rate_limiter.RedisClient
+
+	BeforeEach(func() {
+		server := miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+		redisClient := redis.NewClient(&redis.Options{Addr: server.Addr(), PoolSize: 20})
+		DeferCleanup(redisClient.Close)
+		client = rate_limiter.NewRedisClient(redisClient)
+	})
+
+	It("should never allow more than the limit to concurrent requests", func() {
+		rateLimiter := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 10, 0.01)
+		var (
+			allowed atomic.Int64
+			wg      sync.WaitGroup
+		)
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if rateLimiter.LimitRequests("client") {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(allowed.Load()).To(Equal(int64(10)))
+	})
+})
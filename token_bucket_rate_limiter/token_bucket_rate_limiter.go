@@ -1,53 +1,324 @@
 package token_bucket_ratelimiter
 
 import (
+	"context"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 )
 
+// EXPIRY_SLACK keeps an idle bucket's keys a little longer than the bucket takes to fill up, so
+// that clock skew between instances never drops a bucket that is still refilling
+const EXPIRY_SLACK = 10 * time.Second
+
+// refundScript adds up to ARGV[1] tokens to an existing bucket without raising it above ARGV[2].
+// INCRBY keeps the key's expiry.
+const refundScript = `
+local count = redis.call('GET', KEYS[1])
+if not count then
+	return 0
+end
+local refund = math.max(math.min(tonumber(ARGV[1]), tonumber(ARGV[2]) - tonumber(count)), 0)
+if refund > 0 then
+	redis.call('INCRBY', KEYS[1], refund)
+end
+return refund
+`
+
 type TokenBucketRateLimiter struct {
-	redisClient rate_limiter.RedisClientInterface
+	redisClient    rate_limiter.RedisClientInterface
 	bucketCapacity int
-	refillRate float64
+	refillRate     float64
+	options        rate_limiter.Options
 }
 
-func NewTokenBucketRateLimiter(redisClient rate_limiter.RedisClientInterface, bucketCapacity int, refillRate float64) *TokenBucketRateLimiter {
+func NewTokenBucketRateLimiter(redisClient rate_limiter.RedisClientInterface, bucketCapacity int, refillRate float64, opts ...rate_limiter.Option) *TokenBucketRateLimiter {
 	return &TokenBucketRateLimiter{
-		redisClient: redisClient,
+		redisClient:    redisClient,
 		bucketCapacity: bucketCapacity,
-		refillRate: refillRate,
+		refillRate:     refillRate,
+		options:        rate_limiter.NewOptions(opts...),
 	}
 }
 
 func (t *TokenBucketRateLimiter) LimitRequests(clientId string) bool {
-	keyCount := "rate_limit:" + clientId + ":count"
-	keyLastRefill := "rate_limit:" + clientId + ":lastRefill"
-	currentTime := time.Now().Unix()
+	return t.Decide(clientId).Allowed
+}
 
-	lastRefillTime, tokenCount, err := t.redisClient.GetCountAndLastRefill(keyCount, keyLastRefill)
-	if err != nil {
-		return false
-	}
+func (t *TokenBucketRateLimiter) Decide(clientId string) rate_limiter.Decision {
+	decision := t.decide(clientId)
+	t.options.Logger.Log(decision)
+	return decision
+}
+
+func (t *TokenBucketRateLimiter) decide(clientId string) rate_limiter.Decision {
+	bucketCapacity, refillRate := t.limits(clientId)
+	decision := t.newDecision(clientId, bucketCapacity, refillRate)
+
+	keyCount, keyLastRefill := t.keys(clientId)
+	now := t.options.Clock.Now()
+	currentTime, unitRate := t.refillTime(now, refillRate)
+	decision.Charge = strconv.FormatInt(now.UnixMilli(), 10)
 
-	if lastRefillTime == 0 {
-		lastRefillTime = currentTime
-		tokenCount = t.bucketCapacity
+	// Clients that can take a token atomically avoid the race between the read and the write below
+	if tokenBucketClient, ok := t.redisClient.(rate_limiter.TokenBucketClientInterface); ok {
+		taken, tokenCount, err := tokenBucketClient.TakeTokens(keyCount, keyLastRefill, bucketCapacity, unitRate, currentTime, keyExpiry(bucketCapacity, refillRate), 1)
+		if err != nil {
+			decision.Err = err
+			return decision
+		}
+		decision.Allowed = taken == 1
+		decision.Remaining = int64(tokenCount)
+		return decision
 	}
 
-	elapsedTimeSecs := currentTime - lastRefillTime
+	lastRefillTime, tokenCount, err := t.redisClient.GetCountAndLastRefill(keyCount, keyLastRefill)
+	if err != nil {
+		decision.Err = err
+		return decision
+	}
 
-	tokensToAdd := int(elapsedTimeSecs) * int(t.refillRate)
-	tokenCount = min(t.bucketCapacity, tokenCount + tokensToAdd)
+	tokenCount, lastRefillTime = rate_limiter.RefillTokens(tokenCount, lastRefillTime, bucketCapacity, unitRate, currentTime)
 
 	isAllowed := tokenCount > 0
 	if isAllowed {
 		tokenCount--
 	}
 
-	if err := t.redisClient.SetCountAndLastRefill(keyCount, keyLastRefill, tokenCount, currentTime); err != nil {
-		return false
+	if err := t.setCountAndLastRefill(keyCount, keyLastRefill, tokenCount, lastRefillTime, keyExpiry(bucketCapacity, refillRate)); err != nil {
+		decision.Err = err
+		return decision
+	}
+
+	decision.Allowed = isAllowed
+	decision.Remaining = int64(tokenCount)
+	return decision
+}
+
+// ScriptStep lets the bucket be checked and drained inside a multi-limiter Lua script
+func (t *TokenBucketRateLimiter) ScriptStep(clientId string) rate_limiter.ScriptStep {
+	keyCount, keyLastRefill := t.keys(clientId)
+	bucketCapacity, refillRate := t.limits(clientId)
+	now := t.options.Clock.Now()
+	currentTime, unitRate := t.refillTime(now, refillRate)
+	decision := t.newDecision(clientId, bucketCapacity, refillRate)
+	decision.Charge = strconv.FormatInt(now.UnixMilli(), 10)
+	return rate_limiter.ScriptStep{
+		Client:   t.redisClient,
+		Keys:     []string{keyCount, keyLastRefill},
+		Args:     []interface{}{bucketCapacity, unitRate, currentTime, keyExpiry(bucketCapacity, refillRate).Milliseconds()},
+		Decision: decision,
+		Done:     t.options.Logger.Log,
+	}
+}
+
+// Rollback puts back the token taken by an allowed decision; refills cap the count at capacity
+func (t *TokenBucketRateLimiter) Rollback(decision rate_limiter.Decision) error {
+	if !decision.Allowed {
+		return nil
+	}
+	keyCount, _ := t.keys(decision.Key)
+	_, err := t.redisClient.IncrByIfExists(keyCount, 1)
+	return err
+}
+
+// Lease takes up to n tokens from the bucket at once
+func (t *TokenBucketRateLimiter) Lease(clientId string, n int) (rate_limiter.LeaseGrant, error) {
+	bucketCapacity, refillRate := t.limits(clientId)
+	grant := rate_limiter.LeaseGrant{Decision: t.newDecision(clientId, bucketCapacity, refillRate)}
+	keyCount, keyLastRefill := t.keys(clientId)
+	now := t.options.Clock.Now()
+	currentTime, unitRate := t.refillTime(now, refillRate)
+	grant.Charge = strconv.FormatInt(now.UnixMilli(), 10)
+	expiry := keyExpiry(bucketCapacity, refillRate)
+
+	if tokenBucketClient, ok := t.redisClient.(rate_limiter.TokenBucketClientInterface); ok {
+		taken, tokenCount, err := tokenBucketClient.TakeTokens(keyCount, keyLastRefill, bucketCapacity, unitRate, currentTime, expiry, n)
+		grant.Units, grant.Allowed, grant.Remaining = taken, taken > 0, int64(tokenCount)
+		return grant, err
+	}
+
+	lastRefillTime, tokenCount, err := t.redisClient.GetCountAndLastRefill(keyCount, keyLastRefill)
+	if err != nil {
+		return grant, err
+	}
+	tokenCount, lastRefillTime = rate_limiter.RefillTokens(tokenCount, lastRefillTime, bucketCapacity, unitRate, currentTime)
+	taken := min(n, max(tokenCount, 0))
+	if err := t.setCountAndLastRefill(keyCount, keyLastRefill, tokenCount-taken, lastRefillTime, expiry); err != nil {
+		return grant, err
+	}
+	grant.Units, grant.Allowed, grant.Remaining = taken, taken > 0, int64(tokenCount-taken)
+	return grant, nil
+}
+
+// ReturnLease puts unspent leased tokens back the way RefundDecision does, never above capacity and not
+// once the bucket could have refilled since the lease
+func (t *TokenBucketRateLimiter) ReturnLease(grant rate_limiter.LeaseGrant, n int) error {
+	return t.RefundDecision(context.Background(), grant.Decision, min(n, grant.Units))
+}
+
+// Peek refills the bucket as of now without taking a token or writing it back
+func (t *TokenBucketRateLimiter) Peek(ctx context.Context, clientId string) (rate_limiter.KeyState, error) {
+	bucketCapacity, refillRate := t.limits(clientId)
+	state := rate_limiter.KeyState{Algorithm: rate_limiter.ALGORITHM_TOKEN_BUCKET, Key: clientId, Limit: int64(bucketCapacity)}
+	if err := ctx.Err(); err != nil {
+		return state, err
+	}
+
+	keyCount, keyLastRefill := t.keys(clientId)
+	lastRefillTime, tokenCount, err := t.redisClient.GetCountAndLastRefill(keyCount, keyLastRefill)
+	if err != nil {
+		return state, err
+	}
+	currentTime, unitRate := t.refillTime(t.options.Clock.Now(), refillRate)
+	tokenCount, lastRefillTime = rate_limiter.RefillTokens(tokenCount, lastRefillTime, bucketCapacity, unitRate, currentTime)
+
+	state.Remaining = int64(max(tokenCount, 0))
+	if missing := bucketCapacity - tokenCount; missing > 0 && unitRate > 0 {
+		fullAt := float64(lastRefillTime) + float64(missing)/unitRate
+		state.ResetAfter = max(time.Duration((fullAt-float64(currentTime))*float64(t.refillUnit())), 0)
+	}
+	return state, nil
+}
+
+// Reset deletes both keys of the bucket, which then reads as full
+func (t *TokenBucketRateLimiter) Reset(ctx context.Context, clientId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	keyCount, keyLastRefill := t.keys(clientId)
+	_, err := t.redisClient.Del(keyCount, keyLastRefill)
+	return err
+}
+
+// Refund puts up to n tokens back into clientId's bucket, never above its capacity
+func (t *TokenBucketRateLimiter) Refund(ctx context.Context, clientId string, n int) error {
+	bucketCapacity, refillRate := t.limits(clientId)
+	decision := t.newDecision(clientId, bucketCapacity, refillRate)
+	decision.Allowed = true
+	decision.Charge = strconv.FormatInt(t.options.Clock.Now().UnixMilli(), 10)
+	return t.RefundDecision(ctx, decision, n)
+}
+
+// RefundDecision puts up to n tokens back into the bucket, never above its capacity. Once the
+// bucket has had time to refill from empty since the charge, the tokens have been earned back
+// already and the refund is dropped. Buckets whose keys have expired read as full, so they are
+// left alone too.
+func (t *TokenBucketRateLimiter) RefundDecision(ctx context.Context, decision rate_limiter.Decision, n int) error {
+	if !decision.Allowed || n <= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	chargedAtMs, err := strconv.ParseInt(decision.Charge, 10, 64)
+	if err != nil {
+		return fmt.Errorf("decision carries no charge time: %w", err)
+	}
+	if decision.Window > 0 && !t.options.Clock.Now().Before(time.UnixMilli(chargedAtMs).Add(decision.Window)) {
+		return nil
+	}
+	bucketCapacity, _ := t.limits(decision.Key)
+	keyCount, _ := t.keys(decision.Key)
+
+	if runner, ok := t.redisClient.(rate_limiter.ScriptRunnerInterface); ok {
+		_, err := runner.Eval(refundScript, []string{keyCount}, n, bucketCapacity)
+		return err
+	}
+
+	countStr, err := t.redisClient.Get(keyCount)
+	if err != nil || countStr == "" {
+		return err
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return err
+	}
+	refund := min(n, bucketCapacity-count)
+	if refund <= 0 {
+		return nil
+	}
+	// Concurrent refunds may have filled the bucket in the meantime, so trim what overflows
+	result, err := t.redisClient.IncrByIfExists(keyCount, int64(refund))
+	if err != nil || result <= int64(bucketCapacity) {
+		return err
+	}
+	_, err = t.redisClient.IncrByIfExists(keyCount, int64(bucketCapacity)-result)
+	return err
+}
+
+// keys returns the count and refill time keys of clientId. Refill times in milliseconds get a key of
+// their own, so that an instance still counting in seconds never reads one as a time far ahead.
+func (t *TokenBucketRateLimiter) keys(clientId string) (string, string) {
+	key := t.options.Key(clientId)
+	if t.options.MillisecondRefill {
+		return key + ":count", key + ":lastRefillMs"
+	}
+	return key + ":count", key + ":lastRefill"
+}
+
+// refillUnit is the unit the bucket's refill times are stored in
+func (t *TokenBucketRateLimiter) refillUnit() time.Duration {
+	if t.options.MillisecondRefill {
+		return time.Millisecond
+	}
+	return time.Second
+}
+
+// refillTime returns now in refillUnit, and refillRate in tokens per refillUnit
+func (t *TokenBucketRateLimiter) refillTime(now time.Time, refillRate float64) (int64, float64) {
+	if t.options.MillisecondRefill {
+		return now.UnixMilli(), refillRate / 1000
 	}
+	return now.Unix(), refillRate
+}
+
+// limits returns the bucket capacity and refill rate of clientId, taking overrides into account
+func (t *TokenBucketRateLimiter) limits(clientId string) (int, float64) {
+	override := t.options.Override(clientId)
+	return int(override.LimitOr(int64(t.bucketCapacity))), override.RefillRateOr(t.refillRate)
+}
+
+func (t *TokenBucketRateLimiter) newDecision(clientId string, bucketCapacity int, refillRate float64) rate_limiter.Decision {
+	return rate_limiter.Decision{
+		Algorithm: rate_limiter.ALGORITHM_TOKEN_BUCKET,
+		Key:       clientId,
+		Limit:     int64(bucketCapacity),
+		Window:    refillDuration(bucketCapacity, refillRate),
+	}
+}
 
-	return isAllowed
-}
\ No newline at end of file
+// refillDuration is how long an empty bucket takes to fill back up to capacity
+func refillDuration(bucketCapacity int, refillRate float64) time.Duration {
+	if refillRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(bucketCapacity) / refillRate * float64(time.Second))
+}
+
+// setCountAndLastRefill writes the bucket, expiring its keys on clients that can
+func (t *TokenBucketRateLimiter) setCountAndLastRefill(keyCount, keyLastRefill string, tokenCount int, lastRefillTime int64, expiry time.Duration) error {
+	if expiringClient, ok := t.redisClient.(rate_limiter.ExpiringTokenBucketClientInterface); ok {
+		return expiringClient.SetCountAndLastRefillWithExpiry(keyCount, keyLastRefill, tokenCount, lastRefillTime, expiry)
+	}
+	return t.redisClient.SetCountAndLastRefill(keyCount, keyLastRefill, tokenCount, lastRefillTime)
+}
+
+// keyExpiry is how long the keys of a bucket live after each write. An idle bucket is full again
+// once refillDuration has passed, and missing keys read as a full bucket, so nothing is lost when
+// they expire. Buckets that never refill keep their keys.
+func keyExpiry(bucketCapacity int, refillRate float64) time.Duration {
+	duration := refillDuration(bucketCapacity, refillRate)
+	if duration <= 0 {
+		return 0
+	}
+	return duration + EXPIRY_SLACK
+}
+
+// KeyPatterns returns SCAN patterns matching the keys of every bucket stored under prefix, e.g.
+// to give an expiry to buckets written before keys expired with RedisClient.ExpireOrphanedKeys
+func KeyPatterns(prefix string) []string {
+	return []string{prefix + "*:count", prefix + "*:lastRefill", prefix + "*:lastRefillMs"}
+}
@@ -1,13 +1,16 @@
 package token_bucket_ratelimiter_test
 
 import (
+	"bytes"
 	"errors"
+	"log/slog"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
 	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter/mocks"
 )
 
@@ -40,9 +43,9 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 				var capturedTokenCount int
 				var capturedTime int64
 
-				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, time int64) error {
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
 					capturedTokenCount = tokenCount
-					capturedTime = time
+					capturedTime = refillTime
 					return nil
 				}
 
@@ -55,6 +58,58 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 			})
 		})
 
+		Context("when the bucket is written", func() {
+			It("should expire the keys once an idle bucket would have refilled", func() {
+				mockRedisClient.GetCountAndLastRefillFunc = func(keyCount, keyLastRefill string) (int64, int, error) {
+					return 0, 0, nil
+				}
+				var capturedExpiry time.Duration
+				mockRedisClient.SetCountAndLastRefillWithExpiryFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64, expiry time.Duration) error {
+					capturedExpiry = expiry
+					return nil
+				}
+
+				rateLimiter = token_bucket_ratelimiter.NewTokenBucketRateLimiter(mockRedisClient, bucketCapacity, refillRate)
+				rateLimiter.LimitRequests(clientID)
+
+				Expect(capturedExpiry).To(Equal(10*time.Second + token_bucket_ratelimiter.EXPIRY_SLACK))
+			})
+
+			It("should write without an expiry to clients that cannot set one", func() {
+				clock := rate_limiter.NewManualClock(time.Unix(currentTime, 0))
+				client := rate_limiter.NewMemoryClient(clock)
+				// Embedding only the interface hides every optional capability of the client
+				rateLimiter = token_bucket_ratelimiter.NewTokenBucketRateLimiter(struct{ rate_limiter.RedisClientInterface }{client}, bucketCapacity, refillRate, rate_limiter.WithClock(clock))
+				Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+
+				Expect(client.PTTL("rate_limit:test-client:count")).To(BeZero())
+				Expect(client.Get("rate_limit:test-client:count")).To(Equal("9"))
+			})
+
+			It("should keep buckets that never refill", func() {
+				clock := rate_limiter.NewManualClock(time.Unix(currentTime, 0))
+				client := rate_limiter.NewMemoryClient(clock)
+				rateLimiter = token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, bucketCapacity, 0, rate_limiter.WithClock(clock))
+				rateLimiter.LimitRequests(clientID)
+
+				clock.Advance(365 * 24 * time.Hour)
+				Expect(client.Get("rate_limit:test-client:count")).To(Equal("9"))
+			})
+
+			It("should read an expired bucket as full", func() {
+				clock := rate_limiter.NewManualClock(time.Unix(currentTime, 0))
+				client := rate_limiter.NewMemoryClient(clock)
+				rateLimiter = token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 2, 0.1, rate_limiter.WithClock(clock))
+				Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+				Expect(rateLimiter.LimitRequests(clientID)).To(BeTrue())
+				Expect(rateLimiter.LimitRequests(clientID)).To(BeFalse())
+
+				clock.Advance(20*time.Second + token_bucket_ratelimiter.EXPIRY_SLACK)
+				Expect(client.Get("rate_limit:test-client:count")).To(BeEmpty())
+				Expect(rateLimiter.Decide(clientID).Remaining).To(Equal(int64(1)))
+			})
+		})
+
 		Context("when tokens are available", func() {
 			It("should allow the request and decrement token count", func() {
 				initialTokens := 5
@@ -64,7 +119,7 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 				}
 
 				var capturedTokenCount int
-				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, time int64) error {
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
 					capturedTokenCount = tokenCount
 					return nil
 				}
@@ -90,7 +145,7 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 				}
 
 				var capturedTokenCount int
-				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, time int64) error {
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
 					capturedTokenCount = tokenCount
 					return nil
 				}
@@ -114,7 +169,7 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 				}
 
 				var capturedTokenCount int
-				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, time int64) error {
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
 					capturedTokenCount = tokenCount
 					return nil
 				}
@@ -134,7 +189,7 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 				}
 
 				var capturedTokenCount int
-				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, time int64) error {
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
 					capturedTokenCount = tokenCount
 					return nil
 				}
@@ -164,7 +219,7 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 					return currentTime - 10, 5, nil
 				}
 
-				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, time int64) error {
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
 					return errors.New("redis write error")
 				}
 
@@ -174,5 +229,28 @@ var _ = Describe("TokenBucketRatelimiter", func() {
 				Expect(result).To(BeFalse())
 			})
 		})
+
+		Context("when a decision logger is attached", func() {
+			It("should log the denial with the bucket state", func() {
+				mockRedisClient.GetCountAndLastRefillFunc = func(keyCount, keyLastRefill string) (int64, int, error) {
+					return currentTime, 0, nil
+				}
+				mockRedisClient.SetCountAndLastRefillFunc = func(keyCount, keyLastRefill string, tokenCount int, refillTime int64) error {
+					return nil
+				}
+
+				buffer := &bytes.Buffer{}
+				decisionLogger := rate_limiter.NewDecisionLogger(slog.New(slog.NewTextHandler(buffer, nil)), rate_limiter.DecisionLoggerConfig{})
+
+				rateLimiter = token_bucket_ratelimiter.NewTokenBucketRateLimiter(mockRedisClient, bucketCapacity, refillRate, rate_limiter.WithDecisionLogger(decisionLogger))
+				decision := rateLimiter.Decide(clientID)
+
+				Expect(decision.Allowed).To(BeFalse())
+				Expect(decision.Remaining).To(BeZero())
+				Expect(buffer.String()).To(ContainSubstring("algorithm=token_bucket"))
+				Expect(buffer.String()).To(ContainSubstring("key=test-client"))
+				Expect(buffer.String()).To(ContainSubstring("limit=10"))
+			})
+		})
 	})
 })
@@ -0,0 +1,54 @@
+package token_bucket_ratelimiter_test
+
+import (
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/rate_limiter"
+	token_bucket_ratelimiter "github.com/Shrijeeth/Learning-Golang-by-Implementing-Rate-Limiter-Algorithms/token_bucket_rate_limiter"
+)
+
+var _ = Describe("TokenBucketRatelimiter refill", func() {
+	var (
+		clock  *rate_limiter.ManualClock
+		client *rate_limiter.RedisClient
+	)
+
+	BeforeEach(func() {
+		server := miniredis.NewMiniRedis()
+		Expect(server.Start()).To(Succeed())
+		DeferCleanup(server.Close)
+		redisClient := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		DeferCleanup(redisClient.Close)
+		client = rate_limiter.NewRedisClient(redisClient)
+		clock = rate_limiter.NewManualClock(time.Unix(1_700_000_000, 0))
+	})
+
+	It("should refill rates below one token per second across requests", func() {
+		rateLimiter := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 1, 0.5, rate_limiter.WithClock(clock))
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+
+		clock.Advance(time.Second)
+		Expect(rateLimiter.LimitRequests("client")).To(BeFalse())
+		clock.Advance(time.Second)
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+	})
+
+	It("should keep the time a partial token has earned", func() {
+		rateLimiter := token_bucket_ratelimiter.NewTokenBucketRateLimiter(client, 3, 0.4, rate_limiter.WithClock(clock))
+		for range 3 {
+			Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		}
+
+		// 4 seconds earn 1.6 tokens; the 0.6 carries over into the next second
+		clock.Advance(4 * time.Second)
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+		Expect(rateLimiter.LimitRequests("client")).To(BeFalse())
+		clock.Advance(time.Second)
+		Expect(rateLimiter.LimitRequests("client")).To(BeTrue())
+	})
+})